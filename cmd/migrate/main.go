@@ -7,6 +7,7 @@ import (
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"github.com/joho/godotenv"
 
 	"kerjakuy/internal/models"
@@ -19,7 +20,7 @@ func main() {
 	}
 
 	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=Asia/Jakarta",
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=UTC",
 		os.Getenv("DB_HOST"),
 		os.Getenv("DB_USER"),
 		os.Getenv("DB_PASS"),
@@ -33,6 +34,7 @@ func main() {
 	}
 
 	err = db.AutoMigrate(
+		&models.AccountDeletionTransfer{},
 		&models.ActivityLog{},
 		&models.Attachment{},
 		&models.Board{},
@@ -41,13 +43,25 @@ func main() {
 		&models.ChatMessage{},
 		&models.ChatMessageRead{},
 		&models.Column{},
+		&models.DataMigration{},
+		&models.EmailChangeRequest{},
+		&models.LoginThrottle{},
 		&models.Notification{},
+		&models.OAuthState{},
+		&models.PasswordResetToken{},
+		&models.PersonalAccessToken{},
 		&models.Project{},
+		&models.RevokedToken{},
+		&models.SecurityEvent{},
 		&models.TaskAssignee{},
 		&models.TaskComment{},
 		&models.Task{},
 		&models.User{},
+		&models.UserIdentity{},
+		&models.UserRecoveryCode{},
 		&models.UserSession{},
+		&models.UserTwoFactor{},
+		&models.WorkspaceInvitation{},
 		&models.WorkspaceMember{},
 		&models.Workspace{},
 	)
@@ -56,5 +70,55 @@ func main() {
 		log.Fatal("migration failed: ", err)
 	}
 
+	// Accounts created before email verification existed never had a
+	// verification mail sent. Treat their address as verified, otherwise an
+	// OAuth login with the same email would take the account over as an
+	// unproven claim. This runs once: a newer signup whose verification mail
+	// failed also has no email_verification_sent_at and must stay unverified.
+	err = runOnce(db, "backfill_email_verified_at", func(tx *gorm.DB) error {
+		backfill := tx.Model(&models.User{}).
+			Where("email_verified_at IS NULL AND email_verification_sent_at IS NULL AND anonymized_at IS NULL").
+			Update("email_verified_at", gorm.Expr("created_at"))
+		if backfill.Error != nil {
+			return backfill.Error
+		}
+		if backfill.RowsAffected > 0 {
+			log.Printf("Marked %d existing accounts as verified", backfill.RowsAffected)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal("email verification backfill failed: ", err)
+	}
+
+	// Before plans had limits, anyone could put their workspace on any plan.
+	// Only a platform admin may pick one now, and that sets plan_changed_at,
+	// so every other plan besides free was self-assigned and goes back to
+	// free. Unknown plan names are reset the same way.
+	plans := db.Model(&models.Workspace{}).
+		Where("plan <> ? AND plan_changed_at IS NULL", "free").
+		Update("plan", "free")
+	if plans.Error != nil {
+		log.Fatal("workspace plan reset failed: ", plans.Error)
+	}
+	if plans.RowsAffected > 0 {
+		log.Printf("Moved %d self-assigned workspace plans back to free", plans.RowsAffected)
+	}
+
 	log.Println("Migration completed successfully!")
 }
+
+// runOnce applies a one-off data change unless a data_migrations row says it
+// already ran. The row and the change commit together.
+func runOnce(db *gorm.DB, name string, apply func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.DataMigration{Name: name})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return apply(tx)
+	})
+}
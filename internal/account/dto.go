@@ -0,0 +1,80 @@
+package account
+
+import (
+	"time"
+
+	"kerjakuy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type ScheduleDeletionRequest struct {
+	// Password is required for accounts that have one.
+	Password string `json:"password"`
+	// Transfers names the new owner for every owned workspace that has
+	// other members.
+	Transfers []OwnershipTransfer `json:"transfers" binding:"omitempty,dive"`
+}
+
+type OwnershipTransfer struct {
+	WorkspaceID uuid.UUID `json:"workspace_id" binding:"required"`
+	NewOwnerID  uuid.UUID `json:"new_owner_id" binding:"required"`
+}
+
+type DeletionStatusDTO struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+type ExportMembership struct {
+	WorkspaceID   uuid.UUID `json:"workspace_id"`
+	WorkspaceName string    `json:"workspace_name"`
+	WorkspaceSlug string    `json:"workspace_slug"`
+	Role          string    `json:"role"`
+	JoinedAt      time.Time `json:"joined_at"`
+}
+
+// Export is everything stored about one user. Each field becomes one file
+// in the ZIP variant of the export.
+type Export struct {
+	ExportedAt      time.Time                    `json:"exported_at"`
+	Profile         models.User                  `json:"profile"`
+	Identities      []models.UserIdentity        `json:"identities"`
+	Sessions        []models.UserSession         `json:"sessions"`
+	AccessTokens    []models.PersonalAccessToken `json:"access_tokens"`
+	SecurityEvents  []models.SecurityEvent       `json:"security_events"`
+	Memberships     []ExportMembership           `json:"memberships"`
+	OwnedWorkspaces []models.Workspace           `json:"owned_workspaces"`
+	Projects        []models.Project             `json:"projects_created"`
+	Tasks           []models.Task                `json:"tasks_created"`
+	Assignments     []models.TaskAssignee        `json:"task_assignments"`
+	Comments        []models.TaskComment         `json:"comments"`
+	Attachments     []models.Attachment          `json:"attachments"`
+	ChatMessages    []models.ChatMessage         `json:"chat_messages"`
+	Notifications   []models.Notification        `json:"notifications"`
+	Activity        []models.ActivityLog         `json:"activity"`
+}
+
+func (e *Export) sections() []exportSection {
+	return []exportSection{
+		{"profile", e.Profile},
+		{"identities", e.Identities},
+		{"sessions", e.Sessions},
+		{"access_tokens", e.AccessTokens},
+		{"security_events", e.SecurityEvents},
+		{"memberships", e.Memberships},
+		{"owned_workspaces", e.OwnedWorkspaces},
+		{"projects_created", e.Projects},
+		{"tasks_created", e.Tasks},
+		{"task_assignments", e.Assignments},
+		{"comments", e.Comments},
+		{"attachments", e.Attachments},
+		{"chat_messages", e.ChatMessages},
+		{"notifications", e.Notifications},
+		{"activity", e.Activity},
+	}
+}
+
+type exportSection struct {
+	name string
+	data interface{}
+}
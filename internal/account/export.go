@@ -0,0 +1,28 @@
+package account
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+)
+
+// WriteZIP writes one indented JSON file per section.
+func (e *Export) WriteZIP(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, section := range e.sections() {
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     section.name + ".json",
+			Method:   zip.Deflate,
+			Modified: e.ExportedAt,
+		})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(section.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,102 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"kerjakuy/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountHandler struct {
+	accountService Service
+}
+
+func NewAccountHandler(accountService Service) *AccountHandler {
+	return &AccountHandler{accountService: accountService}
+}
+
+func (h *AccountHandler) ScheduleDeletion(c *gin.Context) {
+	var req ScheduleDeletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	status, err := h.accountService.ScheduleDeletion(c.Request.Context(), userID, req)
+	if err != nil {
+		var owned *WorkspacesOwnedError
+		switch {
+		case errors.As(err, &owned):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "workspace_ids": owned.WorkspaceIDs})
+		case errors.Is(err, ErrPasswordInvalid):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrDeletionAlreadyScheduled):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrOwnershipTransferInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, status)
+}
+
+func (h *AccountHandler) CancelDeletion(c *gin.Context) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if err := h.accountService.CancelDeletion(c.Request.Context(), userID); err != nil {
+		if errors.Is(err, ErrDeletionNotScheduled) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Export returns the archive as JSON, or as a ZIP with one file per section
+// when called with ?format=zip.
+func (h *AccountHandler) Export(c *gin.Context) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format harus json atau zip"})
+		return
+	}
+
+	export, err := h.accountService.Export(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("kerjakuy-export-%s.%s", export.ExportedAt.Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	if format == "json" {
+		c.JSON(http.StatusOK, export)
+		return
+	}
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	if err := export.WriteZIP(c.Writer); err != nil {
+		_ = c.Error(err)
+	}
+}
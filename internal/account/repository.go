@@ -0,0 +1,278 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"kerjakuy/internal/auth"
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/workspace"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OwnedWorkspace is a workspace the user owns together with how many other
+// members it has.
+type OwnedWorkspace struct {
+	ID           uuid.UUID
+	Name         string
+	OtherMembers int64
+}
+
+type Repository interface {
+	ListOwnedWorkspaces(ctx context.Context, userID uuid.UUID) ([]OwnedWorkspace, error)
+	IsMember(ctx context.Context, workspaceID, userID uuid.UUID) (bool, error)
+	ScheduleDeletion(ctx context.Context, userID uuid.UUID, at time.Time, transfers []OwnershipTransfer) (bool, error)
+	CancelDeletion(ctx context.Context, userID uuid.UUID) (bool, error)
+	DeleteAccessTokens(ctx context.Context, userID uuid.UUID) error
+	ListDueForDeletion(ctx context.Context, now time.Time, limit int) ([]models.User, error)
+	Purge(ctx context.Context, account *models.User, now time.Time) error
+	Export(ctx context.Context, userID uuid.UUID) (*Export, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) ListOwnedWorkspaces(ctx context.Context, userID uuid.UUID) ([]OwnedWorkspace, error) {
+	var owned []OwnedWorkspace
+	err := r.db.WithContext(ctx).Table("workspaces AS w").
+		Select("w.id, w.name, COUNT(wm.id) AS other_members").
+		Joins("LEFT JOIN workspace_members wm ON wm.workspace_id = w.id AND wm.user_id <> ?", userID).
+		Where("w.owner_id = ?", userID).
+		Group("w.id, w.name").
+		Scan(&owned).Error
+	if err != nil {
+		return nil, err
+	}
+	return owned, nil
+}
+
+func (r *repository) IsMember(ctx context.Context, workspaceID, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ScheduleDeletion marks the account and stores the chosen new owners in
+// one transaction. The transfers are only applied by Purge.
+func (r *repository) ScheduleDeletion(ctx context.Context, userID uuid.UUID, at time.Time, transfers []OwnershipTransfer) (bool, error) {
+	scheduled := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND deletion_scheduled_at IS NULL AND anonymized_at IS NULL", userID).
+			Update("deletion_scheduled_at", at)
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+		scheduled = true
+		if err := tx.Where("user_id = ?", userID).Delete(&models.AccountDeletionTransfer{}).Error; err != nil {
+			return err
+		}
+		for _, t := range transfers {
+			if err := tx.Create(&models.AccountDeletionTransfer{
+				UserID:      userID,
+				WorkspaceID: t.WorkspaceID,
+				NewOwnerID:  t.NewOwnerID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return scheduled && err == nil, err
+}
+
+func (r *repository) CancelDeletion(ctx context.Context, userID uuid.UUID) (bool, error) {
+	cancelled := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND deletion_scheduled_at IS NOT NULL", userID).
+			Update("deletion_scheduled_at", nil)
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+		cancelled = true
+		return tx.Where("user_id = ?", userID).Delete(&models.AccountDeletionTransfer{}).Error
+	})
+	return cancelled && err == nil, err
+}
+
+func (r *repository) DeleteAccessTokens(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.PersonalAccessToken{}).Error
+}
+
+func (r *repository) ListDueForDeletion(ctx context.Context, now time.Time, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).
+		Where("deletion_scheduled_at <= ? AND anonymized_at IS NULL", now).
+		Order("deletion_scheduled_at").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// Purge removes everything personal about the account in one transaction.
+// The users row itself stays as an anonymous tombstone because projects,
+// tasks, comments and chat messages keep referencing their author; those
+// now show up as written by a deleted user, with their text removed.
+func (r *repository) Purge(ctx context.Context, account *models.User, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.releaseWorkspaces(ctx, tx, account.ID); err != nil {
+			return err
+		}
+
+		userID := account.ID
+		personal := []interface{}{
+			&models.WorkspaceMember{},
+			&models.TaskAssignee{},
+			&models.ChatChannelMember{},
+			&models.ChatMessageRead{},
+			&models.Notification{},
+			&models.UserSession{},
+			&models.UserIdentity{},
+			&models.UserTwoFactor{},
+			&models.UserRecoveryCode{},
+			&models.PersonalAccessToken{},
+			&models.PasswordResetToken{},
+			&models.EmailChangeRequest{},
+			&models.SecurityEvent{},
+			&models.AccountDeletionTransfer{},
+		}
+		for _, model := range personal {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.TaskComment{}).Where("user_id = ?", userID).Update("content", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ChatMessage{}).Where("sender_id = ?", userID).Update("content", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Where("throttle_key = ?", auth.NormalizeLoginEmail(account.Email)).Delete(&models.LoginThrottle{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("email = ?", strings.ToLower(account.Email)).Delete(&models.WorkspaceInvitation{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"name":                       "Pengguna terhapus",
+			"email":                      fmt.Sprintf("deleted+%s@users.kerjakuy.invalid", userID),
+			"password_hash":              "",
+			"avatar_url":                 nil,
+			"avatar_key":                 nil,
+			"working_hours_start":        nil,
+			"working_hours_end":          nil,
+			"preferences":                nil,
+			"email_verified_at":          nil,
+			"email_verification_sent_at": nil,
+			"is_platform_admin":          false,
+			"deletion_scheduled_at":      nil,
+			"anonymized_at":              now,
+		}).Error
+	})
+}
+
+// releaseWorkspaces hands owned workspaces over: to the owner picked when
+// the deletion was scheduled if they are still a member, otherwise to the
+// longest-standing admin (or member). Workspaces nobody else is in are
+// deleted.
+func (r *repository) releaseWorkspaces(ctx context.Context, tx *gorm.DB, userID uuid.UUID) error {
+	var owned []models.Workspace
+	if err := tx.Where("owner_id = ?", userID).Find(&owned).Error; err != nil {
+		return err
+	}
+	var chosen []models.AccountDeletionTransfer
+	if err := tx.Where("user_id = ?", userID).Find(&chosen).Error; err != nil {
+		return err
+	}
+	picked := make(map[uuid.UUID]uuid.UUID, len(chosen))
+	for _, t := range chosen {
+		picked[t.WorkspaceID] = t.NewOwnerID
+	}
+
+	workspaces := workspace.NewWorkspaceRepository(tx)
+	for _, ws := range owned {
+		var successor models.WorkspaceMember
+		err := tx.Where("workspace_id = ? AND user_id <> ?", ws.ID, userID).
+			Order(clause.OrderBy{Expression: clause.Expr{
+				SQL:  "user_id = ? DESC, CASE role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, created_at",
+				Vars: []interface{}{picked[ws.ID]},
+			}}).
+			First(&successor).Error
+		switch {
+		case err == nil:
+			reason := "account_deleted"
+			if successor.UserID == picked[ws.ID] {
+				reason = "account_deletion"
+			}
+			if err := workspaces.TransferOwnership(ctx, ws.ID, userID, successor.UserID, reason); err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := workspaces.Purge(ctx, ws.ID); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repository) Export(ctx context.Context, userID uuid.UUID) (*Export, error) {
+	db := r.db.WithContext(ctx)
+	export := &Export{ExportedAt: time.Now()}
+	if err := db.First(&export.Profile, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+
+	byUser := []struct {
+		dest   interface{}
+		column string
+		order  string
+	}{
+		{&export.Identities, "user_id", "created_at"},
+		{&export.Sessions, "user_id", "created_at"},
+		{&export.AccessTokens, "user_id", "created_at"},
+		{&export.SecurityEvents, "user_id", "created_at"},
+		{&export.OwnedWorkspaces, "owner_id", "created_at"},
+		{&export.Projects, "created_by", "created_at"},
+		{&export.Tasks, "created_by", "created_at"},
+		{&export.Assignments, "user_id", "task_id"},
+		{&export.Comments, "user_id", "created_at"},
+		{&export.Attachments, "uploaded_by", "created_at"},
+		{&export.ChatMessages, "sender_id", "created_at"},
+		{&export.Notifications, "user_id", "created_at"},
+		{&export.Activity, "user_id", "created_at"},
+	}
+	for _, q := range byUser {
+		if err := db.Where(q.column+" = ?", userID).Order(q.order).Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	err := db.Table("workspace_members AS wm").
+		Select("wm.workspace_id, w.name AS workspace_name, w.slug AS workspace_slug, wm.role, wm.created_at AS joined_at").
+		Joins("JOIN workspaces w ON w.id = wm.workspace_id").
+		Where("wm.user_id = ?", userID).
+		Order("wm.created_at").
+		Scan(&export.Memberships).Error
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
@@ -0,0 +1,245 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/mailer"
+	"kerjakuy/internal/user"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const purgeBatchSize = 50
+
+var (
+	ErrPasswordInvalid          = errors.New("password salah")
+	ErrDeletionAlreadyScheduled = errors.New("penghapusan akun sudah dijadwalkan")
+	ErrDeletionNotScheduled     = errors.New("tidak ada penghapusan akun yang dijadwalkan")
+	ErrOwnershipTransferInvalid = errors.New("pemilik baru harus anggota lain dari workspace milik Anda")
+	ErrWorkspacesOwned          = errors.New("akun masih memiliki workspace dengan anggota lain, transfer kepemilikan terlebih dahulu")
+)
+
+// WorkspacesOwnedError lists the shared workspaces that still need a new
+// owner before the account can be deleted.
+type WorkspacesOwnedError struct {
+	WorkspaceIDs []uuid.UUID
+}
+
+func (e *WorkspacesOwnedError) Error() string {
+	return ErrWorkspacesOwned.Error()
+}
+
+func (e *WorkspacesOwnedError) Unwrap() error {
+	return ErrWorkspacesOwned
+}
+
+type Service interface {
+	ScheduleDeletion(ctx context.Context, userID uuid.UUID, req ScheduleDeletionRequest) (*DeletionStatusDTO, error)
+	CancelDeletion(ctx context.Context, userID uuid.UUID) error
+	Export(ctx context.Context, userID uuid.UUID) (*Export, error)
+	Start(ctx context.Context, interval time.Duration)
+}
+
+type userFinder interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*user.UserDTO, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	RemoveAvatar(ctx context.Context, id uuid.UUID) (*user.UserDTO, error)
+}
+
+// sessionTerminator is implemented by auth.Service.
+type sessionTerminator interface {
+	SignOutEverywhere(ctx context.Context, userID uuid.UUID) error
+}
+
+type service struct {
+	repo        Repository
+	users       userFinder
+	sessions    sessionTerminator
+	mailer      mailer.Mailer
+	logger      *slog.Logger
+	gracePeriod time.Duration
+}
+
+func NewService(repo Repository, users userFinder, sessions sessionTerminator, mail mailer.Mailer, logger *slog.Logger, gracePeriod time.Duration) Service {
+	if gracePeriod <= 0 {
+		gracePeriod = 14 * 24 * time.Hour
+	}
+	return &service{
+		repo:        repo,
+		users:       users,
+		sessions:    sessions,
+		mailer:      mail,
+		logger:      logger,
+		gracePeriod: gracePeriod,
+	}
+}
+
+// ScheduleDeletion records who takes over each shared workspace, signs the
+// user out everywhere and marks the account for anonymization after the
+// grace period. Ownership only moves at purge time, so logging in again and
+// calling CancelDeletion undoes all of it.
+func (s *service) ScheduleDeletion(ctx context.Context, userID uuid.UUID, req ScheduleDeletionRequest) (*DeletionStatusDTO, error) {
+	userDTO, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if userDTO.DeletionScheduledAt != nil {
+		return nil, ErrDeletionAlreadyScheduled
+	}
+	account, err := s.users.GetByEmail(ctx, userDTO.Email)
+	if err != nil {
+		return nil, err
+	}
+	if account.PasswordHash != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(req.Password)); err != nil {
+			return nil, ErrPasswordInvalid
+		}
+	}
+
+	transfers, err := s.planTransfers(ctx, userID, req.Transfers)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledAt := time.Now().Add(s.gracePeriod)
+	scheduled, err := s.repo.ScheduleDeletion(ctx, userID, scheduledAt, transfers)
+	if err != nil {
+		return nil, err
+	}
+	if !scheduled {
+		return nil, ErrDeletionAlreadyScheduled
+	}
+	if err := s.repo.DeleteAccessTokens(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.sessions.SignOutEverywhere(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	_ = s.mailer.Send(ctx, mailer.Message{
+		To:      []string{userDTO.Email},
+		Subject: "Akun KerjaKuy Anda akan dihapus",
+		Body: fmt.Sprintf(
+			"Halo %s,\n\nAkun Anda dijadwalkan untuk dihapus permanen pada %s. Semua sesi login telah diakhiri.\n\nJika Anda berubah pikiran, login kembali sebelum tanggal tersebut dan batalkan penghapusan dari pengaturan akun.\n",
+			userDTO.Name, scheduledAt.Format("2 January 2006 15:04 MST"),
+		),
+	})
+	s.logger.Info("account deletion scheduled", "user_id", userID, "scheduled_at", scheduledAt)
+	return &DeletionStatusDTO{DeletionScheduledAt: scheduledAt}, nil
+}
+
+// planTransfers checks that every owned workspace with other members gets
+// a new owner who is one of those members.
+func (s *service) planTransfers(ctx context.Context, userID uuid.UUID, requested []OwnershipTransfer) ([]OwnershipTransfer, error) {
+	owned, err := s.repo.ListOwnedWorkspaces(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	shared := make(map[uuid.UUID]bool, len(owned))
+	for _, ws := range owned {
+		shared[ws.ID] = ws.OtherMembers > 0
+	}
+
+	byWorkspace := make(map[uuid.UUID]OwnershipTransfer, len(requested))
+	for _, t := range requested {
+		isShared, ok := shared[t.WorkspaceID]
+		if !ok || !isShared || t.NewOwnerID == userID {
+			return nil, ErrOwnershipTransferInvalid
+		}
+		member, err := s.repo.IsMember(ctx, t.WorkspaceID, t.NewOwnerID)
+		if err != nil {
+			return nil, err
+		}
+		if !member {
+			return nil, ErrOwnershipTransferInvalid
+		}
+		byWorkspace[t.WorkspaceID] = t
+	}
+
+	var missing []uuid.UUID
+	transfers := make([]OwnershipTransfer, 0, len(byWorkspace))
+	for _, ws := range owned {
+		if !shared[ws.ID] {
+			continue
+		}
+		t, ok := byWorkspace[ws.ID]
+		if !ok {
+			missing = append(missing, ws.ID)
+			continue
+		}
+		transfers = append(transfers, t)
+	}
+	if len(missing) > 0 {
+		return nil, &WorkspacesOwnedError{WorkspaceIDs: missing}
+	}
+	return transfers, nil
+}
+
+func (s *service) CancelDeletion(ctx context.Context, userID uuid.UUID) error {
+	cancelled, err := s.repo.CancelDeletion(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return ErrDeletionNotScheduled
+	}
+	s.logger.Info("account deletion cancelled", "user_id", userID)
+	return nil
+}
+
+func (s *service) Export(ctx context.Context, userID uuid.UUID) (*Export, error) {
+	return s.repo.Export(ctx, userID)
+}
+
+// Start purges accounts whose grace period has ended, once right away and
+// then every interval.
+func (s *service) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		s.purgeDue(ctx)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.purgeDue(ctx)
+			}
+		}
+	}()
+}
+
+func (s *service) purgeDue(ctx context.Context) {
+	now := time.Now()
+	accounts, err := s.repo.ListDueForDeletion(ctx, now, purgeBatchSize)
+	if err != nil {
+		s.logger.Error("failed to list accounts due for deletion", "error", err)
+		return
+	}
+	for i := range accounts {
+		if err := s.purge(ctx, &accounts[i], now); err != nil {
+			s.logger.Error("failed to delete account", "error", err, "user_id", accounts[i].ID)
+			continue
+		}
+		s.logger.Info("account deleted", "user_id", accounts[i].ID)
+	}
+}
+
+func (s *service) purge(ctx context.Context, account *models.User, now time.Time) error {
+	// Sessions started during the grace period must stop working before
+	// their rows disappear with the rest of the account.
+	if err := s.sessions.SignOutEverywhere(ctx, account.ID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if _, err := s.users.RemoveAvatar(ctx, account.ID); err != nil {
+		return err
+	}
+	return s.repo.Purge(ctx, account, now)
+}
@@ -1,9 +1,17 @@
 package app
 
 import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"kerjakuy/internal/account"
 	"kerjakuy/internal/auth"
 	"kerjakuy/internal/middleware"
 	"kerjakuy/internal/pkg/logger"
+	"kerjakuy/internal/pkg/mailer"
+	"kerjakuy/internal/pkg/storage"
 	"kerjakuy/internal/project"
 	"kerjakuy/internal/router/v1"
 	"kerjakuy/internal/task"
@@ -31,45 +39,113 @@ func (a *Application) BuildRouter() *gin.Engine {
 	logger := logger.New()
 	db := database.InitPostgresDB(a.cfg)
 
+	blobStore, err := storage.New(storage.Config{
+		Driver: a.cfg.Storage.Driver,
+		Dir:    a.cfg.Storage.Dir,
+	})
+	if err != nil {
+		log.Fatalf("Gagal memuat konfigurasi storage: %v", err)
+	}
+
 	userRepo := user.NewUserRepository(db)
-	userService := user.NewUserService(userRepo)
+	userService := user.NewUserService(userRepo, blobStore, a.cfg.AvatarBaseURL)
+
+	oauthRegistry, err := auth.NewOAuthRegistryFromConfig(a.cfg.OAuthProviders, &http.Client{Timeout: 10 * time.Second})
+	if err != nil {
+		log.Fatalf("Gagal memuat konfigurasi oauth: %v", err)
+	}
+
+	mail, err := mailer.New(mailer.Config{
+		Driver:   a.cfg.Mail.Driver,
+		From:     a.cfg.Mail.From,
+		Dir:      a.cfg.Mail.Dir,
+		Host:     a.cfg.Mail.SMTPHost,
+		Port:     a.cfg.Mail.SMTPPort,
+		Username: a.cfg.Mail.SMTPUsername,
+		Password: a.cfg.Mail.SMTPPassword,
+	}, logger)
+	if err != nil {
+		log.Fatalf("Gagal memuat konfigurasi mailer: %v", err)
+	}
+
+	var jwtKeys *auth.KeySet
+	if a.cfg.JWTPrivateKeyFile != "" {
+		jwtKeys, err = auth.LoadKeySet(a.cfg.JWTPrivateKeyFile, a.cfg.JWTPublicKeyFiles)
+		if err != nil {
+			log.Fatalf("Gagal memuat key jwt: %v", err)
+		}
+	}
 
 	sessionRepo := auth.NewUserSessionRepository(db)
-	authService := auth.NewService(userService, sessionRepo, auth.Config{
-		Secret:          a.cfg.JWTSecret,
-		Issuer:          a.cfg.JWTIssuer,
-		AccessTokenTTL:  a.cfg.AccessTokenTTL,
-		RefreshTokenTTL: a.cfg.RefreshTokenTTL,
+	identityRepo := auth.NewUserIdentityRepository(db)
+	oauthStateRepo := auth.NewOAuthStateRepository(db)
+	securityEventRepo := auth.NewSecurityEventRepository(db)
+	twoFactorRepo := auth.NewTwoFactorRepository(db)
+	passwordResetRepo := auth.NewPasswordResetRepository(db)
+	emailChangeRepo := auth.NewEmailChangeRepository(db)
+	loginThrottleRepo := auth.NewLoginThrottleRepository(db)
+	accessTokenRepo := auth.NewPersonalAccessTokenRepository(db)
+	revocationStore := auth.NewRevocationStore(auth.NewRevokedTokenRepository(db), logger)
+	revocationStore.Start(context.Background(), a.cfg.RevocationSyncInterval)
+
+	workspaceRepo := workspace.NewWorkspaceRepository(db)
+	memberRepo := workspace.NewWorkspaceMemberRepository(db)
+	permissionService := auth.NewPermissionService(memberRepo, workspaceRepo, twoFactorRepo, userService)
+	invitationService := workspace.NewInvitationService(db, workspace.NewInvitationRepository(db), workspaceRepo, memberRepo, permissionService, userService, mail, a.cfg.AppURL, a.cfg.WorkspaceInvitationTTL, logger)
+
+	authService := auth.NewService(userService, sessionRepo, identityRepo, oauthStateRepo, securityEventRepo, twoFactorRepo, passwordResetRepo, emailChangeRepo, loginThrottleRepo, accessTokenRepo, revocationStore, oauthRegistry, mail, invitationService, memberRepo, logger, auth.Config{
+		Secret:               a.cfg.JWTSecret,
+		Keys:                 jwtKeys,
+		Issuer:               a.cfg.JWTIssuer,
+		AccessTokenTTL:       a.cfg.AccessTokenTTL,
+		RefreshTokenTTL:      a.cfg.RefreshTokenTTL,
+		AppURL:               a.cfg.AppURL,
+		PasswordResetTTL:     a.cfg.PasswordResetTTL,
+		EmailVerificationTTL: a.cfg.EmailVerifyTTL,
+		MagicLinkTTL:         a.cfg.MagicLinkTTL,
+		EmailChangeTTL:       a.cfg.EmailChangeTTL,
+		EmailChangeRevertTTL: a.cfg.EmailChangeRevertTTL,
+		ImpersonationTTL:     a.cfg.ImpersonationTTL,
+		LoginMaxFailures:     a.cfg.LoginMaxFailures,
+		LoginLockout:         a.cfg.LoginLockout,
 	})
+	authService.Start(context.Background(), time.Hour)
 
 	cookieMgr := auth.NewCookieManager(auth.CookieOptions{
+		Domain:     a.cfg.CookieDomain,
+		Secure:     a.cfg.CookieSecure,
+		SameSite:   auth.ParseSameSite(a.cfg.CookieSameSite),
 		AccessTTL:  a.cfg.AccessTokenTTL,
 		RefreshTTL: a.cfg.RefreshTokenTTL,
 	})
 
 	authHandler := auth.NewAuthHandler(authService, cookieMgr)
-	authMiddleware := auth.NewAuthMiddleware(authService)
+	userHandler := user.NewUserHandler(userService, auth.GetUserID)
+	activityRepo := project.NewActivityLogRepository(db)
+	authMiddleware := auth.NewAuthMiddleware(authService, cookieMgr, activityRepo, memberRepo, logger)
 
-	workspaceRepo := workspace.NewWorkspaceRepository(db)
-	memberRepo := workspace.NewWorkspaceMemberRepository(db)
-	permissionService := auth.NewPermissionService(memberRepo)
-	workspaceService := workspace.NewWorkspaceService(db, workspaceRepo, memberRepo, permissionService, logger)
-	workspaceHandler := workspace.NewWorkspaceHandler(workspaceService, userService)
+	workspaceService := workspace.NewWorkspaceService(db, workspaceRepo, memberRepo, permissionService, authService, userService, userService, logger, a.cfg.WorkspaceDeletionGrace)
+	workspaceService.Start(context.Background(), time.Hour)
+	workspaceHandler := workspace.NewWorkspaceHandler(workspaceService, invitationService)
 
 	projectRepo := project.NewProjectRepository(db)
 	boardRepo := project.NewBoardRepository(db)
 	columnRepo := project.NewColumnRepository(db)
-	projectService := project.NewProjectService(projectRepo, boardRepo, columnRepo, permissionService)
+	projectService := project.NewProjectService(db, projectRepo, boardRepo, columnRepo, permissionService, workspaceService)
 	projectHandler := project.NewProjectHandler(projectService)
 
 	taskRepo := task.NewTaskRepository(db)
 	assigneeRepo := task.NewTaskAssigneeRepository(db)
 	commentRepo := task.NewTaskCommentRepository(db)
 	attachmentRepo := task.NewAttachmentRepository(db)
-	taskService := task.NewService(taskRepo, assigneeRepo, commentRepo, attachmentRepo, boardRepo, columnRepo, permissionService)
+	taskService := task.NewService(db, taskRepo, assigneeRepo, commentRepo, attachmentRepo, projectRepo, boardRepo, columnRepo, permissionService, userService, workspaceService)
 	taskHandler := task.NewTaskHandler(taskService)
 
-	router := router.SetupRouter(authHandler, workspaceHandler, projectHandler, taskHandler, authMiddleware)
+	accountService := account.NewService(account.NewRepository(db), userService, authService, mail, logger, a.cfg.AccountDeletionGrace)
+	accountService.Start(context.Background(), time.Hour)
+	accountHandler := account.NewAccountHandler(accountService)
+
+	router := router.SetupRouter(authHandler, accountHandler, userHandler, workspaceHandler, projectHandler, taskHandler, authMiddleware)
 
 	router.Use(middleware.LoggerMiddleware())
 	router.Use(middleware.CORSMiddleware())
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/rbac"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// personalAccessTokenPrefix lets RequireAuth tell PATs from JWTs and
+	// makes leaked tokens easy to find with secret scanners.
+	personalAccessTokenPrefix   = "kjk_pat_"
+	personalAccessTokenShown    = 12
+	personalAccessTokenTouchGap = time.Minute
+)
+
+var (
+	ErrAccessTokenNotFound     = errors.New("personal access token tidak ditemukan")
+	ErrAccessTokenInvalid      = errors.New("personal access token tidak valid atau kedaluwarsa")
+	ErrAccessTokenInvalidScope = errors.New("scope personal access token tidak valid")
+)
+
+func (s *authService) CreateAccessToken(ctx context.Context, userID uuid.UUID, req CreateAccessTokenRequest) (*CreatedAccessTokenResponse, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at harus di masa depan", ErrAccessTokenInvalidScope)
+	}
+	permissions := make([]string, 0, len(req.Permissions))
+	for _, p := range req.Permissions {
+		if !rbac.IsValidPermission(rbac.Permission(p)) {
+			return nil, fmt.Errorf("%w: permission %q tidak dikenal", ErrAccessTokenInvalidScope, p)
+		}
+		permissions = append(permissions, p)
+	}
+	for _, workspaceID := range req.WorkspaceIDs {
+		if _, err := s.members.FindByUserAndWorkspace(ctx, userID, workspaceID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: Anda bukan anggota workspace %s", ErrAccessTokenInvalidScope, workspaceID)
+			}
+			return nil, err
+		}
+	}
+
+	secret, err := generateState()
+	if err != nil {
+		return nil, err
+	}
+	raw := personalAccessTokenPrefix + secret
+
+	token := &models.PersonalAccessToken{
+		UserID:       userID,
+		Name:         strings.TrimSpace(req.Name),
+		TokenPrefix:  raw[:personalAccessTokenShown],
+		TokenHash:    hashToken(raw),
+		WorkspaceIDs: req.WorkspaceIDs,
+		Permissions:  permissions,
+		ExpiresAt:    req.ExpiresAt,
+	}
+	if err := s.accessTokenRepo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+	return &CreatedAccessTokenResponse{
+		Token:       raw,
+		AccessToken: mapAccessTokenToDTO(token),
+	}, nil
+}
+
+func (s *authService) ListAccessTokens(ctx context.Context, userID uuid.UUID) ([]AccessTokenDTO, error) {
+	tokens, err := s.accessTokenRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]AccessTokenDTO, 0, len(tokens))
+	for i := range tokens {
+		result = append(result, mapAccessTokenToDTO(&tokens[i]))
+	}
+	return result, nil
+}
+
+func (s *authService) RevokeAccessToken(ctx context.Context, userID, tokenID uuid.UUID) error {
+	deleted, err := s.accessTokenRepo.Delete(ctx, userID, tokenID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// ValidatePersonalAccessToken resolves a PAT into claims carrying its scope.
+func (s *authService) ValidatePersonalAccessToken(ctx context.Context, raw string) (*Claims, error) {
+	token, err := s.accessTokenRepo.FindByTokenHash(ctx, hashToken(raw))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccessTokenInvalid
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if token.ExpiresAt != nil && !token.ExpiresAt.After(now) {
+		return nil, ErrAccessTokenInvalid
+	}
+
+	userDTO, err := s.userSvc.GetByID(ctx, token.UserID)
+	if err != nil {
+		return nil, ErrAccessTokenInvalid
+	}
+	if err := s.accessTokenRepo.TouchLastUsed(ctx, token.ID, now, now.Add(-personalAccessTokenTouchGap)); err != nil {
+		return nil, err
+	}
+
+	scope := &TokenScope{
+		TokenID:      token.ID,
+		WorkspaceIDs: token.WorkspaceIDs,
+		Permissions:  make([]rbac.Permission, 0, len(token.Permissions)),
+	}
+	for _, p := range token.Permissions {
+		scope.Permissions = append(scope.Permissions, rbac.Permission(p))
+	}
+
+	claims := &Claims{
+		UserID: userDTO.ID,
+		Email:  userDTO.Email,
+		Scope:  scope,
+	}
+	if token.ExpiresAt != nil {
+		claims.ExpiresAt = *token.ExpiresAt
+	}
+	return claims, nil
+}
+
+func isPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, personalAccessTokenPrefix)
+}
+
+func mapAccessTokenToDTO(token *models.PersonalAccessToken) AccessTokenDTO {
+	return AccessTokenDTO{
+		ID:           token.ID,
+		Name:         token.Name,
+		TokenPrefix:  token.TokenPrefix,
+		WorkspaceIDs: token.WorkspaceIDs,
+		Permissions:  token.Permissions,
+		ExpiresAt:    token.ExpiresAt,
+		LastUsedAt:   token.LastUsedAt,
+		CreatedAt:    token.CreatedAt,
+	}
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"kerjakuy/internal/pkg/rbac"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateAccessTokenScope(t *testing.T) {
+	ta := newTestAuth(t)
+	u := ta.users.add(t, "ana@example.com", "rahasia123")
+	own := uuid.New()
+	ta.members.add(u.ID, own, string(rbac.RoleMember))
+
+	tests := []struct {
+		name        string
+		workspaces  []uuid.UUID
+		permissions []string
+		wantErr     error
+	}{
+		{name: "own workspace", workspaces: []uuid.UUID{own}, permissions: []string{string(rbac.PermissionCreateTask)}},
+		{name: "foreign workspace", workspaces: []uuid.UUID{own, uuid.New()}, wantErr: ErrAccessTokenInvalidScope},
+		{name: "unknown permission", workspaces: []uuid.UUID{own}, permissions: []string{"task:everything"}, wantErr: ErrAccessTokenInvalidScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created, err := ta.CreateAccessToken(context.Background(), u.ID, CreateAccessTokenRequest{
+				Name:         tt.name,
+				WorkspaceIDs: tt.workspaces,
+				Permissions:  tt.permissions,
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CreateAccessToken error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateAccessToken: %v", err)
+			}
+			claims, err := ta.ValidatePersonalAccessToken(context.Background(), created.Token)
+			if err != nil {
+				t.Fatalf("ValidatePersonalAccessToken: %v", err)
+			}
+			if claims.UserID != u.ID || claims.Scope == nil || !claims.Scope.AllowsWorkspace(own) {
+				t.Errorf("claims = %+v, want scope over %s", claims, own)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// Start periodically deletes expired OAuth states, password reset tokens,
+// sessions and stale login throttle counters; nothing else removes rows that
+// were never redeemed.
+func (s *authService) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		s.deleteExpired(ctx)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.deleteExpired(ctx)
+			}
+		}
+	}()
+}
+
+func (s *authService) deleteExpired(ctx context.Context) {
+	now := time.Now()
+	if err := s.stateRepo.DeleteExpired(ctx, now); err != nil {
+		s.logger.Error("failed to delete expired oauth states", "error", err)
+	}
+	if err := s.resetRepo.DeleteExpired(ctx, now); err != nil {
+		s.logger.Error("failed to delete expired password reset tokens", "error", err)
+	}
+	if err := s.sessionRepo.DeleteExpired(ctx, now); err != nil {
+		s.logger.Error("failed to delete expired sessions", "error", err)
+	}
+	if err := s.throttleRepo.DeleteStale(ctx, now, now.Add(-loginWindow)); err != nil {
+		s.logger.Error("failed to delete stale login throttles", "error", err)
+	}
+}
@@ -1,20 +1,30 @@
 package auth
 
 import (
+	"crypto/subtle"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// CSRFHeaderName is the header a browser client must echo the CSRF cookie in
+// for unsafe requests authenticated by cookie.
+const CSRFHeaderName = "X-CSRF-Token"
+
 type CookieManager interface {
 	SetTokens(c *gin.Context, tokens AuthTokens)
 	ClearTokens(c *gin.Context)
+	AccessToken(c *gin.Context) string
+	RefreshToken(c *gin.Context) string
+	ValidCSRF(c *gin.Context) bool
 }
 
 type CookieOptions struct {
 	AccessCookieName  string
 	RefreshCookieName string
+	CSRFCookieName    string
 	Domain            string
 	Path              string
 	Secure            bool
@@ -32,6 +42,7 @@ func NewCookieManager(opts CookieOptions) CookieManager {
 	defaults := CookieOptions{
 		AccessCookieName:  "kerjakuy_access",
 		RefreshCookieName: "kerjakuy_refresh",
+		CSRFCookieName:    "kerjakuy_csrf",
 		Path:              "/",
 		HTTPOnly:          true,
 		SameSite:          http.SameSiteLaxMode,
@@ -45,6 +56,9 @@ func NewCookieManager(opts CookieOptions) CookieManager {
 	if opts.RefreshCookieName == "" {
 		opts.RefreshCookieName = defaults.RefreshCookieName
 	}
+	if opts.CSRFCookieName == "" {
+		opts.CSRFCookieName = defaults.CSRFCookieName
+	}
 	if opts.Path == "" {
 		opts.Path = defaults.Path
 	}
@@ -57,9 +71,9 @@ func NewCookieManager(opts CookieOptions) CookieManager {
 	if opts.RefreshTTL == 0 {
 		opts.RefreshTTL = defaults.RefreshTTL
 	}
-	if opts.HTTPOnly == false && !opts.Secure {
-		opts.HTTPOnly = defaults.HTTPOnly
-	}
+	// Token cookies are never readable from scripts, whatever Secure is set
+	// to; only the CSRF cookie is.
+	opts.HTTPOnly = defaults.HTTPOnly
 
 	return &cookieManager{opts: opts}
 }
@@ -68,23 +82,57 @@ func (m *cookieManager) SetTokens(c *gin.Context, tokens AuthTokens) {
 	accessMaxAge := durationOrSeconds(m.opts.AccessTTL, tokens.ExpiresIn)
 	refreshMaxAge := int(m.opts.RefreshTTL.Seconds())
 
-	m.setCookie(c, m.opts.AccessCookieName, tokens.AccessToken, accessMaxAge)
-	m.setCookie(c, m.opts.RefreshCookieName, tokens.RefreshToken, refreshMaxAge)
+	m.setCookie(c, m.opts.AccessCookieName, tokens.AccessToken, accessMaxAge, m.opts.HTTPOnly)
+	m.setCookie(c, m.opts.RefreshCookieName, tokens.RefreshToken, refreshMaxAge, m.opts.HTTPOnly)
+
+	// A new CSRF token comes with every new token pair. It has to be
+	// readable by the frontend so it can be echoed in CSRFHeaderName.
+	if csrf, err := generateState(); err == nil {
+		m.setCookie(c, m.opts.CSRFCookieName, csrf, refreshMaxAge, false)
+	}
 }
 
 func (m *cookieManager) ClearTokens(c *gin.Context) {
-	m.setCookie(c, m.opts.AccessCookieName, "", -1)
-	m.setCookie(c, m.opts.RefreshCookieName, "", -1)
+	m.setCookie(c, m.opts.AccessCookieName, "", -1, m.opts.HTTPOnly)
+	m.setCookie(c, m.opts.RefreshCookieName, "", -1, m.opts.HTTPOnly)
+	m.setCookie(c, m.opts.CSRFCookieName, "", -1, false)
+}
+
+func (m *cookieManager) AccessToken(c *gin.Context) string {
+	return m.cookieValue(c, m.opts.AccessCookieName)
+}
+
+func (m *cookieManager) RefreshToken(c *gin.Context) string {
+	return m.cookieValue(c, m.opts.RefreshCookieName)
 }
 
-func (m *cookieManager) setCookie(c *gin.Context, name, value string, maxAge int) {
+// ValidCSRF implements the double-submit check: the header must repeat the
+// CSRF cookie, which a cross-site page can neither read nor set.
+func (m *cookieManager) ValidCSRF(c *gin.Context) bool {
+	cookie := m.cookieValue(c, m.opts.CSRFCookieName)
+	header := strings.TrimSpace(c.GetHeader(CSRFHeaderName))
+	if cookie == "" || header == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
+
+func (m *cookieManager) cookieValue(c *gin.Context, name string) string {
+	value, err := c.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+func (m *cookieManager) setCookie(c *gin.Context, name, value string, maxAge int, httpOnly bool) {
 	cookie := &http.Cookie{
 		Name:     name,
 		Value:    value,
 		Path:     m.opts.Path,
 		Domain:   m.opts.Domain,
 		Secure:   m.opts.Secure,
-		HttpOnly: m.opts.HTTPOnly,
+		HttpOnly: httpOnly,
 		SameSite: m.opts.SameSite,
 	}
 
@@ -108,3 +156,16 @@ func durationOrSeconds(d time.Duration, seconds int64) int {
 	}
 	return 0
 }
+
+// ParseSameSite maps the COOKIE_SAMESITE setting to http.SameSite. Unknown
+// values fall back to Lax.
+func ParseSameSite(value string) http.SameSite {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
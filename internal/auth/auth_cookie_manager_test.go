@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRefreshTokenFromRequestCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cookies := NewCookieManager(CookieOptions{})
+	h := &AuthHandler{cookieMgr: cookies}
+
+	tests := []struct {
+		name      string
+		body      string
+		cookie    string
+		csrf      string
+		header    string
+		wantToken string
+		wantCode  int
+	}{
+		{name: "body token needs no csrf", body: `{"refresh_token":"dari-body"}`, wantToken: "dari-body"},
+		{name: "cookie with matching header", cookie: "dari-cookie", csrf: "abc", header: "abc", wantToken: "dari-cookie"},
+		{name: "cookie without header", cookie: "dari-cookie", csrf: "abc", wantCode: http.StatusForbidden},
+		{name: "cookie with wrong header", cookie: "dari-cookie", csrf: "abc", header: "xyz", wantCode: http.StatusForbidden},
+		{name: "header without csrf cookie", cookie: "dari-cookie", header: "abc", wantCode: http.StatusForbidden},
+		{name: "no token at all", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			if tt.cookie != "" {
+				c.Request.AddCookie(&http.Cookie{Name: "kerjakuy_refresh", Value: tt.cookie})
+			}
+			if tt.csrf != "" {
+				c.Request.AddCookie(&http.Cookie{Name: "kerjakuy_csrf", Value: tt.csrf})
+			}
+			if tt.header != "" {
+				c.Request.Header.Set(CSRFHeaderName, tt.header)
+			}
+
+			token, ok := h.refreshTokenFromRequest(c)
+			if tt.wantCode != 0 {
+				if ok || w.Code != tt.wantCode {
+					t.Fatalf("got ok=%v code=%d, want code %d", ok, w.Code, tt.wantCode)
+				}
+				return
+			}
+			if !ok || token != tt.wantToken {
+				t.Fatalf("got %q, %v; want %q", token, ok, tt.wantToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/mailer"
+	"kerjakuy/internal/user"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEmailChangeInvalid  = errors.New("tautan perubahan email tidak valid atau kedaluwarsa")
+	ErrEmailUnchanged      = errors.New("email baru sama dengan email saat ini")
+	ErrRecentLoginRequired = errors.New("login ulang atau masukkan kode verifikasi dua langkah untuk mengganti email")
+)
+
+// emailChangeReauthWindow is how recent a login must be for an account
+// without a password to change its email without a 2FA code.
+const emailChangeReauthWindow = 10 * time.Minute
+
+// RequestEmailChange mails a confirmation link to the new address and a
+// notice with a revert link to the current one. Nothing changes on the
+// account until the link is confirmed.
+func (s *authService) RequestEmailChange(ctx context.Context, userID, currentSessionID uuid.UUID, req ChangeEmailRequest, meta Metadata) error {
+	userDTO, err := s.userSvc.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	account, err := s.userSvc.GetByEmail(ctx, userDTO.Email)
+	if err != nil {
+		return err
+	}
+	if account.PasswordHash != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(req.Password)); err != nil {
+			s.recordSecurityEvent(ctx, SecurityEventEmailChange, &userID, SecurityOutcomeFailure, meta, map[string]interface{}{
+				"stage": "requested",
+			})
+			return ErrCurrentPasswordInvalid
+		}
+	} else if err := s.reauthenticateWithoutPassword(ctx, userID, currentSessionID, req.TwoFactorCode); err != nil {
+		s.recordSecurityEvent(ctx, SecurityEventEmailChange, &userID, SecurityOutcomeFailure, meta, map[string]interface{}{
+			"stage":  "requested",
+			"reason": "reauthentication",
+		})
+		return err
+	}
+
+	newEmail := NormalizeLoginEmail(req.NewEmail)
+	if strings.EqualFold(newEmail, account.Email) {
+		return ErrEmailUnchanged
+	}
+	inUse, err := s.userSvc.EmailInUse(ctx, newEmail)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		// Answer as if the request went through so the endpoint cannot be
+		// used to find registered addresses. The owner of the address hears
+		// about it instead.
+		s.recordSecurityEvent(ctx, SecurityEventEmailChange, &userID, SecurityOutcomeFailure, meta, map[string]interface{}{
+			"stage":  "requested",
+			"reason": "email_taken",
+		})
+		return s.mailer.Send(ctx, mailer.Message{
+			To:      []string{newEmail},
+			Subject: "Permintaan perubahan email KerjaKuy",
+			Body:    "Halo,\n\nSeseorang mencoba memakai alamat ini sebagai email login akun KerjaKuy lain. Alamat ini sudah terdaftar, jadi tidak ada yang berubah. Abaikan email ini jika Anda tidak mengenalinya.\n",
+		})
+	}
+
+	// Only the most recent request stays valid.
+	if err := s.emailChangeRepo.DeletePendingByUser(ctx, userID); err != nil {
+		return err
+	}
+	confirmToken, err := generateState()
+	if err != nil {
+		return err
+	}
+	revertToken, err := generateState()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	change := &models.EmailChangeRequest{
+		UserID:           userID,
+		OldEmail:         account.Email,
+		NewEmail:         newEmail,
+		ConfirmTokenHash: hashToken(confirmToken),
+		RevertTokenHash:  hashToken(revertToken),
+		ExpiresAt:        now.Add(s.emailChangeTTL),
+		RevertExpiresAt:  now.Add(s.emailChangeTTL + s.emailChangeRevertTTL),
+	}
+	if meta.IP != "" {
+		ip := meta.IP
+		change.RequestedIP = &ip
+	}
+	if err := s.emailChangeRepo.Create(ctx, change); err != nil {
+		return err
+	}
+
+	confirmLink := s.appURL + "/confirm-email-change?token=" + url.QueryEscape(confirmToken)
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      []string{newEmail},
+		Subject: "Konfirmasi email baru KerjaKuy",
+		Body: fmt.Sprintf(
+			"Halo %s,\n\nBuka tautan berikut dalam %d jam untuk memakai alamat ini sebagai email login KerjaKuy:\n\n%s\n\nSetelah dikonfirmasi, semua sesi akan dikeluarkan. Abaikan email ini jika Anda tidak memintanya.\n",
+			account.Name, int(s.emailChangeTTL.Hours()), confirmLink,
+		),
+	}); err != nil {
+		return err
+	}
+
+	revertLink := s.appURL + "/revert-email-change?token=" + url.QueryEscape(revertToken)
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      []string{account.Email},
+		Subject: "Permintaan perubahan email akun KerjaKuy",
+		Body: fmt.Sprintf(
+			"Halo %s,\n\nAda permintaan untuk mengganti email login akun Anda menjadi %s (dari IP %s).\n\nJika ini bukan Anda, buka tautan berikut untuk membatalkan atau mengembalikan perubahan. Tautan berlaku %d hari:\n\n%s\n",
+			account.Name, newEmail, meta.IP, int((s.emailChangeTTL+s.emailChangeRevertTTL).Hours()/24), revertLink,
+		),
+	}); err != nil {
+		return err
+	}
+
+	s.recordSecurityEvent(ctx, SecurityEventEmailChange, &userID, SecurityOutcomeSuccess, meta, map[string]interface{}{
+		"stage": "requested",
+	})
+	return nil
+}
+
+// reauthenticateWithoutPassword stands in for the password check on OAuth
+// and magic-link accounts, so a stolen session alone cannot move the login
+// email: either a 2FA code or a session signed in within
+// emailChangeReauthWindow.
+func (s *authService) reauthenticateWithoutPassword(ctx context.Context, userID, sessionID uuid.UUID, code string) error {
+	if code != "" {
+		tf, err := s.findEnabledTwoFactor(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return s.verifyManagedSecondFactor(ctx, tf, code)
+	}
+	session, err := s.sessionRepo.FindActiveByFamily(ctx, userID, sessionID, time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRecentLoginRequired
+	}
+	if err != nil {
+		return err
+	}
+	if time.Since(session.SignedInAt) > emailChangeReauthWindow {
+		return ErrRecentLoginRequired
+	}
+	return nil
+}
+
+// ConfirmEmailChange switches the login email to the confirmed address,
+// which counts as verified, and signs the account out everywhere. Links
+// and password resets sent to the old address stop working.
+func (s *authService) ConfirmEmailChange(ctx context.Context, token string, meta Metadata) (*user.UserDTO, error) {
+	change, err := s.emailChangeRepo.ApplyConfirm(ctx, hashToken(token), time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEmailChangeInvalid
+		}
+		return nil, err
+	}
+
+	// Sessions end after the commit: access tokens are revoked through the
+	// revocation store, which is not part of the transaction.
+	if err := s.SignOutEverywhere(ctx, change.UserID); err != nil {
+		return nil, err
+	}
+	s.claimInvitations(ctx, change.UserID, change.NewEmail)
+	s.recordSecurityEvent(ctx, SecurityEventEmailChange, &change.UserID, SecurityOutcomeSuccess, meta, map[string]interface{}{
+		"stage": "confirmed",
+	})
+	return s.userSvc.GetByID(ctx, change.UserID)
+}
+
+// RevertEmailChange is the old address saying "this was not me". Whoever
+// made the change likely holds the password and a session, so besides
+// cancelling or undoing the change it revokes personal access tokens and
+// unlinks identities and 2FA added since the request. A confirmed change
+// also loses its password and all 2FA. Either way every session ends.
+func (s *authService) RevertEmailChange(ctx context.Context, token string, meta Metadata) error {
+	change, err := s.emailChangeRepo.ApplyRevert(ctx, hashToken(token), time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrEmailChangeInvalid
+		}
+		return err
+	}
+
+	if err := s.SignOutEverywhere(ctx, change.UserID); err != nil {
+		return err
+	}
+	s.recordSecurityEvent(ctx, SecurityEventEmailChange, &change.UserID, SecurityOutcomeSuccess, meta, map[string]interface{}{
+		"stage":     "reverted",
+		"confirmed": change.ConfirmedAt != nil,
+	})
+	return nil
+}
@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/user"
+
+	"github.com/google/uuid"
+)
+
+var linkTokenPattern = regexp.MustCompile(`\?token=(\S+)`)
+
+// emailChangeAuth is newTestAuth with an email change repository and a
+// signed-in password account, ana@example.com.
+func emailChangeAuth(t *testing.T) (*testAuth, *models.User, *AuthResponse) {
+	t.Helper()
+	ta := newTestAuth(t)
+	ta.emailChangeRepo = &fakeEmailChanges{ta: ta}
+	ta.invitations = &fakeInvitationClaimer{}
+	u := ta.users.add(t, "ana@example.com", "rahasia123")
+	login, err := ta.Login(context.Background(), LoginRequest{Email: u.Email, Password: "rahasia123"}, Metadata{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	return ta, u, login
+}
+
+// requestChange asks to move u to newEmail and returns the confirm and
+// revert tokens from the two mails.
+func (ta *testAuth) requestChange(t *testing.T, u *models.User, newEmail string) (string, string) {
+	t.Helper()
+	before := len(ta.mail.sent)
+	if err := ta.RequestEmailChange(context.Background(), u.ID, uuid.Nil, ChangeEmailRequest{NewEmail: newEmail, Password: "rahasia123"}, Metadata{IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("RequestEmailChange: %v", err)
+	}
+	sent := ta.mail.waitSent(t, before+2)[before:]
+	return linkToken(t, sent[0].Body), linkToken(t, sent[1].Body)
+}
+
+func linkToken(t *testing.T, body string) string {
+	t.Helper()
+	m := linkTokenPattern.FindStringSubmatch(body)
+	if m == nil {
+		t.Fatalf("no link in mail: %s", body)
+	}
+	token, err := url.QueryUnescape(m[1])
+	if err != nil {
+		t.Fatalf("unescape token: %v", err)
+	}
+	return token
+}
+
+func TestEmailChange(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ta *testAuth, u *models.User, login *AuthResponse)
+	}{
+		{
+			name: "confirm moves the login email and signs out everywhere",
+			run: func(t *testing.T, ta *testAuth, u *models.User, login *AuthResponse) {
+				ta.resets.rows = append(ta.resets.rows, &models.PasswordResetToken{UserID: u.ID, TokenHash: "sent-to-old-address"})
+				confirm, _ := ta.requestChange(t, u, "Ana.Baru@Example.com ")
+				if u.Email != "ana@example.com" {
+					t.Fatalf("email changed before confirmation: %s", u.Email)
+				}
+
+				dto, err := ta.ConfirmEmailChange(context.Background(), confirm, Metadata{})
+				if err != nil {
+					t.Fatalf("ConfirmEmailChange: %v", err)
+				}
+				if dto.Email != "ana.baru@example.com" || u.EmailVerifiedAt == nil {
+					t.Errorf("email = %q, verified = %v; want the normalized new address, verified", dto.Email, u.EmailVerifiedAt)
+				}
+				if len(ta.resets.rows) != 0 {
+					t.Errorf("password reset sent to the old address still valid")
+				}
+				if _, err := ta.ValidateAccessToken(login.Tokens.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+					t.Errorf("access token after confirm: %v, want %v", err, ErrSessionRevoked)
+				}
+				if _, err := ta.ConfirmEmailChange(context.Background(), confirm, Metadata{}); !errors.Is(err, ErrEmailChangeInvalid) {
+					t.Errorf("second confirm error = %v, want %v", err, ErrEmailChangeInvalid)
+				}
+				if _, err := ta.Login(context.Background(), LoginRequest{Email: "ana@example.com", Password: "rahasia123"}, Metadata{}); err == nil {
+					t.Errorf("old address still signs in")
+				}
+			},
+		},
+		{
+			name: "a newer request invalidates the earlier link",
+			run: func(t *testing.T, ta *testAuth, u *models.User, login *AuthResponse) {
+				first, _ := ta.requestChange(t, u, "satu@example.com")
+				second, _ := ta.requestChange(t, u, "dua@example.com")
+				if _, err := ta.ConfirmEmailChange(context.Background(), first, Metadata{}); !errors.Is(err, ErrEmailChangeInvalid) {
+					t.Fatalf("earlier link error = %v, want %v", err, ErrEmailChangeInvalid)
+				}
+				if _, err := ta.ConfirmEmailChange(context.Background(), second, Metadata{}); err != nil {
+					t.Fatalf("latest link: %v", err)
+				}
+				if u.Email != "dua@example.com" {
+					t.Errorf("email = %q, want dua@example.com", u.Email)
+				}
+			},
+		},
+		{
+			name: "revert before confirm cancels the change",
+			run: func(t *testing.T, ta *testAuth, u *models.User, login *AuthResponse) {
+				confirm, revert := ta.requestChange(t, u, "baru@example.com")
+				ta.tokens.rows = append(ta.tokens.rows, &models.PersonalAccessToken{UserID: u.ID})
+				ta.enableTwoFactor(t, u)
+
+				if err := ta.RevertEmailChange(context.Background(), revert, Metadata{}); err != nil {
+					t.Fatalf("RevertEmailChange: %v", err)
+				}
+				if _, err := ta.ConfirmEmailChange(context.Background(), confirm, Metadata{}); !errors.Is(err, ErrEmailChangeInvalid) {
+					t.Errorf("confirm after revert error = %v, want %v", err, ErrEmailChangeInvalid)
+				}
+				if u.Email != "ana@example.com" || u.PasswordHash == "" {
+					t.Errorf("pending revert should leave email and password alone")
+				}
+				if len(ta.tokens.rows) != 0 || ta.twoFactor.byUser[u.ID] != nil {
+					t.Errorf("access tokens and 2FA added since the request survived the revert")
+				}
+				if _, err := ta.ValidateAccessToken(login.Tokens.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+					t.Errorf("access token after revert: %v, want %v", err, ErrSessionRevoked)
+				}
+			},
+		},
+		{
+			name: "revert after confirm restores the old email and drops the password",
+			run: func(t *testing.T, ta *testAuth, u *models.User, login *AuthResponse) {
+				confirm, revert := ta.requestChange(t, u, "baru@example.com")
+				if _, err := ta.ConfirmEmailChange(context.Background(), confirm, Metadata{}); err != nil {
+					t.Fatalf("ConfirmEmailChange: %v", err)
+				}
+				ta.enableTwoFactor(t, u)
+
+				if err := ta.RevertEmailChange(context.Background(), revert, Metadata{}); err != nil {
+					t.Fatalf("RevertEmailChange: %v", err)
+				}
+				if u.Email != "ana@example.com" || u.PasswordHash != "" || ta.twoFactor.byUser[u.ID] != nil {
+					t.Errorf("email = %q, password kept = %v, 2FA kept = %v", u.Email, u.PasswordHash != "", ta.twoFactor.byUser[u.ID] != nil)
+				}
+				if err := ta.RevertEmailChange(context.Background(), revert, Metadata{}); !errors.Is(err, ErrEmailChangeInvalid) {
+					t.Errorf("second revert error = %v, want %v", err, ErrEmailChangeInvalid)
+				}
+			},
+		},
+		{
+			name: "expired links are refused",
+			run: func(t *testing.T, ta *testAuth, u *models.User, login *AuthResponse) {
+				confirm, revert := ta.requestChange(t, u, "baru@example.com")
+				change := ta.emailChangeRepo.(*fakeEmailChanges).rows[0]
+				change.ExpiresAt = time.Now().Add(-time.Minute)
+				if _, err := ta.ConfirmEmailChange(context.Background(), confirm, Metadata{}); !errors.Is(err, ErrEmailChangeInvalid) {
+					t.Errorf("expired confirm error = %v, want %v", err, ErrEmailChangeInvalid)
+				}
+				change.RevertExpiresAt = time.Now().Add(-time.Minute)
+				if err := ta.RevertEmailChange(context.Background(), revert, Metadata{}); !errors.Is(err, ErrEmailChangeInvalid) {
+					t.Errorf("expired revert error = %v, want %v", err, ErrEmailChangeInvalid)
+				}
+				if u.Email != "ana@example.com" {
+					t.Errorf("email = %q, want it unchanged", u.Email)
+				}
+			},
+		},
+		{
+			name: "address taken after the request is refused on confirm",
+			run: func(t *testing.T, ta *testAuth, u *models.User, login *AuthResponse) {
+				confirm, _ := ta.requestChange(t, u, "baru@example.com")
+				ta.users.add(t, "baru@example.com", "rahasia456")
+				if _, err := ta.ConfirmEmailChange(context.Background(), confirm, Metadata{}); !errors.Is(err, user.ErrEmailTaken) {
+					t.Errorf("confirm error = %v, want %v", err, user.ErrEmailTaken)
+				}
+				if u.Email != "ana@example.com" {
+					t.Errorf("email = %q, want it unchanged", u.Email)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta, u, login := emailChangeAuth(t)
+			tt.run(t, ta, u, login)
+		})
+	}
+}
+
+func TestEmailChangeDoesNotRevealTakenAddress(t *testing.T) {
+	ta, u, _ := emailChangeAuth(t)
+	ta.users.add(t, "budi@example.com", "rahasia456")
+
+	err := ta.RequestEmailChange(context.Background(), u.ID, uuid.Nil, ChangeEmailRequest{NewEmail: "BUDI@example.com", Password: "rahasia123"}, Metadata{})
+	if err != nil {
+		t.Fatalf("RequestEmailChange = %v, want the same answer as for a free address", err)
+	}
+	sent := ta.mail.waitSent(t, 1)
+	if sent[0].To[0] != "budi@example.com" || strings.Contains(sent[0].Body, "token=") {
+		t.Errorf("owner of the address should get a notice without a link, got %+v", sent[0])
+	}
+	if rows := ta.emailChangeRepo.(*fakeEmailChanges).rows; len(rows) != 0 {
+		t.Errorf("change request stored for a taken address")
+	}
+	if ta.mail.sentTo("ana@example.com") != 0 {
+		t.Errorf("requester was told about the taken address")
+	}
+}
+
+func TestEmailChangeReauthentication(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   bool
+		signedInAt time.Duration
+		twoFactor  bool
+		code       func(secret string) string
+		noSession  bool
+		err        error
+	}{
+		{name: "password account with a wrong password", password: true, code: func(string) string { return "" }, err: ErrCurrentPasswordInvalid},
+		{name: "oauth account with a recent login", signedInAt: time.Minute},
+		{name: "oauth account with an old login", signedInAt: time.Hour, err: ErrRecentLoginRequired},
+		{name: "oauth account without a session", noSession: true, err: ErrRecentLoginRequired},
+		{name: "oauth account with an old login and a 2FA code", signedInAt: time.Hour, twoFactor: true, code: func(secret string) string { return currentCode(t, secret) }},
+		{name: "oauth account with a wrong 2FA code", signedInAt: time.Minute, twoFactor: true, code: func(string) string { return "000000" }, err: ErrTwoFactorInvalidCode},
+		{name: "oauth account sending a code without 2FA", signedInAt: time.Minute, code: func(string) string { return "123456" }, err: ErrTwoFactorNotEnabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta, u, _ := emailChangeAuth(t)
+			if !tt.password {
+				u.PasswordHash = ""
+			}
+			ta.sessions.rows[0].SignedInAt = time.Now().Add(-tt.signedInAt)
+			sessionID := ta.sessions.rows[0].FamilyID
+			if tt.noSession {
+				sessionID = uuid.Nil
+			}
+			var secret string
+			if tt.twoFactor {
+				secret = ta.enableTwoFactor(t, u)
+			}
+			req := ChangeEmailRequest{NewEmail: "baru@example.com", Password: "salah"}
+			if tt.code != nil {
+				req.TwoFactorCode = tt.code(secret)
+			}
+
+			err := ta.RequestEmailChange(context.Background(), u.ID, sessionID, req, Metadata{})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("RequestEmailChange error = %v, want %v", err, tt.err)
+			}
+			if stored := len(ta.emailChangeRepo.(*fakeEmailChanges).rows); (stored == 1) != (tt.err == nil) {
+				t.Errorf("stored requests = %d", stored)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"kerjakuy/internal/pkg/mailer"
+	"kerjakuy/internal/user"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEmailVerificationInvalid   = errors.New("tautan verifikasi email tidak valid atau kedaluwarsa")
+	ErrEmailAlreadyVerified       = errors.New("email sudah terverifikasi")
+	ErrVerificationEmailThrottled = errors.New("email verifikasi baru saja dikirim, coba lagi nanti")
+)
+
+func (s *authService) VerifyEmail(ctx context.Context, token string) (*user.UserDTO, error) {
+	claims, err := s.tokens.ValidateToken(token, tokenTypeEmailVerification)
+	if err != nil {
+		return nil, ErrEmailVerificationInvalid
+	}
+	if err := s.userSvc.MarkEmailVerified(ctx, claims.UserID, claims.Email, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEmailVerificationInvalid
+		}
+		return nil, err
+	}
+	s.claimInvitations(ctx, claims.UserID, claims.Email)
+	return s.userSvc.GetByID(ctx, claims.UserID)
+}
+
+func (s *authService) ResendVerificationEmail(ctx context.Context, userID uuid.UUID) error {
+	userDTO, err := s.userSvc.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if userDTO.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+	return s.sendVerificationEmail(ctx, userDTO)
+}
+
+// VerificationResendInterval is the minimum gap between two verification
+// emails for the same account.
+func (s *authService) VerificationResendInterval() time.Duration {
+	return s.verificationResendInterval
+}
+
+// claimInvitations turns workspace invitations sent to an address the
+// account has just proven to own into memberships. It is best effort: the
+// invitations stay pending and can still be accepted from the email.
+func (s *authService) claimInvitations(ctx context.Context, userID uuid.UUID, email string) {
+	if err := s.invitations.ClaimInvitations(ctx, userID, email); err != nil {
+		s.logger.Error("failed to claim invitations", "error", err, "user_id", userID)
+	}
+}
+
+func (s *authService) sendVerificationEmail(ctx context.Context, userDTO *user.UserDTO) error {
+	reserved, err := s.userSvc.ReserveVerificationEmail(ctx, userDTO.ID, time.Now(), s.verificationResendInterval)
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		return ErrVerificationEmailThrottled
+	}
+
+	token, err := s.tokens.GenerateVerificationToken(Claims{UserID: userDTO.ID, Email: userDTO.Email}, s.emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	link := s.appURL + "/verify-email?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      []string{userDTO.Email},
+		Subject: "Verifikasi email KerjaKuy",
+		Body: fmt.Sprintf(
+			"Halo %s,\n\nKonfirmasi alamat email Anda dengan membuka tautan berikut dalam %d jam:\n\n%s\n\nAbaikan email ini jika Anda tidak mendaftar di KerjaKuy.\n",
+			userDTO.Name, int(s.emailVerificationTTL.Hours()), link,
+		),
+	})
+}
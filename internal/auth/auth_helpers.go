@@ -1,14 +1,20 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"time"
 
+	"kerjakuy/internal/pkg/mailer"
 	"kerjakuy/internal/user"
 )
 
+// mailTimeout bounds a send made by sendMailAsync.
+const mailTimeout = 30 * time.Second
+
 func buildAuthResponse(user *user.UserDTO, tokens *AuthTokens) *AuthResponse {
 	return &AuthResponse{
 		User:   *user,
@@ -28,3 +34,16 @@ func generateState() (string, error) {
 	}
 	return base64.RawURLEncoding.EncodeToString(b), nil
 }
+
+// sendMailAsync delivers msg off the request path, so neither the response
+// time nor a delivery failure tells the caller whether an account exists.
+// Failures are logged.
+func (s *authService) sendMailAsync(ctx context.Context, msg mailer.Message) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), mailTimeout)
+	go func() {
+		defer cancel()
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			s.logger.Error("failed to send mail", "error", err, "subject", msg.Subject)
+		}
+	}()
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/mailer"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	loginScopeEmail = "email"
+	loginScopeIP    = "ip"
+	// loginScopeChallenge counts wrong codes per 2FA challenge (its jti).
+	loginScopeChallenge = "2fa"
+	// loginScopeTwoFactorUser counts wrong codes per account when a
+	// signed-in user manages 2FA.
+	loginScopeTwoFactorUser = "2fa_user"
+
+	// Failures before backoff starts, the first backoff step, and how many
+	// more failures an IP gets than a single account (NAT, offices).
+	loginFreeFailures = 2
+	loginBackoffBase  = 2 * time.Second
+	loginIPFactor     = 4
+	loginMaxBlock     = 24 * time.Hour
+	loginWindow       = 24 * time.Hour
+)
+
+var (
+	ErrInvalidCredentials = errors.New("email atau password salah")
+	ErrLoginThrottled     = errors.New("terlalu banyak percobaan login, coba lagi nanti")
+)
+
+// LoginThrottledError tells the client how long to wait. It is returned for
+// unknown emails as well, so it does not reveal whether an account exists.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return ErrLoginThrottled.Error()
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrLoginThrottled
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// compareDummyPassword spends the same bcrypt time as a real check so that
+// response times do not leak which emails are registered.
+func compareDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("kerjakuy-dummy-password"), bcrypt.DefaultCost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+// NormalizeLoginEmail is the form an email takes as a login throttle key.
+func NormalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (s *authService) checkLoginThrottle(ctx context.Context, email, ip string) error {
+	now := time.Now()
+	var wait time.Duration
+	for _, k := range loginThrottleKeys(email, ip) {
+		throttle, err := s.throttleRepo.FindBlocked(ctx, k.scope, k.key, now)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+		if d := throttle.BlockedUntil.Sub(now); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		return &LoginThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// registerLoginFailure bumps both counters and blocks further attempts with
+// exponential backoff. Reaching loginMaxFailures for an email locks it and
+// notifies the owner, if there is one.
+func (s *authService) registerLoginFailure(ctx context.Context, email string, account *models.User, meta Metadata) error {
+	now := time.Now()
+	for _, k := range loginThrottleKeys(email, meta.IP) {
+		maxFailures := s.loginMaxFailures
+		if k.scope == loginScopeIP {
+			maxFailures *= loginIPFactor
+		}
+
+		throttle, err := s.throttleRepo.Increment(ctx, k.scope, k.key, now, now.Add(-loginWindow))
+		if err != nil {
+			return err
+		}
+		delay, locked := s.loginBlockDuration(throttle.Failures, maxFailures)
+		if delay == 0 {
+			continue
+		}
+		if err := s.throttleRepo.SetBlockedUntil(ctx, throttle.ID, now.Add(delay)); err != nil {
+			return err
+		}
+		if locked && k.scope == loginScopeEmail && throttle.Failures == maxFailures && account != nil {
+			s.notifyLockout(ctx, account, delay, meta)
+		}
+	}
+	return nil
+}
+
+// resetLoginFailures clears both counters after a completed login.
+func (s *authService) resetLoginFailures(ctx context.Context, email, ip string) error {
+	for _, k := range loginThrottleKeys(email, ip) {
+		if err := s.throttleRepo.Reset(ctx, k.scope, k.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loginBlockDuration returns how long to refuse logins after the given number
+// of failures and whether that counts as a lockout.
+func (s *authService) loginBlockDuration(failures, maxFailures int) (time.Duration, bool) {
+	if failures >= maxFailures {
+		d := s.loginLockout << min(failures-maxFailures, 10)
+		return min(d, loginMaxBlock), true
+	}
+	if failures < loginFreeFailures {
+		return 0, false
+	}
+	return loginBackoffBase << (failures - loginFreeFailures), false
+}
+
+func (s *authService) notifyLockout(ctx context.Context, account *models.User, lockedFor time.Duration, meta Metadata) {
+	s.recordSecurityEvent(ctx, SecurityEventLoginLockout, &account.ID, SecurityOutcomeBlocked, meta, map[string]interface{}{
+		"locked_for_seconds": int(lockedFor.Seconds()),
+	})
+
+	ip := meta.IP
+	if ip == "" {
+		ip = "tidak diketahui"
+	}
+	s.sendMailAsync(ctx, mailer.Message{
+		To:      []string{account.Email},
+		Subject: "Akun KerjaKuy Anda dikunci sementara",
+		Body: fmt.Sprintf(
+			"Halo %s,\n\nKami mendeteksi beberapa percobaan login yang gagal ke akun Anda (IP terakhir: %s). Login dikunci selama %d menit.\n\nJika itu bukan Anda, segera reset password Anda melalui %s/forgot-password.\n",
+			account.Name, ip, int(lockedFor.Minutes()), s.appURL,
+		),
+	})
+}
+
+type loginThrottleKey struct {
+	scope string
+	key   string
+}
+
+// loginThrottleKeys normalizes the email itself, so every caller counts
+// against the same row however the address was typed.
+func loginThrottleKeys(email, ip string) []loginThrottleKey {
+	keys := []loginThrottleKey{{scope: loginScopeEmail, key: NormalizeLoginEmail(email)}}
+	if ip != "" {
+		keys = append(keys, loginThrottleKey{scope: loginScopeIP, key: ip})
+	}
+	return keys
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLoginBlockDuration(t *testing.T) {
+	s := &authService{loginLockout: 15 * time.Minute}
+	tests := []struct {
+		failures   int
+		max        int
+		wantDelay  time.Duration
+		wantLocked bool
+	}{
+		{failures: 1, max: 5},
+		{failures: 2, max: 5, wantDelay: 2 * time.Second},
+		{failures: 4, max: 5, wantDelay: 8 * time.Second},
+		{failures: 5, max: 5, wantDelay: 15 * time.Minute, wantLocked: true},
+		{failures: 6, max: 5, wantDelay: 30 * time.Minute, wantLocked: true},
+		{failures: 40, max: 5, wantDelay: loginMaxBlock, wantLocked: true},
+	}
+	for _, tt := range tests {
+		delay, locked := s.loginBlockDuration(tt.failures, tt.max)
+		if delay != tt.wantDelay || locked != tt.wantLocked {
+			t.Errorf("loginBlockDuration(%d, %d) = %v, %v; want %v, %v", tt.failures, tt.max, delay, locked, tt.wantDelay, tt.wantLocked)
+		}
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	const ip = "10.0.0.1"
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ta *testAuth)
+	}{
+		{
+			name: "differently cased emails share one counter",
+			run: func(t *testing.T, ta *testAuth) {
+				for _, email := range []string{"ana@example.com", " ANA@example.com", "Ana@Example.COM "} {
+					ta.throttle.unblock()
+					ta.Login(context.Background(), LoginRequest{Email: email, Password: "salah"}, Metadata{IP: ip})
+				}
+				if got := ta.throttle.failures(loginScopeEmail, "ana@example.com"); got != 3 {
+					t.Errorf("email failures = %d, want 3", got)
+				}
+			},
+		},
+		{
+			name: "differently cased email signs in to the same account",
+			run: func(t *testing.T, ta *testAuth) {
+				ta.Login(context.Background(), LoginRequest{Email: "ana@example.com", Password: "salah"}, Metadata{IP: ip})
+				if _, err := ta.Login(context.Background(), LoginRequest{Email: " Ana@Example.COM ", Password: "rahasia123"}, Metadata{IP: ip}); err != nil {
+					t.Fatalf("Login: %v", err)
+				}
+				if got := ta.throttle.failures(loginScopeEmail, "ana@example.com"); got != 0 {
+					t.Errorf("email failures = %d, want 0", got)
+				}
+			},
+		},
+		{
+			name: "blocked login is refused even with the right password",
+			run: func(t *testing.T, ta *testAuth) {
+				for i := 0; i < 2; i++ {
+					ta.Login(context.Background(), LoginRequest{Email: "ana@example.com", Password: "salah"}, Metadata{IP: ip})
+				}
+				_, err := ta.Login(context.Background(), LoginRequest{Email: "ana@example.com", Password: "rahasia123"}, Metadata{IP: ip})
+				var throttled *LoginThrottledError
+				if !errors.As(err, &throttled) || throttled.RetryAfter <= 0 {
+					t.Fatalf("Login error = %v, want LoginThrottledError", err)
+				}
+			},
+		},
+		{
+			name: "successful login clears email and ip counters",
+			run: func(t *testing.T, ta *testAuth) {
+				ta.Login(context.Background(), LoginRequest{Email: "ana@example.com", Password: "salah"}, Metadata{IP: ip})
+				if _, err := ta.Login(context.Background(), LoginRequest{Email: "ana@example.com", Password: "rahasia123"}, Metadata{IP: ip}); err != nil {
+					t.Fatalf("Login: %v", err)
+				}
+				if got := ta.throttle.failures(loginScopeEmail, "ana@example.com"); got != 0 {
+					t.Errorf("email failures = %d, want 0", got)
+				}
+				if got := ta.throttle.failures(loginScopeIP, ip); got != 0 {
+					t.Errorf("ip failures = %d, want 0", got)
+				}
+			},
+		},
+		{
+			name: "lockout notifies the owner once",
+			run: func(t *testing.T, ta *testAuth) {
+				for i := 0; i < ta.loginMaxFailures+2; i++ {
+					ta.throttle.unblock()
+					ta.Login(context.Background(), LoginRequest{Email: "ana@example.com", Password: "salah"}, Metadata{IP: ip})
+				}
+				ta.mail.waitSent(t, 1)
+				if got := ta.events.count(SecurityEventLoginLockout); got != 1 {
+					t.Errorf("lockout events = %d, want 1", got)
+				}
+			},
+		},
+		{
+			name: "unknown email is throttled like a real one",
+			run: func(t *testing.T, ta *testAuth) {
+				for i := 0; i < 2; i++ {
+					ta.Login(context.Background(), LoginRequest{Email: "nobody@example.com", Password: "salah"}, Metadata{IP: ip})
+				}
+				_, err := ta.Login(context.Background(), LoginRequest{Email: "nobody@example.com", Password: "salah"}, Metadata{IP: ip})
+				if !errors.Is(err, ErrLoginThrottled) {
+					t.Fatalf("Login error = %v, want %v", err, ErrLoginThrottled)
+				}
+				ta.mail.waitSent(t, 0)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			ta.users.add(t, "ana@example.com", "rahasia123")
+			tt.run(t, ta)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"kerjakuy/internal/pkg/mailer"
+
+	"gorm.io/gorm"
+)
+
+const (
+	loginScopeMagicLink = "magic_link"
+
+	// At most magicLinkMaxRequests links per email within magicLinkWindow.
+	magicLinkMaxRequests = 3
+	magicLinkWindow      = 15 * time.Minute
+)
+
+var ErrMagicLinkInvalid = errors.New("tautan login tidak valid atau kedaluwarsa")
+
+// RequestMagicLink emails a single-use login link. Like ForgotPassword it
+// succeeds for unknown emails, and the rate limit counts every request for
+// the address so a 429 does not reveal whether an account exists either.
+func (s *authService) RequestMagicLink(ctx context.Context, email string, meta Metadata) error {
+	email = NormalizeLoginEmail(email)
+	now := time.Now()
+	throttle, err := s.throttleRepo.Increment(ctx, loginScopeMagicLink, email, now, now.Add(-magicLinkWindow))
+	if err != nil {
+		return err
+	}
+	if throttle.Failures > magicLinkMaxRequests {
+		return &LoginThrottledError{RetryAfter: magicLinkWindow}
+	}
+
+	account, err := s.userSvc.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := s.tokens.GenerateMagicLinkToken(Claims{UserID: account.ID, Email: account.Email}, s.magicLinkTTL)
+	if err != nil {
+		return err
+	}
+	link := s.appURL + "/magic-link?token=" + url.QueryEscape(token)
+	s.sendMailAsync(ctx, mailer.Message{
+		To:      []string{account.Email},
+		Subject: "Tautan login KerjaKuy",
+		Body: fmt.Sprintf(
+			"Halo %s,\n\nBuka tautan berikut dalam %d menit untuk masuk ke KerjaKuy:\n\n%s\n\nTautan hanya bisa dipakai sekali. Permintaan ini berasal dari IP %s. Abaikan email ini jika Anda tidak memintanya.\n",
+			account.Name, int(s.magicLinkTTL.Minutes()), link, meta.IP,
+		),
+	})
+	return nil
+}
+
+// ConsumeMagicLink logs in with a link from RequestMagicLink. Opening the
+// link proves control of the mailbox, so the email is marked verified; 2FA
+// still applies through completeLogin.
+func (s *authService) ConsumeMagicLink(ctx context.Context, token string, meta Metadata) (*AuthResponse, error) {
+	claims, err := s.tokens.ValidateToken(token, tokenTypeMagicLink)
+	if err != nil {
+		return nil, ErrMagicLinkInvalid
+	}
+	fresh, err := s.revocations.ConsumeToken(ctx, claims.TokenID, claims.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	userDTO, err := s.userSvc.GetByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMagicLinkInvalid
+		}
+		return nil, err
+	}
+	// A link sent to an address the account no longer uses is dead.
+	if userDTO.Email != claims.Email {
+		return nil, ErrMagicLinkInvalid
+	}
+	if userDTO.EmailVerifiedAt == nil {
+		verifiedAt := time.Now()
+		if err := s.userSvc.MarkEmailVerified(ctx, userDTO.ID, userDTO.Email, verifiedAt); err != nil {
+			return nil, err
+		}
+		userDTO.EmailVerifiedAt = &verifiedAt
+		s.claimInvitations(ctx, userDTO.ID, userDTO.Email)
+	}
+	return s.completeLogin(ctx, userDTO, LoginMethodMagicLink, meta)
+}
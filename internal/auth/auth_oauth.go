@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/user"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (s *authService) BeginOAuth(ctx context.Context, provider, redirectURI string) (*OAuthRedirectResponse, error) {
+	p, err := s.oauth.Get(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	if redirectURI == "" {
+		redirectURI = p.RedirectURL()
+	}
+	if redirectURI == "" || redirectURI != p.RedirectURL() {
+		return nil, ErrOAuthInvalidRedirect
+	}
+
+	state, err := generateState()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := generateState()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state, pkceChallenge(verifier), redirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.stateRepo.Create(ctx, &models.OAuthState{
+		StateHash:    hashToken(state),
+		Provider:     provider,
+		CodeVerifier: verifier,
+		RedirectURI:  redirectURI,
+		ExpiresAt:    time.Now().Add(s.oauthStateTTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &OAuthRedirectResponse{
+		Provider:         provider,
+		AuthorizationURL: authURL,
+		State:            state,
+	}, nil
+}
+
+func (s *authService) HandleOAuthCallback(ctx context.Context, provider, code, state string, meta Metadata) (*AuthResponse, error) {
+	p, err := s.oauth.Get(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.stateRepo.Consume(ctx, hashToken(state))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthInvalidState
+		}
+		return nil, err
+	}
+	if pending.Provider != provider || pending.ExpiresAt.Before(time.Now()) {
+		return nil, ErrOAuthInvalidState
+	}
+
+	info, err := p.Exchange(ctx, code, pending.CodeVerifier, pending.RedirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	userDTO, err := s.resolveOAuthUser(ctx, provider, info, meta)
+	if err != nil {
+		return nil, err
+	}
+	return s.completeLogin(ctx, userDTO, LoginMethodOAuth, meta)
+}
+
+// resolveOAuthUser returns the account already linked to the external
+// identity, or links it to the account with the same verified email, or
+// creates a new password-less account.
+func (s *authService) resolveOAuthUser(ctx context.Context, provider string, info *OAuthUserInfo, meta Metadata) (*user.UserDTO, error) {
+	identity, err := s.identityRepo.FindByProviderSubject(ctx, provider, info.Subject)
+	if err == nil {
+		return s.userSvc.GetByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if info.Email == "" || !info.EmailVerified {
+		return nil, ErrOAuthEmailUnverified
+	}
+
+	var userDTO *user.UserDTO
+	account, err := s.userSvc.GetByEmail(ctx, info.Email)
+	switch {
+	case err == nil:
+		if account.EmailVerifiedAt == nil {
+			if err := s.claimUnverifiedAccount(ctx, account.ID, account.Email); err != nil {
+				return nil, err
+			}
+			verifiedAt := time.Now()
+			account.EmailVerifiedAt = &verifiedAt
+		}
+		userDTO = s.userSvc.ToDTO(account)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		name := info.Name
+		if name == "" {
+			name = strings.SplitN(info.Email, "@", 2)[0]
+		}
+		userDTO, err = s.userSvc.Register(ctx, user.CreateUserRequest{Name: name, Email: info.Email}, "")
+		if err != nil {
+			return nil, err
+		}
+		verifiedAt := time.Now()
+		if err := s.userSvc.MarkEmailVerified(ctx, userDTO.ID, userDTO.Email, verifiedAt); err != nil {
+			return nil, err
+		}
+		userDTO.EmailVerifiedAt = &verifiedAt
+		s.claimInvitations(ctx, userDTO.ID, userDTO.Email)
+		if info.AvatarURL != nil {
+			userDTO, err = s.userSvc.UpdateProfile(ctx, userDTO.ID, user.UpdateUserProfileRequest{AvatarURL: info.AvatarURL})
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, err
+	}
+
+	if err := s.identityRepo.Create(ctx, &models.UserIdentity{
+		UserID:   userDTO.ID,
+		Provider: provider,
+		Subject:  info.Subject,
+		Email:    info.Email,
+	}); err != nil {
+		return nil, err
+	}
+	s.recordSecurityEvent(ctx, SecurityEventOAuthLink, &userDTO.ID, SecurityOutcomeSuccess, meta, map[string]interface{}{
+		"provider": provider,
+	})
+	return userDTO, nil
+}
+
+// claimUnverifiedAccount handles a provider-verified email that matches a
+// local account whose owner never proved the address. Whoever registered it
+// may not own the mailbox, so their password and sessions are dropped before
+// the identity is linked.
+func (s *authService) claimUnverifiedAccount(ctx context.Context, userID uuid.UUID, email string) error {
+	if err := s.userSvc.ClearPassword(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.SignOutEverywhere(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.userSvc.MarkEmailVerified(ctx, userID, email, time.Now()); err != nil {
+		return err
+	}
+	s.claimInvitations(ctx, userID, email)
+	return nil
+}
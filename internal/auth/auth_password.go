@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/mailer"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPasswordResetInvalid   = errors.New("token reset password tidak valid atau kedaluwarsa")
+	ErrCurrentPasswordInvalid = errors.New("password saat ini salah")
+)
+
+// ForgotPassword always succeeds for well-formed input so the response does
+// not reveal whether an account exists for the email; the mail goes out in
+// the background for the same reason.
+func (s *authService) ForgotPassword(ctx context.Context, email string, meta Metadata) error {
+	account, err := s.userSvc.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// Only the most recent link stays valid.
+	if err := s.resetRepo.DeleteByUser(ctx, account.ID); err != nil {
+		return err
+	}
+
+	token, err := generateState()
+	if err != nil {
+		return err
+	}
+	reset := &models.PasswordResetToken{
+		UserID:    account.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.passwordResetTTL),
+	}
+	if meta.IP != "" {
+		ip := meta.IP
+		reset.RequestedIP = &ip
+	}
+	if err := s.resetRepo.Create(ctx, reset); err != nil {
+		return err
+	}
+
+	link := s.appURL + "/reset-password?token=" + url.QueryEscape(token)
+	s.sendMailAsync(ctx, mailer.Message{
+		To:      []string{account.Email},
+		Subject: "Reset password KerjaKuy",
+		Body: fmt.Sprintf(
+			"Halo %s,\n\nKami menerima permintaan untuk mereset password akun Anda. Buka tautan berikut dalam %d menit:\n\n%s\n\nAbaikan email ini jika Anda tidak memintanya.\n",
+			account.Name, int(s.passwordResetTTL.Minutes()), link,
+		),
+	})
+	return nil
+}
+
+// ResetPassword consumes a reset token and signs the account out everywhere.
+func (s *authService) ResetPassword(ctx context.Context, token, newPassword string, meta Metadata) error {
+	reset, err := s.resetRepo.Consume(ctx, hashToken(token), time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPasswordResetInvalid
+		}
+		return err
+	}
+
+	if err := s.userSvc.UpdatePassword(ctx, reset.UserID, newPassword); err != nil {
+		return err
+	}
+	if err := s.SignOutEverywhere(ctx, reset.UserID); err != nil {
+		return err
+	}
+	s.recordSecurityEvent(ctx, SecurityEventPasswordReset, &reset.UserID, SecurityOutcomeSuccess, meta, nil)
+	return nil
+}
+
+// ChangePassword keeps the caller's own session and revokes every other one.
+func (s *authService) ChangePassword(ctx context.Context, userID, currentSessionID uuid.UUID, req ChangePasswordRequest, meta Metadata) error {
+	userDTO, err := s.userSvc.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	account, err := s.userSvc.GetByEmail(ctx, userDTO.Email)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		s.recordSecurityEvent(ctx, SecurityEventPasswordChange, &userID, SecurityOutcomeFailure, meta, nil)
+		return ErrCurrentPasswordInvalid
+	}
+
+	if err := s.userSvc.UpdatePassword(ctx, userID, req.NewPassword); err != nil {
+		return err
+	}
+	if err := s.revokeUserSessions(ctx, userID, currentSessionID); err != nil {
+		return err
+	}
+	s.recordSecurityEvent(ctx, SecurityEventPasswordChange, &userID, SecurityOutcomeSuccess, meta, nil)
+	return nil
+}
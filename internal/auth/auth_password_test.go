@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestForgotPasswordDoesNotRevealAccounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		wantMail int
+	}{
+		{name: "registered address", email: "ana@example.com", wantMail: 1},
+		{name: "unknown address", email: "nobody@example.com", wantMail: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			u := ta.users.add(t, "ana@example.com", "rahasia123")
+
+			if err := ta.ForgotPassword(context.Background(), tt.email, Metadata{IP: "10.0.0.1"}); err != nil {
+				t.Fatalf("ForgotPassword: %v", err)
+			}
+			sent := ta.mail.waitSent(t, tt.wantMail)
+			if tt.wantMail == 0 {
+				return
+			}
+			if len(ta.resets.rows) != 1 || ta.resets.rows[0].UserID != u.ID {
+				t.Fatalf("reset tokens = %+v", ta.resets.rows)
+			}
+			if !strings.Contains(sent[0].Body, "/reset-password?token=") {
+				t.Errorf("mail body has no reset link: %s", sent[0].Body)
+			}
+		})
+	}
+}
+
+func TestForgotPasswordKeepsOnlyLatestLink(t *testing.T) {
+	ta := newTestAuth(t)
+	ta.users.add(t, "ana@example.com", "rahasia123")
+	for i := 0; i < 3; i++ {
+		if err := ta.ForgotPassword(context.Background(), "ana@example.com", Metadata{}); err != nil {
+			t.Fatalf("ForgotPassword: %v", err)
+		}
+	}
+	ta.mail.waitSent(t, 3)
+	if len(ta.resets.rows) != 1 {
+		t.Errorf("reset tokens = %d, want 1", len(ta.resets.rows))
+	}
+}
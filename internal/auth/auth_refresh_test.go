@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRefreshRotation(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ta *testAuth, refresh string)
+	}{
+		{
+			name: "refresh rotates the token within the same family",
+			run: func(t *testing.T, ta *testAuth, refresh string) {
+				resp, err := ta.Refresh(context.Background(), refresh, Metadata{})
+				if err != nil {
+					t.Fatalf("Refresh: %v", err)
+				}
+				if resp.Tokens.RefreshToken == "" || resp.Tokens.RefreshToken == refresh {
+					t.Fatalf("Refresh did not issue a new refresh token")
+				}
+				if len(ta.sessions.rows) != 2 || ta.sessions.rows[1].FamilyID != ta.sessions.rows[0].FamilyID {
+					t.Errorf("rotated session should join the original family")
+				}
+				if ta.sessions.rows[0].RotatedAt == nil {
+					t.Errorf("old session not marked rotated")
+				}
+			},
+		},
+		{
+			name: "reusing a rotated token revokes the family",
+			run: func(t *testing.T, ta *testAuth, refresh string) {
+				resp, err := ta.Refresh(context.Background(), refresh, Metadata{})
+				if err != nil {
+					t.Fatalf("Refresh: %v", err)
+				}
+				if _, err := ta.Refresh(context.Background(), refresh, Metadata{}); !errors.Is(err, ErrRefreshTokenReused) {
+					t.Fatalf("reuse error = %v, want %v", err, ErrRefreshTokenReused)
+				}
+				if _, err := ta.Refresh(context.Background(), resp.Tokens.RefreshToken, Metadata{}); !errors.Is(err, ErrSessionRevoked) {
+					t.Errorf("newest token error = %v, want %v", err, ErrSessionRevoked)
+				}
+				if got := ta.events.count(SecurityEventRefreshReuse); got != 1 {
+					t.Errorf("reuse events = %d, want 1", got)
+				}
+			},
+		},
+		{
+			name: "access token is rejected after reuse",
+			run: func(t *testing.T, ta *testAuth, refresh string) {
+				resp, err := ta.Refresh(context.Background(), refresh, Metadata{})
+				if err != nil {
+					t.Fatalf("Refresh: %v", err)
+				}
+				ta.Refresh(context.Background(), refresh, Metadata{})
+				if _, err := ta.ValidateAccessToken(resp.Tokens.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+					t.Errorf("ValidateAccessToken error = %v, want %v", err, ErrSessionRevoked)
+				}
+			},
+		},
+		{
+			name: "access token cannot be used as a refresh token",
+			run: func(t *testing.T, ta *testAuth, refresh string) {
+				login, err := ta.Login(context.Background(), LoginRequest{Email: "ana@example.com", Password: "rahasia123"}, Metadata{})
+				if err != nil {
+					t.Fatalf("Login: %v", err)
+				}
+				if _, err := ta.Refresh(context.Background(), login.Tokens.AccessToken, Metadata{}); err == nil {
+					t.Errorf("Refresh accepted an access token")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			ta.users.add(t, "ana@example.com", "rahasia123")
+			login, err := ta.Login(context.Background(), LoginRequest{Email: "ana@example.com", Password: "rahasia123"}, Metadata{})
+			if err != nil {
+				t.Fatalf("Login: %v", err)
+			}
+			tt.run(t, ta, login.Tokens.RefreshToken)
+		})
+	}
+}
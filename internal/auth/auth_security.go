@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/mailer"
+	"kerjakuy/internal/user"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SecurityEventLogin          = "login"
+	SecurityEventLoginFailed    = "login_failed"
+	SecurityEventTokenRefresh   = "token_refresh"
+	SecurityEventLogout         = "logout"
+	SecurityEventOAuthLink      = "oauth_link"
+	SecurityEventRefreshReuse   = "refresh_token_reuse"
+	SecurityEventPasswordReset  = "password_reset"
+	SecurityEventPasswordChange = "password_change"
+	SecurityEventLoginLockout   = "login_lockout"
+	SecurityEventImpersonation  = "impersonation"
+	SecurityEventEmailChange    = "email_change"
+)
+
+const (
+	SecurityOutcomeSuccess = "success"
+	SecurityOutcomeFailure = "failure"
+	SecurityOutcomeBlocked = "blocked"
+)
+
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodOAuth     = "oauth"
+	LoginMethodMagicLink = "magic_link"
+	LoginMethodTwoFactor = "two_factor"
+)
+
+const (
+	securityEventDefaultLimit = 50
+	// A login from a user agent/IP pair not seen in this window counts as a
+	// new device.
+	newDeviceLookback = 30 * 24 * time.Hour
+)
+
+// recordSecurityEvent is best effort: a failure to write the audit row must
+// not turn a successful (or already failing) auth operation into a 500.
+func (s *authService) recordSecurityEvent(ctx context.Context, eventType string, userID *uuid.UUID, outcome string, meta Metadata, data map[string]interface{}) {
+	if s.eventRepo == nil {
+		return
+	}
+	_ = s.eventRepo.Create(ctx, newSecurityEvent(eventType, userID, outcome, meta, data))
+}
+
+func newSecurityEvent(eventType string, userID *uuid.UUID, outcome string, meta Metadata, data map[string]interface{}) *models.SecurityEvent {
+	event := &models.SecurityEvent{
+		UserID:   userID,
+		Type:     eventType,
+		Outcome:  outcome,
+		Metadata: data,
+	}
+	if meta.UserAgent != "" {
+		userAgent := meta.UserAgent
+		event.UserAgent = &userAgent
+	}
+	if meta.IP != "" {
+		ip := meta.IP
+		event.IPAddress = &ip
+	}
+	return event
+}
+
+func (s *authService) ListSecurityEvents(ctx context.Context, userID uuid.UUID, query SecurityEventQuery) ([]SecurityEventDTO, error) {
+	return s.listSecurityEvents(ctx, map[uuid.UUID]time.Time{userID: {}}, query, true)
+}
+
+// ListSecurityEventsForMembers shows a workspace the events of its members
+// since each joined. Metadata is left out because it can describe the
+// member's other workspaces and accounts (e.g. email addresses). It does no
+// access checks; callers decide whose events the actor may see.
+func (s *authService) ListSecurityEventsForMembers(ctx context.Context, joinedAt map[uuid.UUID]time.Time, query SecurityEventQuery) ([]SecurityEventDTO, error) {
+	return s.listSecurityEvents(ctx, joinedAt, query, false)
+}
+
+func (s *authService) listSecurityEvents(ctx context.Context, since map[uuid.UUID]time.Time, query SecurityEventQuery, withMetadata bool) ([]SecurityEventDTO, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = securityEventDefaultLimit
+	}
+	events, err := s.eventRepo.ListByUsers(ctx, since, query.Before, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]SecurityEventDTO, 0, len(events))
+	for i := range events {
+		dto := mapSecurityEventToDTO(&events[i])
+		if !withMetadata {
+			dto.Metadata = nil
+		}
+		result = append(result, dto)
+	}
+	return result, nil
+}
+
+// startSession issues tokens for a completed login, records it, and warns
+// the user by email when the device is new to the account.
+func (s *authService) startSession(ctx context.Context, userDTO *user.UserDTO, method string, meta Metadata) (*AuthResponse, error) {
+	// Only a completed login clears the throttle; a correct password that
+	// still needs a second factor does not.
+	if err := s.resetLoginFailures(ctx, userDTO.Email, meta.IP); err != nil {
+		return nil, err
+	}
+	newDevice, err := s.isNewDevice(ctx, userDTO.ID, meta)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.issueTokens(ctx, userDTO.ID, userDTO.Email, meta)
+	if err != nil {
+		return nil, err
+	}
+	s.recordSecurityEvent(ctx, SecurityEventLogin, &userDTO.ID, SecurityOutcomeSuccess, meta, map[string]interface{}{
+		"method":     method,
+		"new_device": newDevice,
+	})
+	if newDevice {
+		s.notifyNewDevice(ctx, userDTO, meta)
+	}
+	return buildAuthResponse(userDTO, resp), nil
+}
+
+// isNewDevice is false for an account without any recent session, so the
+// very first login after signup does not trigger a warning.
+func (s *authService) isNewDevice(ctx context.Context, userID uuid.UUID, meta Metadata) (bool, error) {
+	total, matching, err := s.sessionRepo.DeviceHistory(ctx, userID, meta.UserAgent, meta.IP, time.Now().Add(-newDeviceLookback))
+	if err != nil {
+		return false, err
+	}
+	return total > 0 && matching == 0, nil
+}
+
+func (s *authService) notifyNewDevice(ctx context.Context, userDTO *user.UserDTO, meta Metadata) {
+	device := parseUserAgent(meta.UserAgent)
+	_ = s.mailer.Send(ctx, mailer.Message{
+		To:      []string{userDTO.Email},
+		Subject: "Login dari perangkat baru di KerjaKuy",
+		Body: fmt.Sprintf(
+			"Halo %s,\n\nAkun Anda baru saja dipakai login dari perangkat yang belum pernah terlihat:\n\nPerangkat: %s di %s\nIP: %s\nWaktu: %s\n\nJika ini bukan Anda, segera ganti password dan cabut sesi tersebut dari halaman keamanan akun.\n",
+			userDTO.Name, device.Browser, device.OS, meta.IP, time.Now().Format(time.RFC1123),
+		),
+	})
+}
+
+func mapSecurityEventToDTO(event *models.SecurityEvent) SecurityEventDTO {
+	dto := SecurityEventDTO{
+		ID:        event.ID,
+		UserID:    event.UserID,
+		Type:      event.Type,
+		Outcome:   event.Outcome,
+		IPAddress: event.IPAddress,
+		UserAgent: event.UserAgent,
+		Metadata:  event.Metadata,
+		CreatedAt: event.CreatedAt,
+	}
+	if event.UserAgent != nil {
+		dto.Device = parseUserAgent(*event.UserAgent)
+	} else {
+		dto.Device = parseUserAgent("")
+	}
+	return dto
+}
@@ -2,24 +2,35 @@ package auth
 
 import (
 	"context"
-	"encoding/base64"
 	"errors"
-	"fmt"
+	"log/slog"
 	"time"
 
 	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/mailer"
 	"kerjakuy/internal/user"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 const (
-	tokenTypeAccess  = "access"
-	tokenTypeRefresh = "refresh"
+	tokenTypeAccess            = "access"
+	tokenTypeRefresh           = "refresh"
+	tokenTypeTwoFactor         = "2fa_challenge"
+	tokenTypeEmailVerification = "email_verification"
+	tokenTypeMagicLink         = "magic_link"
 )
 
-var ErrOAuthProviderNotConfigured = errors.New("oauth provider belum dikonfigurasi")
+var (
+	ErrOAuthProviderNotConfigured = errors.New("oauth provider belum dikonfigurasi")
+	ErrOAuthInvalidState          = errors.New("oauth state tidak valid atau kedaluwarsa")
+	ErrOAuthInvalidRedirect       = errors.New("oauth redirect_uri tidak diizinkan")
+	ErrOAuthEmailUnverified       = errors.New("email dari oauth provider belum terverifikasi")
+	ErrRefreshTokenReused         = errors.New("refresh token sudah pernah dipakai, semua sesi terkait dicabut")
+	ErrSessionRevoked             = errors.New("sesi sudah dicabut")
+)
 
 type Metadata struct {
 	UserAgent string
@@ -27,51 +38,230 @@ type Metadata struct {
 }
 
 type Config struct {
-	Secret          string
+	Secret string
+	// Keys switches signing to RS256/EdDSA. Optional; HS256 with Secret is
+	// used when nil.
+	Keys            *KeySet
 	Issuer          string
 	AccessTokenTTL  time.Duration
 	RefreshTokenTTL time.Duration
+	OAuthStateTTL   time.Duration
+	ChallengeTTL    time.Duration
+	// AppURL is the public base URL used to build links sent by email.
+	AppURL                     string
+	PasswordResetTTL           time.Duration
+	EmailVerificationTTL       time.Duration
+	VerificationResendInterval time.Duration
+	MagicLinkTTL               time.Duration
+	ImpersonationTTL           time.Duration
+	// EmailChangeTTL is how long the confirmation link sent to a new
+	// address works; EmailChangeRevertTTL how long the old address can undo
+	// the change.
+	EmailChangeTTL       time.Duration
+	EmailChangeRevertTTL time.Duration
+	// LoginMaxFailures failed logins for one email (four times as many for
+	// one IP) lock further attempts for LoginLockout, doubling on each
+	// additional failure.
+	LoginMaxFailures int
+	LoginLockout     time.Duration
 }
 
 type Claims struct {
 	UserID    uuid.UUID
 	Email     string
+	SessionID uuid.UUID
+	// TokenID is the jti of a JWT.
+	TokenID   string
 	ExpiresAt time.Time
+	// Scope is set when the request used a personal access token.
+	Scope *TokenScope
+	// ActorID is the platform admin behind an impersonation token.
+	ActorID uuid.UUID
 }
 
 type Service interface {
 	Register(ctx context.Context, req user.CreateUserRequest, meta Metadata) (*AuthResponse, error)
 	Login(ctx context.Context, req LoginRequest, meta Metadata) (*AuthResponse, error)
+	RequestMagicLink(ctx context.Context, email string, meta Metadata) error
+	ConsumeMagicLink(ctx context.Context, token string, meta Metadata) (*AuthResponse, error)
 	Refresh(ctx context.Context, refreshToken string, meta Metadata) (*AuthResponse, error)
-	Logout(ctx context.Context, refreshToken string) error
+	Logout(ctx context.Context, refreshToken string, meta Metadata) error
 	ValidateAccessToken(token string) (*Claims, error)
+	JWKS() JWKSet
+	ValidatePersonalAccessToken(ctx context.Context, token string) (*Claims, error)
+	CreateAccessToken(ctx context.Context, userID uuid.UUID, req CreateAccessTokenRequest) (*CreatedAccessTokenResponse, error)
+	ListAccessTokens(ctx context.Context, userID uuid.UUID) ([]AccessTokenDTO, error)
+	RevokeAccessToken(ctx context.Context, userID, tokenID uuid.UUID) error
+	SignOutEverywhere(ctx context.Context, userID uuid.UUID) error
+	StartImpersonation(ctx context.Context, actorID uuid.UUID, req StartImpersonationRequest, meta Metadata) (*ImpersonationResponse, error)
+	StopImpersonation(ctx context.Context, subjectID, actorID, sessionID uuid.UUID, meta Metadata) error
 	BeginOAuth(ctx context.Context, provider, redirectURI string) (*OAuthRedirectResponse, error)
 	HandleOAuthCallback(ctx context.Context, provider, code, state string, meta Metadata) (*AuthResponse, error)
+	ListSessions(ctx context.Context, userID, currentSessionID uuid.UUID) ([]SessionDTO, error)
+	RenameSession(ctx context.Context, userID, sessionID uuid.UUID, name string) (*SessionDTO, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, userID, currentSessionID uuid.UUID) error
+	ListSecurityEvents(ctx context.Context, userID uuid.UUID, query SecurityEventQuery) ([]SecurityEventDTO, error)
+	AuditImpersonatedWrite(ctx context.Context, imp *Impersonation, meta Metadata, data map[string]interface{}) error
+	ListSecurityEventsForMembers(ctx context.Context, joinedAt map[uuid.UUID]time.Time, query SecurityEventQuery) ([]SecurityEventDTO, error)
+	EnrollTwoFactor(ctx context.Context, userID uuid.UUID) (*TwoFactorEnrollmentResponse, error)
+	ConfirmTwoFactor(ctx context.Context, userID uuid.UUID, code string) (*RecoveryCodesResponse, error)
+	DisableTwoFactor(ctx context.Context, userID uuid.UUID, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) (*RecoveryCodesResponse, error)
+	VerifyTwoFactor(ctx context.Context, challengeToken, code string, meta Metadata) (*AuthResponse, error)
+	ForgotPassword(ctx context.Context, email string, meta Metadata) error
+	ResetPassword(ctx context.Context, token, newPassword string, meta Metadata) error
+	ChangePassword(ctx context.Context, userID, currentSessionID uuid.UUID, req ChangePasswordRequest, meta Metadata) error
+	VerifyEmail(ctx context.Context, token string) (*user.UserDTO, error)
+	ResendVerificationEmail(ctx context.Context, userID uuid.UUID) error
+	VerificationResendInterval() time.Duration
+	RequestEmailChange(ctx context.Context, userID, currentSessionID uuid.UUID, req ChangeEmailRequest, meta Metadata) error
+	ConfirmEmailChange(ctx context.Context, token string, meta Metadata) (*user.UserDTO, error)
+	RevertEmailChange(ctx context.Context, token string, meta Metadata) error
+	Start(ctx context.Context, interval time.Duration)
 }
 
 type userManager interface {
+	Register(ctx context.Context, req user.CreateUserRequest, hashedPassword string) (*user.UserDTO, error)
 	CreateWithPassword(ctx context.Context, req user.CreateUserRequest) (*user.UserDTO, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*user.UserDTO, error)
+	UpdateProfile(ctx context.Context, id uuid.UUID, req user.UpdateUserProfileRequest) (*user.UserDTO, error)
+	UpdatePassword(ctx context.Context, id uuid.UUID, password string) error
+	ClearPassword(ctx context.Context, id uuid.UUID) error
+	MarkEmailVerified(ctx context.Context, id uuid.UUID, email string, at time.Time) error
+	ReserveVerificationEmail(ctx context.Context, id uuid.UUID, now time.Time, interval time.Duration) (bool, error)
+	EmailInUse(ctx context.Context, email string) (bool, error)
+	IsPlatformAdmin(ctx context.Context, id uuid.UUID) (bool, error)
+	ToDTO(account *models.User) *user.UserDTO
+}
+
+// invitationClaimer is implemented by workspace.InvitationService.
+type invitationClaimer interface {
+	ClaimInvitations(ctx context.Context, userID uuid.UUID, email string) error
+}
+
+// memberFinder is implemented by repository.WorkspaceMemberRepository.
+type memberFinder interface {
+	FindByUserAndWorkspace(ctx context.Context, userID, workspaceID uuid.UUID) (*models.WorkspaceMember, error)
 }
 
 type authService struct {
-	userSvc     userManager
-	sessionRepo UserSessionRepository
-	tokens      tokenManager
+	userSvc                    userManager
+	sessionRepo                UserSessionRepository
+	identityRepo               UserIdentityRepository
+	stateRepo                  OAuthStateRepository
+	eventRepo                  SecurityEventRepository
+	twoFactorRepo              TwoFactorRepository
+	resetRepo                  PasswordResetRepository
+	emailChangeRepo            EmailChangeRepository
+	throttleRepo               LoginThrottleRepository
+	accessTokenRepo            PersonalAccessTokenRepository
+	revocations                RevocationStore
+	oauth                      *OAuthRegistry
+	mailer                     mailer.Mailer
+	invitations                invitationClaimer
+	members                    memberFinder
+	logger                     *slog.Logger
+	tokens                     tokenManager
+	issuer                     string
+	appURL                     string
+	oauthStateTTL              time.Duration
+	challengeTTL               time.Duration
+	passwordResetTTL           time.Duration
+	emailVerificationTTL       time.Duration
+	verificationResendInterval time.Duration
+	magicLinkTTL               time.Duration
+	impersonationTTL           time.Duration
+	emailChangeTTL             time.Duration
+	emailChangeRevertTTL       time.Duration
+	loginMaxFailures           int
+	loginLockout               time.Duration
 }
 
-func NewService(userSvc userManager, sessionRepo UserSessionRepository, cfg Config) Service {
+func NewService(userSvc userManager, sessionRepo UserSessionRepository, identityRepo UserIdentityRepository, stateRepo OAuthStateRepository, eventRepo SecurityEventRepository, twoFactorRepo TwoFactorRepository, resetRepo PasswordResetRepository, emailChangeRepo EmailChangeRepository, throttleRepo LoginThrottleRepository, accessTokenRepo PersonalAccessTokenRepository, revocations RevocationStore, oauth *OAuthRegistry, mail mailer.Mailer, invitations invitationClaimer, members memberFinder, logger *slog.Logger, cfg Config) Service {
 	tokenMgr := &jwtTokenManager{
 		secret:     []byte(cfg.Secret),
+		keys:       cfg.Keys,
 		issuer:     cfg.Issuer,
 		accessTTL:  cfg.AccessTokenTTL,
 		refreshTTL: cfg.RefreshTokenTTL,
 	}
+	stateTTL := cfg.OAuthStateTTL
+	if stateTTL == 0 {
+		stateTTL = 10 * time.Minute
+	}
+	challengeTTL := cfg.ChallengeTTL
+	if challengeTTL == 0 {
+		challengeTTL = 5 * time.Minute
+	}
+	resetTTL := cfg.PasswordResetTTL
+	if resetTTL == 0 {
+		resetTTL = time.Hour
+	}
+	verificationTTL := cfg.EmailVerificationTTL
+	if verificationTTL == 0 {
+		verificationTTL = 24 * time.Hour
+	}
+	resendInterval := cfg.VerificationResendInterval
+	if resendInterval == 0 {
+		resendInterval = time.Minute
+	}
+	magicLinkTTL := cfg.MagicLinkTTL
+	if magicLinkTTL == 0 {
+		magicLinkTTL = 15 * time.Minute
+	}
+	impersonationTTL := cfg.ImpersonationTTL
+	if impersonationTTL == 0 {
+		impersonationTTL = 15 * time.Minute
+	}
+	emailChangeTTL := cfg.EmailChangeTTL
+	if emailChangeTTL == 0 {
+		emailChangeTTL = 24 * time.Hour
+	}
+	emailChangeRevertTTL := cfg.EmailChangeRevertTTL
+	if emailChangeRevertTTL == 0 {
+		emailChangeRevertTTL = 7 * 24 * time.Hour
+	}
+	maxFailures := cfg.LoginMaxFailures
+	if maxFailures <= 0 {
+		maxFailures = 5
+	}
+	lockout := cfg.LoginLockout
+	if lockout == 0 {
+		lockout = 15 * time.Minute
+	}
 	return &authService{
-		userSvc:     userSvc,
-		sessionRepo: sessionRepo,
-		tokens:      tokenMgr,
+		userSvc:                    userSvc,
+		sessionRepo:                sessionRepo,
+		identityRepo:               identityRepo,
+		stateRepo:                  stateRepo,
+		eventRepo:                  eventRepo,
+		twoFactorRepo:              twoFactorRepo,
+		resetRepo:                  resetRepo,
+		emailChangeRepo:            emailChangeRepo,
+		throttleRepo:               throttleRepo,
+		accessTokenRepo:            accessTokenRepo,
+		revocations:                revocations,
+		oauth:                      oauth,
+		mailer:                     mail,
+		invitations:                invitations,
+		members:                    members,
+		logger:                     logger,
+		tokens:                     tokenMgr,
+		issuer:                     cfg.Issuer,
+		appURL:                     cfg.AppURL,
+		oauthStateTTL:              stateTTL,
+		challengeTTL:               challengeTTL,
+		passwordResetTTL:           resetTTL,
+		emailVerificationTTL:       verificationTTL,
+		verificationResendInterval: resendInterval,
+		magicLinkTTL:               magicLinkTTL,
+		impersonationTTL:           impersonationTTL,
+		emailChangeTTL:             emailChangeTTL,
+		emailChangeRevertTTL:       emailChangeRevertTTL,
+		loginMaxFailures:           maxFailures,
+		loginLockout:               lockout,
 	}
 }
 
@@ -80,6 +270,11 @@ func (s *authService) Register(ctx context.Context, req user.CreateUserRequest,
 	if err != nil {
 		return nil, err
 	}
+	// The account exists at this point; a mail failure must not fail the
+	// signup; the user can ask for another link from /auth/email/resend.
+	if err := s.sendVerificationEmail(ctx, userDTO); err != nil {
+		s.logger.Error("failed to send verification email", "error", err, "user_id", userDTO.ID)
+	}
 	resp, err := s.issueTokens(ctx, userDTO.ID, userDTO.Email, meta)
 	if err != nil {
 		return nil, err
@@ -88,28 +283,36 @@ func (s *authService) Register(ctx context.Context, req user.CreateUserRequest,
 }
 
 func (s *authService) Login(ctx context.Context, req LoginRequest, meta Metadata) (*AuthResponse, error) {
-	account, err := s.userSvc.GetByEmail(ctx, req.Email)
-	if err != nil {
+	email := NormalizeLoginEmail(req.Email)
+	if err := s.checkLoginThrottle(ctx, email, meta.IP); err != nil {
 		return nil, err
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(req.Password)); err != nil {
-		return nil, errors.New("invalid credentials")
-	}
 
-	userDTO := user.UserDTO{
-		ID:        account.ID,
-		Name:      account.Name,
-		Email:     account.Email,
-		AvatarURL: account.AvatarURL,
-		CreatedAt: account.CreatedAt,
-		UpdatedAt: account.UpdatedAt,
-	}
-
-	resp, err := s.issueTokens(ctx, account.ID, account.Email, meta)
+	account, err := s.userSvc.GetByEmail(ctx, email)
 	if err != nil {
-		return nil, err
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		compareDummyPassword(req.Password)
+		if err := s.registerLoginFailure(ctx, email, nil, meta); err != nil {
+			return nil, err
+		}
+		s.recordSecurityEvent(ctx, SecurityEventLoginFailed, nil, SecurityOutcomeFailure, meta, map[string]interface{}{
+			"method": LoginMethodPassword,
+			"email":  email,
+		})
+		return nil, ErrInvalidCredentials
 	}
-	return buildAuthResponse(&userDTO, resp), nil
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(req.Password)); err != nil {
+		if err := s.registerLoginFailure(ctx, email, account, meta); err != nil {
+			return nil, err
+		}
+		s.recordSecurityEvent(ctx, SecurityEventLoginFailed, &account.ID, SecurityOutcomeFailure, meta, map[string]interface{}{
+			"method": LoginMethodPassword,
+		})
+		return nil, ErrInvalidCredentials
+	}
+	return s.completeLogin(ctx, s.userSvc.ToDTO(account), LoginMethodPassword, meta)
 }
 
 func (s *authService) Refresh(ctx context.Context, refreshToken string, meta Metadata) (*AuthResponse, error) {
@@ -122,69 +325,107 @@ func (s *authService) Refresh(ctx context.Context, refreshToken string, meta Met
 	if err != nil {
 		return nil, err
 	}
+	if session.UserID != claims.UserID {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if session.RevokedAt != nil {
+		return nil, ErrSessionRevoked
+	}
+	if session.RotatedAt != nil {
+		return nil, s.handleRefreshReuse(ctx, session, meta)
+	}
 
 	if session.ExpiresAt.Before(time.Now()) {
 		return nil, errors.New("refresh token expired")
 	}
 
-	if err := s.sessionRepo.DeleteByID(ctx, session.ID); err != nil {
+	rotated, err := s.sessionRepo.MarkRotated(ctx, session.ID, time.Now())
+	if err != nil {
 		return nil, err
 	}
+	if !rotated {
+		// Another request rotated the same token between our read and write.
+		return nil, s.handleRefreshReuse(ctx, session, meta)
+	}
 
 	userDTO, err := s.userSvc.GetByID(ctx, claims.UserID)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := s.issueTokens(ctx, claims.UserID, claims.Email, meta)
+	resp, err := s.issueSessionTokens(ctx, claims.UserID, claims.Email, meta, session)
 	if err != nil {
 		return nil, err
 	}
+	s.recordSecurityEvent(ctx, SecurityEventTokenRefresh, &claims.UserID, SecurityOutcomeSuccess, meta, map[string]interface{}{
+		"family_id": session.FamilyID.String(),
+	})
 
 	return buildAuthResponse(userDTO, resp), nil
 }
 
-func (s *authService) Logout(ctx context.Context, refreshToken string) error {
+func (s *authService) Logout(ctx context.Context, refreshToken string, meta Metadata) error {
 	session, err := s.sessionRepo.FindByTokenHash(ctx, hashToken(refreshToken))
 	if err != nil {
 		return err
 	}
-	return s.sessionRepo.DeleteByID(ctx, session.ID)
+	if err := s.sessionRepo.DeleteFamily(ctx, session.FamilyID); err != nil {
+		return err
+	}
+	if err := s.revokeSessionTokens(ctx, session.FamilyID); err != nil {
+		return err
+	}
+	s.recordSecurityEvent(ctx, SecurityEventLogout, &session.UserID, SecurityOutcomeSuccess, meta, map[string]interface{}{
+		"family_id": session.FamilyID.String(),
+	})
+	return nil
 }
 
-func (s *authService) ValidateAccessToken(token string) (*Claims, error) {
-	return s.tokens.ValidateToken(token, tokenTypeAccess)
+// handleRefreshReuse revokes every session descended from the same login
+// once an already rotated refresh token is presented again, so whichever
+// party holds a leaked token loses access together with the real client.
+func (s *authService) handleRefreshReuse(ctx context.Context, session *models.UserSession, meta Metadata) error {
+	if err := s.revokeFamily(ctx, session.FamilyID); err != nil {
+		return err
+	}
+	s.recordSecurityEvent(ctx, SecurityEventRefreshReuse, &session.UserID, SecurityOutcomeBlocked, meta, map[string]interface{}{
+		"session_id": session.ID.String(),
+		"family_id":  session.FamilyID.String(),
+	})
+	return ErrRefreshTokenReused
 }
 
-func (s *authService) BeginOAuth(ctx context.Context, provider, redirectURI string) (*OAuthRedirectResponse, error) {
-	state, err := generateState()
+func (s *authService) ValidateAccessToken(token string) (*Claims, error) {
+	claims, err := s.tokens.ValidateToken(token, tokenTypeAccess)
 	if err != nil {
 		return nil, err
 	}
-	if redirectURI == "" {
-		redirectURI = "https://app.kerjakuy.local/auth/callback"
-	}
-	authURL := fmt.Sprintf("https://auth.%s.example.com/oauth/authorize?client_id=%s&redirect_uri=%s&response_type=code&state=%s",
-		provider,
-		"CHANGE_ME",
-		base64.URLEncoding.EncodeToString([]byte(redirectURI)),
-		state,
-	)
-	return &OAuthRedirectResponse{
-		Provider:         provider,
-		AuthorizationURL: authURL,
-		State:            state,
-	}, nil
+	if s.revocations.IsRevoked(claims) {
+		return nil, ErrSessionRevoked
+	}
+	return claims, nil
 }
 
-func (s *authService) HandleOAuthCallback(ctx context.Context, provider, code, state string, meta Metadata) (*AuthResponse, error) {
-	return nil, fmt.Errorf("%w: %s", ErrOAuthProviderNotConfigured, provider)
+func (s *authService) JWKS() JWKSet {
+	return s.tokens.JWKS()
 }
 
 func (s *authService) issueTokens(ctx context.Context, userID uuid.UUID, email string, meta Metadata) (*AuthTokens, error) {
+	return s.issueSessionTokens(ctx, userID, email, meta, nil)
+}
+
+// issueSessionTokens signs a new token pair. When parent is set the new
+// session joins the parent's family instead of starting a new one.
+func (s *authService) issueSessionTokens(ctx context.Context, userID uuid.UUID, email string, meta Metadata, parent *models.UserSession) (*AuthTokens, error) {
+	familyID := uuid.New()
+	if parent != nil {
+		familyID = parent.FamilyID
+	}
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		SessionID: familyID,
 	}
 	accessToken, err := s.tokens.GenerateAccessToken(claims)
 	if err != nil {
@@ -195,7 +436,7 @@ func (s *authService) issueTokens(ctx context.Context, userID uuid.UUID, email s
 		return nil, err
 	}
 
-	if err := s.createSession(ctx, userID, refreshToken, meta); err != nil {
+	if err := s.createSession(ctx, userID, familyID, refreshToken, meta, parent); err != nil {
 		return nil, err
 	}
 
@@ -207,14 +448,20 @@ func (s *authService) issueTokens(ctx context.Context, userID uuid.UUID, email s
 	}, nil
 }
 
-func (s *authService) createSession(ctx context.Context, userID uuid.UUID, refreshToken string, meta Metadata) error {
+func (s *authService) createSession(ctx context.Context, userID, familyID uuid.UUID, refreshToken string, meta Metadata, parent *models.UserSession) error {
 	userAgent := meta.UserAgent
 	ip := meta.IP
 	session := &models.UserSession{
 		UserID:    userID,
+		FamilyID:  familyID,
 		TokenHash: hashToken(refreshToken),
 		ExpiresAt: time.Now().Add(s.tokens.RefreshTTL()),
 	}
+	if parent != nil {
+		session.ParentID = &parent.ID
+		session.Name = parent.Name
+		session.SignedInAt = parent.SignedInAt
+	}
 	if userAgent != "" {
 		session.UserAgent = &userAgent
 	}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"kerjakuy/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSessionNotFound = errors.New("sesi tidak ditemukan")
+	ErrSessionUnknown  = errors.New("sesi saat ini tidak diketahui, silakan login ulang")
+)
+
+// ListSessions returns the live sessions of a user. Sessions are exposed by
+// their family id, which stays stable across refresh-token rotations.
+func (s *authService) ListSessions(ctx context.Context, userID, currentSessionID uuid.UUID) ([]SessionDTO, error) {
+	sessions, err := s.sessionRepo.ListActiveByUser(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	result := make([]SessionDTO, 0, len(sessions))
+	for i := range sessions {
+		result = append(result, *mapSessionToDTO(&sessions[i], currentSessionID))
+	}
+	return result, nil
+}
+
+func (s *authService) RenameSession(ctx context.Context, userID, sessionID uuid.UUID, name string) (*SessionDTO, error) {
+	session, err := s.findActiveSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("nama sesi tidak boleh kosong")
+	}
+	if err := s.sessionRepo.UpdateName(ctx, userID, sessionID, &name); err != nil {
+		return nil, err
+	}
+	session.Name = &name
+	return mapSessionToDTO(session, uuid.Nil), nil
+}
+
+func (s *authService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if _, err := s.findActiveSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	return s.revokeFamily(ctx, sessionID)
+}
+
+func (s *authService) RevokeOtherSessions(ctx context.Context, userID, currentSessionID uuid.UUID) error {
+	if currentSessionID == uuid.Nil {
+		return ErrSessionUnknown
+	}
+	return s.revokeUserSessions(ctx, userID, currentSessionID)
+}
+
+// SignOutEverywhere ends every session of the user, including access tokens
+// that have not expired yet. Personal access tokens are not affected.
+func (s *authService) SignOutEverywhere(ctx context.Context, userID uuid.UUID) error {
+	return s.revokeUserSessions(ctx, userID, uuid.Nil)
+}
+
+func (s *authService) revokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	if err := s.sessionRepo.RevokeFamily(ctx, familyID, time.Now()); err != nil {
+		return err
+	}
+	return s.revokeSessionTokens(ctx, familyID)
+}
+
+// revokeUserSessions revokes every session family of the user except keep
+// (uuid.Nil keeps none), refresh rows and live access tokens alike.
+func (s *authService) revokeUserSessions(ctx context.Context, userID, keep uuid.UUID) error {
+	now := time.Now()
+	sessions, err := s.sessionRepo.ListActiveByUser(ctx, userID, now)
+	if err != nil {
+		return err
+	}
+	if err := s.sessionRepo.RevokeAllExcept(ctx, userID, keep, now); err != nil {
+		return err
+	}
+	for i := range sessions {
+		if sessions[i].FamilyID == keep {
+			continue
+		}
+		if err := s.revokeSessionTokens(ctx, sessions[i].FamilyID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeSessionTokens blocks the access tokens of a family. Any such token
+// was issued before now and so is expired one access TTL from now.
+func (s *authService) revokeSessionTokens(ctx context.Context, familyID uuid.UUID) error {
+	return s.revocations.RevokeSession(ctx, familyID, time.Now().Add(s.tokens.AccessTTL()))
+}
+
+func (s *authService) findActiveSession(ctx context.Context, userID, sessionID uuid.UUID) (*models.UserSession, error) {
+	session, err := s.sessionRepo.FindActiveByFamily(ctx, userID, sessionID, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+func mapSessionToDTO(session *models.UserSession, currentSessionID uuid.UUID) *SessionDTO {
+	userAgent := ""
+	if session.UserAgent != nil {
+		userAgent = *session.UserAgent
+	}
+	return &SessionDTO{
+		ID:         session.FamilyID,
+		Name:       session.Name,
+		Device:     parseUserAgent(userAgent),
+		UserAgent:  session.UserAgent,
+		IPAddress:  session.IPAddress,
+		SignedInAt: session.SignedInAt,
+		LastUsedAt: session.CreatedAt,
+		ExpiresAt:  session.ExpiresAt,
+		Current:    currentSessionID != uuid.Nil && session.FamilyID == currentSessionID,
+	}
+}
@@ -12,13 +12,22 @@ import (
 type tokenManager interface {
 	GenerateAccessToken(claims Claims) (string, error)
 	GenerateRefreshToken(claims Claims) (string, error)
+	GenerateChallengeToken(claims Claims, ttl time.Duration) (string, error)
+	GenerateVerificationToken(claims Claims, ttl time.Duration) (string, error)
+	GenerateMagicLinkToken(claims Claims, ttl time.Duration) (string, error)
+	GenerateImpersonationToken(claims Claims, ttl time.Duration) (string, error)
 	ValidateToken(token string, expectedType string) (*Claims, error)
+	JWKS() JWKSet
 	AccessTTL() time.Duration
 	RefreshTTL() time.Duration
 }
 
+// jwtTokenManager signs with the active key of keys when one is configured
+// and falls back to HS256 with secret otherwise. While both are set, HS256
+// tokens issued before the switch remain valid until they expire.
 type jwtTokenManager struct {
 	secret     []byte
+	keys       *KeySet
 	issuer     string
 	accessTTL  time.Duration
 	refreshTTL time.Duration
@@ -28,9 +37,17 @@ type jwtClaims struct {
 	UserID    string `json:"uid"`
 	Email     string `json:"email"`
 	TokenType string `json:"typ"`
+	SessionID string `json:"sid,omitempty"`
+	// Actor is set on impersonation tokens; the token's subject is the
+	// impersonated user (RFC 8693 "act" claim).
+	Actor *jwtActor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+type jwtActor struct {
+	Subject string `json:"sub"`
+}
+
 func (m *jwtTokenManager) GenerateAccessToken(claims Claims) (string, error) {
 	return m.generateToken(claims, tokenTypeAccess, m.accessTTL)
 }
@@ -39,6 +56,31 @@ func (m *jwtTokenManager) GenerateRefreshToken(claims Claims) (string, error) {
 	return m.generateToken(claims, tokenTypeRefresh, m.refreshTTL)
 }
 
+func (m *jwtTokenManager) GenerateChallengeToken(claims Claims, ttl time.Duration) (string, error) {
+	return m.generateToken(claims, tokenTypeTwoFactor, ttl)
+}
+
+func (m *jwtTokenManager) GenerateVerificationToken(claims Claims, ttl time.Duration) (string, error) {
+	return m.generateToken(claims, tokenTypeEmailVerification, ttl)
+}
+
+func (m *jwtTokenManager) GenerateMagicLinkToken(claims Claims, ttl time.Duration) (string, error) {
+	return m.generateToken(claims, tokenTypeMagicLink, ttl)
+}
+
+// GenerateImpersonationToken issues an access token with a custom TTL and
+// no matching refresh token.
+func (m *jwtTokenManager) GenerateImpersonationToken(claims Claims, ttl time.Duration) (string, error) {
+	return m.generateToken(claims, tokenTypeAccess, ttl)
+}
+
+func (m *jwtTokenManager) JWKS() JWKSet {
+	if m.keys == nil {
+		return JWKSet{Keys: []JWK{}}
+	}
+	return m.keys.JWKS()
+}
+
 func (m *jwtTokenManager) AccessTTL() time.Duration {
 	return m.accessTTL
 }
@@ -56,6 +98,7 @@ func (m *jwtTokenManager) generateToken(claims Claims, tokenType string, ttl tim
 		Email:     claims.Email,
 		TokenType: tokenType,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			Issuer:    m.issuer,
 			Subject:   claims.UserID.String(),
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
@@ -64,17 +107,37 @@ func (m *jwtTokenManager) generateToken(claims Claims, tokenType string, ttl tim
 		},
 	}
 
+	if claims.SessionID != uuid.Nil {
+		jClaims.SessionID = claims.SessionID.String()
+	}
+	if claims.ActorID != uuid.Nil {
+		jClaims.Actor = &jwtActor{Subject: claims.ActorID.String()}
+	}
+
+	if m.keys != nil {
+		token := jwt.NewWithClaims(m.keys.active.method, jClaims)
+		token.Header["kid"] = m.keys.active.kid
+		return token.SignedString(m.keys.active.private)
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jClaims)
 	return token.SignedString(m.secret)
 }
 
-func (m *jwtTokenManager) ValidateToken(tokenString string, expectedType string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwtClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+func (m *jwtTokenManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if len(m.secret) == 0 {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return m.secret, nil
-	})
+	}
+	if m.keys == nil {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return m.keys.verificationKey(token)
+}
+
+func (m *jwtTokenManager) ValidateToken(tokenString string, expectedType string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &jwtClaims{}, m.keyFunc)
 
 	if err != nil {
 		return nil, err
@@ -90,11 +153,27 @@ func (m *jwtTokenManager) ValidateToken(tokenString string, expectedType string)
 			return nil, errors.New("invalid user id in token")
 		}
 
-		return &Claims{
+		result := &Claims{
 			UserID:    userID,
 			Email:     claims.Email,
+			TokenID:   claims.ID,
 			ExpiresAt: claims.ExpiresAt.Time,
-		}, nil
+		}
+		if claims.SessionID != "" {
+			sessionID, err := uuid.Parse(claims.SessionID)
+			if err != nil {
+				return nil, errors.New("invalid session id in token")
+			}
+			result.SessionID = sessionID
+		}
+		if claims.Actor != nil {
+			actorID, err := uuid.Parse(claims.Actor.Subject)
+			if err != nil {
+				return nil, errors.New("invalid actor in token")
+			}
+			result.ActorID = actorID
+		}
+		return result, nil
 	}
 
 	return nil, errors.New("invalid token")
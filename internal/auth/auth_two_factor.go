@@ -0,0 +1,325 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/totp"
+	"kerjakuy/internal/user"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	recoveryCodeCount = 10
+	totpAllowedSkew   = 1
+
+	// twoFactorMaxAttempts wrong codes burn a login challenge, or lock 2FA
+	// management for the account.
+	twoFactorMaxAttempts = 5
+)
+
+var (
+	ErrTwoFactorRequired       = errors.New("verifikasi dua langkah diperlukan")
+	ErrTwoFactorAlreadyEnabled = errors.New("verifikasi dua langkah sudah aktif")
+	ErrTwoFactorNotEnabled     = errors.New("verifikasi dua langkah belum aktif")
+	ErrTwoFactorInvalidCode    = errors.New("kode verifikasi tidak valid")
+	ErrTwoFactorChallengeSpent = errors.New("terlalu banyak kode yang salah, silakan login ulang")
+)
+
+// TwoFactorChallengeError is returned by login flows when the account has
+// 2FA enabled. It carries the short-lived token for /auth/2fa/verify.
+type TwoFactorChallengeError struct {
+	ChallengeToken string
+	ExpiresIn      int64
+}
+
+func (e *TwoFactorChallengeError) Error() string {
+	return ErrTwoFactorRequired.Error()
+}
+
+func (e *TwoFactorChallengeError) Unwrap() error {
+	return ErrTwoFactorRequired
+}
+
+func (s *authService) EnrollTwoFactor(ctx context.Context, userID uuid.UUID) (*TwoFactorEnrollmentResponse, error) {
+	existing, err := s.twoFactorRepo.FindByUser(ctx, userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.ConfirmedAt != nil {
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		// Restarting enrolment discards the previous, never confirmed secret.
+		if err := s.twoFactorRepo.DeleteByUser(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	account, err := s.userSvc.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.Create(ctx, &models.UserTwoFactor{
+		UserID: userID,
+		Secret: secret,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.issuer, account.Email, secret),
+	}, nil
+}
+
+func (s *authService) ConfirmTwoFactor(ctx context.Context, userID uuid.UUID, code string) (*RecoveryCodesResponse, error) {
+	tf, err := s.twoFactorRepo.FindByUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTwoFactorNotEnabled
+		}
+		return nil, err
+	}
+	if tf.ConfirmedAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(tf.Secret, code, time.Now(), totpAllowedSkew)
+	if !ok {
+		return nil, ErrTwoFactorInvalidCode
+	}
+	if err := s.twoFactorRepo.Confirm(ctx, tf.ID, step, time.Now()); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+func (s *authService) DisableTwoFactor(ctx context.Context, userID uuid.UUID, code string) error {
+	tf, err := s.findEnabledTwoFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyManagedSecondFactor(ctx, tf, code); err != nil {
+		return err
+	}
+	return s.twoFactorRepo.DeleteByUser(ctx, userID)
+}
+
+func (s *authService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) (*RecoveryCodesResponse, error) {
+	tf, err := s.findEnabledTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyManagedSecondFactor(ctx, tf, code); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+func (s *authService) VerifyTwoFactor(ctx context.Context, challengeToken, code string, meta Metadata) (*AuthResponse, error) {
+	claims, err := s.tokens.ValidateToken(challengeToken, tokenTypeTwoFactor)
+	if err != nil {
+		return nil, err
+	}
+
+	email := NormalizeLoginEmail(claims.Email)
+	if err := s.checkLoginThrottle(ctx, email, meta.IP); err != nil {
+		return nil, err
+	}
+
+	tf, err := s.findEnabledTwoFactor(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if s.revocations.IsRevoked(claims) {
+		return nil, ErrTwoFactorInvalidCode
+	}
+	if err := s.verifySecondFactor(ctx, tf, code); err != nil {
+		if errors.Is(err, ErrTwoFactorInvalidCode) {
+			s.recordSecurityEvent(ctx, SecurityEventLoginFailed, &claims.UserID, SecurityOutcomeFailure, meta, map[string]interface{}{
+				"method": LoginMethodTwoFactor,
+			})
+			return nil, s.registerTwoFactorFailure(ctx, claims, email, meta)
+		}
+		return nil, err
+	}
+	// A challenge token completes exactly one login.
+	fresh, err := s.revocations.ConsumeToken(ctx, claims.TokenID, claims.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrTwoFactorInvalidCode
+	}
+	if err := s.throttleRepo.Reset(ctx, loginScopeChallenge, claims.TokenID); err != nil {
+		return nil, err
+	}
+
+	userDTO, err := s.userSvc.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.startSession(ctx, userDTO, LoginMethodTwoFactor, meta)
+}
+
+// registerTwoFactorFailure counts a wrong code against the challenge and
+// against the account's login throttle. The challenge is burned after
+// twoFactorMaxAttempts, so guessing needs a fresh first-factor login, which
+// the throttle slows down.
+func (s *authService) registerTwoFactorFailure(ctx context.Context, claims *Claims, email string, meta Metadata) error {
+	now := time.Now()
+	throttle, err := s.throttleRepo.Increment(ctx, loginScopeChallenge, claims.TokenID, now, now.Add(-loginWindow))
+	if err != nil {
+		return err
+	}
+	if err := s.registerLoginFailure(ctx, email, nil, meta); err != nil {
+		return err
+	}
+	if throttle.Failures < twoFactorMaxAttempts {
+		return ErrTwoFactorInvalidCode
+	}
+	if _, err := s.revocations.ConsumeToken(ctx, claims.TokenID, claims.ExpiresAt); err != nil {
+		return err
+	}
+	return ErrTwoFactorChallengeSpent
+}
+
+// verifyManagedSecondFactor checks a code for disabling 2FA or replacing
+// recovery codes. Wrong codes count per account with the login backoff and
+// lock out after twoFactorMaxAttempts, so a stolen session cannot guess its
+// way to switching 2FA off.
+func (s *authService) verifyManagedSecondFactor(ctx context.Context, tf *models.UserTwoFactor, code string) error {
+	key := tf.UserID.String()
+	now := time.Now()
+	blocked, err := s.throttleRepo.FindBlocked(ctx, loginScopeTwoFactorUser, key, now)
+	if err == nil {
+		return &LoginThrottledError{RetryAfter: blocked.BlockedUntil.Sub(now)}
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if err := s.verifySecondFactor(ctx, tf, code); err != nil {
+		if !errors.Is(err, ErrTwoFactorInvalidCode) {
+			return err
+		}
+		throttle, ierr := s.throttleRepo.Increment(ctx, loginScopeTwoFactorUser, key, now, now.Add(-loginWindow))
+		if ierr != nil {
+			return ierr
+		}
+		if delay, _ := s.loginBlockDuration(throttle.Failures, twoFactorMaxAttempts); delay > 0 {
+			if err := s.throttleRepo.SetBlockedUntil(ctx, throttle.ID, now.Add(delay)); err != nil {
+				return err
+			}
+		}
+		return ErrTwoFactorInvalidCode
+	}
+	return s.throttleRepo.Reset(ctx, loginScopeTwoFactorUser, key)
+}
+
+// completeLogin is the last step of every first-factor login. Accounts with
+// 2FA get a challenge instead of a session.
+func (s *authService) completeLogin(ctx context.Context, userDTO *user.UserDTO, method string, meta Metadata) (*AuthResponse, error) {
+	enabled, err := s.twoFactorRepo.IsEnabled(ctx, userDTO.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		token, err := s.tokens.GenerateChallengeToken(Claims{UserID: userDTO.ID, Email: userDTO.Email}, s.challengeTTL)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &TwoFactorChallengeError{
+			ChallengeToken: token,
+			ExpiresIn:      int64(s.challengeTTL.Seconds()),
+		}
+	}
+
+	return s.startSession(ctx, userDTO, method, meta)
+}
+
+func (s *authService) findEnabledTwoFactor(ctx context.Context, userID uuid.UUID) (*models.UserTwoFactor, error) {
+	tf, err := s.twoFactorRepo.FindByUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTwoFactorNotEnabled
+		}
+		return nil, err
+	}
+	if tf.ConfirmedAt == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return tf, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused
+// recovery code. Each TOTP step and each recovery code works only once.
+func (s *authService) verifySecondFactor(ctx context.Context, tf *models.UserTwoFactor, code string) error {
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(tf.Secret, code, time.Now(), totpAllowedSkew); ok {
+		fresh, err := s.twoFactorRepo.MarkUsedStep(ctx, tf.ID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrTwoFactorInvalidCode
+		}
+		return nil
+	}
+
+	used, err := s.twoFactorRepo.UseRecoveryCode(ctx, tf.UserID, hashToken(normalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrTwoFactorInvalidCode
+	}
+	return nil
+}
+
+func (s *authService) issueRecoveryCodes(ctx context.Context, userID uuid.UUID) (*RecoveryCodesResponse, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.UserRecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		rows = append(rows, models.UserRecoveryCode{
+			UserID:   userID,
+			CodeHash: hashToken(normalizeRecoveryCode(code)),
+		})
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, userID, rows); err != nil {
+		return nil, err
+	}
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// generateRecoveryCode returns a code formatted as xxxxx-xxxxx.
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	raw := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return raw[:5] + "-" + raw[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/totp"
+)
+
+// enableTwoFactor gives the user a confirmed TOTP secret and returns it.
+func (ta *testAuth) enableTwoFactor(t *testing.T, u *models.User) string {
+	t.Helper()
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	now := time.Now()
+	if err := ta.twoFactor.Create(context.Background(), &models.UserTwoFactor{UserID: u.ID, Secret: secret, ConfirmedAt: &now}); err != nil {
+		t.Fatalf("create two factor: %v", err)
+	}
+	return secret
+}
+
+func (ta *testAuth) challenge(t *testing.T, email, password string) string {
+	t.Helper()
+	_, err := ta.Login(context.Background(), LoginRequest{Email: email, Password: password}, Metadata{IP: "10.0.0.1"})
+	var challenge *TwoFactorChallengeError
+	if !errors.As(err, &challenge) {
+		t.Fatalf("Login() error = %v, want a 2FA challenge", err)
+	}
+	return challenge.ChallengeToken
+}
+
+func currentCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := totp.CodeAt(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatalf("CodeAt: %v", err)
+	}
+	return code
+}
+
+func TestVerifyTwoFactor(t *testing.T) {
+	tests := []struct {
+		name     string
+		wrong    int
+		useValid bool
+		replay   bool
+		wantErr  error
+	}{
+		{name: "valid code", useValid: true},
+		{name: "wrong code", wrong: 1, wantErr: ErrTwoFactorInvalidCode},
+		{name: "valid code after a few wrong ones", wrong: twoFactorMaxAttempts - 1, useValid: true},
+		{name: "challenge burned after max attempts", wrong: twoFactorMaxAttempts, useValid: true, wantErr: ErrTwoFactorInvalidCode},
+		{name: "challenge cannot be reused", useValid: true, replay: true, wantErr: ErrTwoFactorInvalidCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			u := ta.users.add(t, "ana@example.com", "rahasia123")
+			secret := ta.enableTwoFactor(t, u)
+			token := ta.challenge(t, u.Email, "rahasia123")
+			meta := Metadata{IP: "10.0.0.1"}
+
+			var err error
+			for i := 0; i < tt.wrong; i++ {
+				ta.throttle.unblock()
+				_, err = ta.VerifyTwoFactor(context.Background(), token, "000000", meta)
+			}
+			if tt.wrong == twoFactorMaxAttempts && !errors.Is(err, ErrTwoFactorChallengeSpent) {
+				t.Fatalf("last wrong code error = %v, want %v", err, ErrTwoFactorChallengeSpent)
+			}
+			if tt.useValid {
+				ta.throttle.unblock()
+				_, err = ta.VerifyTwoFactor(context.Background(), token, currentCode(t, secret), meta)
+			}
+			if tt.replay {
+				if err != nil {
+					t.Fatalf("first VerifyTwoFactor: %v", err)
+				}
+				// The TOTP step is spent too, so only the token check can fail
+				// with a recovery code.
+				codes, err := ta.issueRecoveryCodes(context.Background(), u.ID)
+				if err != nil {
+					t.Fatalf("issueRecoveryCodes: %v", err)
+				}
+				_, err = ta.VerifyTwoFactor(context.Background(), token, codes.RecoveryCodes[0], meta)
+				if !errors.Is(err, ErrTwoFactorInvalidCode) {
+					t.Fatalf("replayed challenge error = %v, want %v", err, ErrTwoFactorInvalidCode)
+				}
+				return
+			}
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("VerifyTwoFactor: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyTwoFactor error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyTwoFactorCountsTowardsLoginThrottle(t *testing.T) {
+	ta := newTestAuth(t)
+	u := ta.users.add(t, "ana@example.com", "rahasia123")
+	ta.enableTwoFactor(t, u)
+	token := ta.challenge(t, u.Email, "rahasia123")
+
+	if _, err := ta.VerifyTwoFactor(context.Background(), token, "000000", Metadata{IP: "10.0.0.1"}); !errors.Is(err, ErrTwoFactorInvalidCode) {
+		t.Fatalf("VerifyTwoFactor error = %v", err)
+	}
+	if got := ta.throttle.failures(loginScopeEmail, u.Email); got != 1 {
+		t.Errorf("email failures = %d, want 1", got)
+	}
+	// A correct password that still needs a second factor must not clear
+	// the counter, or repeated logins would allow unlimited guesses.
+	ta.throttle.unblock()
+	ta.challenge(t, u.Email, "rahasia123")
+	if got := ta.throttle.failures(loginScopeEmail, u.Email); got != 1 {
+		t.Errorf("email failures after first factor = %d, want 1", got)
+	}
+}
+
+func TestRecoveryCodeWorksOnce(t *testing.T) {
+	ta := newTestAuth(t)
+	u := ta.users.add(t, "ana@example.com", "rahasia123")
+	ta.enableTwoFactor(t, u)
+	codes, err := ta.issueRecoveryCodes(context.Background(), u.ID)
+	if err != nil {
+		t.Fatalf("issueRecoveryCodes: %v", err)
+	}
+
+	for i, want := range []error{nil, ErrTwoFactorInvalidCode} {
+		token := ta.challenge(t, u.Email, "rahasia123")
+		_, err := ta.VerifyTwoFactor(context.Background(), token, codes.RecoveryCodes[0], Metadata{})
+		if !errors.Is(err, want) {
+			t.Fatalf("attempt %d: error = %v, want %v", i, err, want)
+		}
+	}
+}
+
+func TestManageTwoFactorLocksOutAfterWrongCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		manage func(ta *testAuth, u *models.User, code string) error
+	}{
+		{
+			name: "disable",
+			manage: func(ta *testAuth, u *models.User, code string) error {
+				return ta.DisableTwoFactor(context.Background(), u.ID, code)
+			},
+		},
+		{
+			name: "regenerate recovery codes",
+			manage: func(ta *testAuth, u *models.User, code string) error {
+				_, err := ta.RegenerateRecoveryCodes(context.Background(), u.ID, code)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			u := ta.users.add(t, "ana@example.com", "rahasia123")
+			secret := ta.enableTwoFactor(t, u)
+
+			for i := 0; i < twoFactorMaxAttempts; i++ {
+				ta.throttle.unblock()
+				if err := tt.manage(ta, u, "000000"); !errors.Is(err, ErrTwoFactorInvalidCode) {
+					t.Fatalf("wrong code %d error = %v, want %v", i+1, err, ErrTwoFactorInvalidCode)
+				}
+			}
+			err := tt.manage(ta, u, currentCode(t, secret))
+			var throttled *LoginThrottledError
+			if !errors.As(err, &throttled) || throttled.RetryAfter < ta.loginLockout-time.Second {
+				t.Fatalf("valid code after lockout error = %v, want LoginThrottledError for the lockout", err)
+			}
+			if enabled, _ := ta.twoFactor.IsEnabled(context.Background(), u.ID); !enabled {
+				t.Errorf("2FA was disabled while locked out")
+			}
+		})
+	}
+}
+
+func TestManageTwoFactorValidCodeResetsCounter(t *testing.T) {
+	ta := newTestAuth(t)
+	u := ta.users.add(t, "ana@example.com", "rahasia123")
+	secret := ta.enableTwoFactor(t, u)
+
+	ta.RegenerateRecoveryCodes(context.Background(), u.ID, "000000")
+	if _, err := ta.RegenerateRecoveryCodes(context.Background(), u.ID, currentCode(t, secret)); err != nil {
+		t.Fatalf("RegenerateRecoveryCodes: %v", err)
+	}
+	if got := ta.throttle.failures(loginScopeTwoFactorUser, u.ID.String()); got != 0 {
+		t.Errorf("failures after a valid code = %d, want 0", got)
+	}
+}
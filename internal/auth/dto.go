@@ -1,6 +1,12 @@
 package auth
 
-import "kerjakuy/internal/user"
+import (
+	"time"
+
+	"kerjakuy/internal/user"
+
+	"github.com/google/uuid"
+)
 
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
@@ -19,8 +25,10 @@ type AuthTokens struct {
 	TokenType    string `json:"token_type"`
 }
 
+// RefreshTokenRequest may be empty for browser clients, which send the
+// refresh token as a cookie instead.
 type RefreshTokenRequest struct {
-	RefreshToken string `json:"refresh_token" binding:"required"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 type OAuthRedirectResponse struct {
@@ -28,3 +36,138 @@ type OAuthRedirectResponse struct {
 	AuthorizationURL string `json:"authorization_url"`
 	State            string `json:"state"`
 }
+
+type SessionDTO struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       *string    `json:"name,omitempty"`
+	Device     DeviceInfo `json:"device"`
+	UserAgent  *string    `json:"user_agent,omitempty"`
+	IPAddress  *string    `json:"ip_address,omitempty"`
+	SignedInAt time.Time  `json:"signed_in_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Current    bool       `json:"current"`
+}
+
+type StartImpersonationRequest struct {
+	UserID uuid.UUID `json:"user_id" binding:"required"`
+	Reason string    `json:"reason" binding:"required,min=5,max=500"`
+}
+
+type ImpersonationResponse struct {
+	User        user.UserDTO `json:"user"`
+	ActorID     uuid.UUID    `json:"actor_id"`
+	AccessToken string       `json:"access_token"`
+	ExpiresIn   int64        `json:"expires_in"`
+	TokenType   string       `json:"token_type"`
+}
+
+type SecurityEventDTO struct {
+	ID        uuid.UUID              `json:"id"`
+	UserID    *uuid.UUID             `json:"user_id,omitempty"`
+	Type      string                 `json:"type"`
+	Outcome   string                 `json:"outcome"`
+	Device    DeviceInfo             `json:"device"`
+	IPAddress *string                `json:"ip_address,omitempty"`
+	UserAgent *string                `json:"user_agent,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// SecurityEventQuery pages through events newest first; pass the created_at
+// of the last event received as Before to get the next page.
+type SecurityEventQuery struct {
+	Limit  int        `form:"limit" binding:"omitempty,min=1,max=200"`
+	Before *time.Time `form:"before" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type RenameSessionRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type TwoFactorEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangeEmailRequest starts an email change. Password is required when the
+// account has one; accounts without one either send a 2FA code or must have
+// signed in recently.
+type ChangeEmailRequest struct {
+	NewEmail      string `json:"new_email" binding:"required,email,max=150"`
+	Password      string `json:"password"`
+	TwoFactorCode string `json:"two_factor_code"`
+}
+
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ConsumeMagicLinkRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type CreateAccessTokenRequest struct {
+	Name         string      `json:"name" binding:"required,min=1,max=100"`
+	WorkspaceIDs []uuid.UUID `json:"workspace_ids" binding:"required,min=1"`
+	Permissions  []string    `json:"permissions" binding:"required,min=1"`
+	ExpiresAt    *time.Time  `json:"expires_at,omitempty"`
+}
+
+type AccessTokenDTO struct {
+	ID           uuid.UUID   `json:"id"`
+	Name         string      `json:"name"`
+	TokenPrefix  string      `json:"token_prefix"`
+	WorkspaceIDs []uuid.UUID `json:"workspace_ids"`
+	Permissions  []string    `json:"permissions"`
+	ExpiresAt    *time.Time  `json:"expires_at,omitempty"`
+	LastUsedAt   *time.Time  `json:"last_used_at,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// CreatedAccessTokenResponse is the only time the plain token is returned.
+type CreatedAccessTokenResponse struct {
+	Token       string         `json:"token"`
+	AccessToken AccessTokenDTO `json:"access_token"`
+}
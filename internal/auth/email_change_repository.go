@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/user"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EmailChangeRepository interface {
+	Create(ctx context.Context, req *models.EmailChangeRequest) error
+	DeletePendingByUser(ctx context.Context, userID uuid.UUID) error
+	ApplyConfirm(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChangeRequest, error)
+	ApplyRevert(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChangeRequest, error)
+}
+
+type emailChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailChangeRepository(db *gorm.DB) EmailChangeRepository {
+	return &emailChangeRepository{db: db}
+}
+
+func (r *emailChangeRepository) Create(ctx context.Context, req *models.EmailChangeRequest) error {
+	return r.db.WithContext(ctx).Create(req).Error
+}
+
+// DeletePendingByUser drops unconfirmed requests so only the newest link
+// works. Confirmed ones stay for their revert window.
+func (r *emailChangeRepository) DeletePendingByUser(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND confirmed_at IS NULL", userID).
+		Delete(&models.EmailChangeRequest{}).Error
+}
+
+// confirm marks an open request as confirmed and returns it. Like password
+// reset tokens, the conditional update makes each link single-use.
+func (r *emailChangeRepository) confirm(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChangeRequest, error) {
+	return r.consume(ctx, "confirm_token_hash = ? AND confirmed_at IS NULL AND reverted_at IS NULL AND expires_at > ?", tokenHash, now, "confirmed_at")
+}
+
+// revert marks a request, confirmed or not, as reverted and returns it.
+func (r *emailChangeRepository) revert(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChangeRequest, error) {
+	return r.consume(ctx, "revert_token_hash = ? AND reverted_at IS NULL AND revert_expires_at > ?", tokenHash, now, "reverted_at")
+}
+
+// ApplyConfirm consumes a confirm link and, in the same transaction, moves
+// the login email to the new, now verified, address and drops password
+// reset tokens sent to the old one. If any step fails the link stays
+// usable.
+func (r *emailChangeRepository) ApplyConfirm(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChangeRequest, error) {
+	var change *models.EmailChangeRequest
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		change, err = (&emailChangeRepository{db: tx}).confirm(ctx, tokenHash, now)
+		if err != nil {
+			return err
+		}
+		if err := moveEmail(ctx, tx, change.UserID, change.OldEmail, change.NewEmail, now); err != nil {
+			return err
+		}
+		return NewPasswordResetRepository(tx).DeleteByUser(ctx, change.UserID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// ApplyRevert consumes a revert link and locks out whoever made the change,
+// all in one transaction. Personal access tokens are revoked and identities
+// linked since the request are unlinked. A pending change is simply
+// cancelled along with any 2FA enrolled since the request. A confirmed one
+// gets the old email back and loses its password, reset tokens and 2FA, so
+// the owner recovers it through a password reset to the old address.
+func (r *emailChangeRepository) ApplyRevert(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChangeRequest, error) {
+	var change *models.EmailChangeRequest
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		change, err = (&emailChangeRepository{db: tx}).revert(ctx, tokenHash, now)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", change.UserID).Delete(&models.PersonalAccessToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND created_at >= ?", change.UserID, change.CreatedAt).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+
+		twoFactor := NewTwoFactorRepository(tx)
+		if change.ConfirmedAt == nil {
+			tf, err := twoFactor.FindByUser(ctx, change.UserID)
+			if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && tf.CreatedAt.Before(change.CreatedAt)) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			return twoFactor.DeleteByUser(ctx, change.UserID)
+		}
+
+		if err := moveEmail(ctx, tx, change.UserID, change.NewEmail, change.OldEmail, now); err != nil {
+			return err
+		}
+		users := user.NewUserRepository(tx)
+		if err := users.UpdatePasswordHash(ctx, change.UserID.String(), ""); err != nil {
+			return err
+		}
+		if err := NewPasswordResetRepository(tx).DeleteByUser(ctx, change.UserID); err != nil {
+			return err
+		}
+		return twoFactor.DeleteByUser(ctx, change.UserID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// moveEmail switches the login email from one address to another inside
+// tx. It fails with user.ErrEmailTaken if another account holds to, and
+// with gorm.ErrRecordNotFound if the account no longer uses from.
+func moveEmail(ctx context.Context, tx *gorm.DB, userID uuid.UUID, from, to string, verifiedAt time.Time) error {
+	users := user.NewUserRepository(tx)
+	existing, err := users.FindByEmailFold(ctx, to)
+	switch {
+	case err == nil && existing.ID != userID:
+		return user.ErrEmailTaken
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+	return users.UpdateEmail(ctx, userID.String(), from, to, &verifiedAt)
+}
+
+func (r *emailChangeRepository) consume(ctx context.Context, where string, tokenHash string, now time.Time, column string) (*models.EmailChangeRequest, error) {
+	var requests []models.EmailChangeRequest
+	result := r.db.WithContext(ctx).Model(&requests).
+		Clauses(clause.Returning{}).
+		Where(where, tokenHash, now).
+		Update(column, now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(requests) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &requests[0], nil
+}
@@ -0,0 +1,600 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/mailer"
+	"kerjakuy/internal/repository"
+	"kerjakuy/internal/user"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// The fakes below keep just enough state in memory to drive authService.
+// Each embeds its interface, so calling a method a test does not expect
+// panics instead of silently passing.
+
+type fakeUsers struct {
+	userManager
+	byID map[uuid.UUID]*models.User
+}
+
+func newFakeUsers() *fakeUsers {
+	return &fakeUsers{byID: map[uuid.UUID]*models.User{}}
+}
+
+func (f *fakeUsers) add(t *testing.T, email, password string) *models.User {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	u := &models.User{ID: uuid.New(), Name: "Test", Email: email, PasswordHash: string(hash)}
+	f.byID[u.ID] = u
+	return u
+}
+
+func (f *fakeUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, u := range f.byID {
+		if strings.EqualFold(u.Email, strings.TrimSpace(email)) {
+			return u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeUsers) GetByID(ctx context.Context, id uuid.UUID) (*user.UserDTO, error) {
+	u, ok := f.byID[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return f.ToDTO(u), nil
+}
+
+func (f *fakeUsers) IsPlatformAdmin(ctx context.Context, id uuid.UUID) (bool, error) {
+	u, ok := f.byID[id]
+	return ok && u.IsPlatformAdmin, nil
+}
+
+func (f *fakeUsers) IsEmailVerified(ctx context.Context, id uuid.UUID) (bool, error) {
+	u, ok := f.byID[id]
+	return ok && u.EmailVerifiedAt != nil, nil
+}
+
+func (f *fakeUsers) EmailInUse(ctx context.Context, email string) (bool, error) {
+	_, err := f.GetByEmail(ctx, email)
+	return err == nil, nil
+}
+
+func (f *fakeUsers) ToDTO(u *models.User) *user.UserDTO {
+	return &user.UserDTO{ID: u.ID, Name: u.Name, Email: u.Email, EmailVerifiedAt: u.EmailVerifiedAt}
+}
+
+type fakeSessions struct {
+	UserSessionRepository
+	rows []*models.UserSession
+}
+
+func (f *fakeSessions) Create(ctx context.Context, session *models.UserSession) error {
+	session.ID = uuid.New()
+	if session.FamilyID == uuid.Nil {
+		session.FamilyID = session.ID
+	}
+	if session.SignedInAt.IsZero() {
+		session.SignedInAt = time.Now()
+	}
+	session.CreatedAt = time.Now()
+	f.rows = append(f.rows, session)
+	return nil
+}
+
+func (f *fakeSessions) FindByTokenHash(ctx context.Context, hash string) (*models.UserSession, error) {
+	for _, s := range f.rows {
+		if s.TokenHash == hash {
+			return s, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeSessions) FindActiveByFamily(ctx context.Context, userID, familyID uuid.UUID, now time.Time) (*models.UserSession, error) {
+	for _, s := range f.rows {
+		if s.UserID == userID && s.FamilyID == familyID && s.RotatedAt == nil && s.RevokedAt == nil && s.ExpiresAt.After(now) {
+			return s, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeSessions) ListActiveByUser(ctx context.Context, userID uuid.UUID, now time.Time) ([]models.UserSession, error) {
+	var out []models.UserSession
+	for _, s := range f.rows {
+		if s.UserID == userID && s.RotatedAt == nil && s.RevokedAt == nil && s.ExpiresAt.After(now) {
+			out = append(out, *s)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeSessions) DeviceHistory(ctx context.Context, userID uuid.UUID, userAgent, ip string, since time.Time) (int64, int64, error) {
+	return 0, 0, nil
+}
+
+func (f *fakeSessions) MarkRotated(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	for _, s := range f.rows {
+		if s.ID == id && s.RotatedAt == nil {
+			s.RotatedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeSessions) RevokeFamily(ctx context.Context, familyID uuid.UUID, at time.Time) error {
+	for _, s := range f.rows {
+		if s.FamilyID == familyID && s.RevokedAt == nil {
+			s.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (f *fakeSessions) RevokeAllExcept(ctx context.Context, userID, keepFamilyID uuid.UUID, at time.Time) error {
+	for _, s := range f.rows {
+		if s.UserID == userID && s.FamilyID != keepFamilyID && s.RevokedAt == nil {
+			s.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+type fakeTwoFactor struct {
+	TwoFactorRepository
+	byUser   map[uuid.UUID]*models.UserTwoFactor
+	recovery map[uuid.UUID]map[string]bool
+}
+
+func newFakeTwoFactor() *fakeTwoFactor {
+	return &fakeTwoFactor{byUser: map[uuid.UUID]*models.UserTwoFactor{}, recovery: map[uuid.UUID]map[string]bool{}}
+}
+
+func (f *fakeTwoFactor) FindByUser(ctx context.Context, userID uuid.UUID) (*models.UserTwoFactor, error) {
+	tf, ok := f.byUser[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return tf, nil
+}
+
+func (f *fakeTwoFactor) IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	tf, ok := f.byUser[userID]
+	return ok && tf.ConfirmedAt != nil, nil
+}
+
+func (f *fakeTwoFactor) Create(ctx context.Context, tf *models.UserTwoFactor) error {
+	tf.ID = uuid.New()
+	tf.CreatedAt = time.Now()
+	f.byUser[tf.UserID] = tf
+	return nil
+}
+
+func (f *fakeTwoFactor) Confirm(ctx context.Context, id uuid.UUID, step int64, at time.Time) error {
+	for _, tf := range f.byUser {
+		if tf.ID == id {
+			tf.ConfirmedAt = &at
+			tf.LastUsedStep = step
+		}
+	}
+	return nil
+}
+
+func (f *fakeTwoFactor) MarkUsedStep(ctx context.Context, id uuid.UUID, step int64) (bool, error) {
+	for _, tf := range f.byUser {
+		if tf.ID == id && tf.LastUsedStep < step {
+			tf.LastUsedStep = step
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeTwoFactor) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	delete(f.byUser, userID)
+	delete(f.recovery, userID)
+	return nil
+}
+
+func (f *fakeTwoFactor) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []models.UserRecoveryCode) error {
+	f.recovery[userID] = map[string]bool{}
+	for _, c := range codes {
+		f.recovery[userID][c.CodeHash] = false
+	}
+	return nil
+}
+
+func (f *fakeTwoFactor) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string, at time.Time) (bool, error) {
+	used, ok := f.recovery[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	f.recovery[userID][codeHash] = true
+	return true, nil
+}
+
+type fakeThrottle struct {
+	LoginThrottleRepository
+	rows map[string]*models.LoginThrottle
+}
+
+func newFakeThrottle() *fakeThrottle {
+	return &fakeThrottle{rows: map[string]*models.LoginThrottle{}}
+}
+
+func (f *fakeThrottle) FindBlocked(ctx context.Context, scope, key string, now time.Time) (*models.LoginThrottle, error) {
+	row, ok := f.rows[scope+"|"+key]
+	if !ok || row.BlockedUntil == nil || !row.BlockedUntil.After(now) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return row, nil
+}
+
+func (f *fakeThrottle) Increment(ctx context.Context, scope, key string, now, windowStart time.Time) (*models.LoginThrottle, error) {
+	row, ok := f.rows[scope+"|"+key]
+	if !ok {
+		row = &models.LoginThrottle{ID: uuid.New(), Scope: scope, Key: key}
+		f.rows[scope+"|"+key] = row
+	}
+	if row.LastFailedAt.Before(windowStart) {
+		row.Failures = 0
+	}
+	row.Failures++
+	row.LastFailedAt = now
+	copied := *row
+	return &copied, nil
+}
+
+func (f *fakeThrottle) SetBlockedUntil(ctx context.Context, id uuid.UUID, until time.Time) error {
+	for _, row := range f.rows {
+		if row.ID == id {
+			row.BlockedUntil = &until
+		}
+	}
+	return nil
+}
+
+func (f *fakeThrottle) Reset(ctx context.Context, scope, key string) error {
+	delete(f.rows, scope+"|"+key)
+	return nil
+}
+
+func (f *fakeThrottle) failures(scope, key string) int {
+	if row, ok := f.rows[scope+"|"+key]; ok {
+		return row.Failures
+	}
+	return 0
+}
+
+func (f *fakeThrottle) unblock() {
+	for _, row := range f.rows {
+		row.BlockedUntil = nil
+	}
+}
+
+type fakeRevokedTokens struct {
+	RevokedTokenRepository
+	rows map[string]time.Time
+}
+
+func (f *fakeRevokedTokens) Upsert(ctx context.Context, kind, value string, expiresAt time.Time) error {
+	f.rows[kind+":"+value] = expiresAt
+	return nil
+}
+
+func (f *fakeRevokedTokens) InsertOnce(ctx context.Context, kind, value string, expiresAt time.Time) (bool, error) {
+	if _, ok := f.rows[kind+":"+value]; ok {
+		return false, nil
+	}
+	f.rows[kind+":"+value] = expiresAt
+	return true, nil
+}
+
+type fakeEvents struct {
+	SecurityEventRepository
+	events []models.SecurityEvent
+	err    error
+}
+
+func (f *fakeEvents) Create(ctx context.Context, event *models.SecurityEvent) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.events = append(f.events, *event)
+	return nil
+}
+
+func (f *fakeEvents) count(eventType string) int {
+	n := 0
+	for _, e := range f.events {
+		if e.Type == eventType {
+			n++
+		}
+	}
+	return n
+}
+
+type fakeResets struct {
+	PasswordResetRepository
+	rows []*models.PasswordResetToken
+}
+
+func (f *fakeResets) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	token.ID = uuid.New()
+	f.rows = append(f.rows, token)
+	return nil
+}
+
+func (f *fakeResets) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	kept := f.rows[:0]
+	for _, row := range f.rows {
+		if row.UserID != userID {
+			kept = append(kept, row)
+		}
+	}
+	f.rows = kept
+	return nil
+}
+
+type fakeMembers struct {
+	repository.WorkspaceMemberRepository
+	roles map[[2]uuid.UUID]string
+}
+
+func newFakeMembers() *fakeMembers {
+	return &fakeMembers{roles: map[[2]uuid.UUID]string{}}
+}
+
+func (f *fakeMembers) add(userID, workspaceID uuid.UUID, role string) {
+	f.roles[[2]uuid.UUID{userID, workspaceID}] = role
+}
+
+func (f *fakeMembers) FindByUserAndWorkspace(ctx context.Context, userID, workspaceID uuid.UUID) (*models.WorkspaceMember, error) {
+	role, ok := f.roles[[2]uuid.UUID{userID, workspaceID}]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.WorkspaceMember{UserID: userID, WorkspaceID: workspaceID, Role: role}, nil
+}
+
+func (f *fakeMembers) ListWorkspaceIDsByUser(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for key := range f.roles {
+		if key[0] == userID {
+			ids = append(ids, key[1])
+		}
+	}
+	return ids, nil
+}
+
+type fakeActivity struct {
+	rows []models.ActivityLog
+}
+
+func (f *fakeActivity) Create(ctx context.Context, log *models.ActivityLog) error {
+	f.rows = append(f.rows, *log)
+	return nil
+}
+
+type fakeWorkspaces struct {
+	byID map[uuid.UUID]*models.Workspace
+}
+
+func (f *fakeWorkspaces) FindByID(ctx context.Context, id uuid.UUID) (*models.Workspace, error) {
+	ws, ok := f.byID[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return ws, nil
+}
+
+type fakeAccessTokens struct {
+	PersonalAccessTokenRepository
+	rows []*models.PersonalAccessToken
+}
+
+func (f *fakeAccessTokens) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	token.ID = uuid.New()
+	f.rows = append(f.rows, token)
+	return nil
+}
+
+func (f *fakeAccessTokens) FindByTokenHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error) {
+	for _, row := range f.rows {
+		if row.TokenHash == hash {
+			return row, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeAccessTokens) TouchLastUsed(ctx context.Context, id uuid.UUID, at, staleBefore time.Time) error {
+	return nil
+}
+
+// fakeEmailChanges mirrors emailChangeRepository: links are single-use
+// and expire, and applying one moves the email on the fake user the way
+// the transaction does on the real tables.
+type fakeEmailChanges struct {
+	EmailChangeRepository
+	ta   *testAuth
+	rows []*models.EmailChangeRequest
+}
+
+func (f *fakeEmailChanges) Create(ctx context.Context, req *models.EmailChangeRequest) error {
+	req.ID = uuid.New()
+	req.CreatedAt = time.Now()
+	f.rows = append(f.rows, req)
+	return nil
+}
+
+func (f *fakeEmailChanges) DeletePendingByUser(ctx context.Context, userID uuid.UUID) error {
+	kept := f.rows[:0]
+	for _, row := range f.rows {
+		if row.UserID != userID || row.ConfirmedAt != nil {
+			kept = append(kept, row)
+		}
+	}
+	f.rows = kept
+	return nil
+}
+
+func (f *fakeEmailChanges) ApplyConfirm(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChangeRequest, error) {
+	for _, row := range f.rows {
+		if row.ConfirmTokenHash != tokenHash || row.ConfirmedAt != nil || row.RevertedAt != nil || !row.ExpiresAt.After(now) {
+			continue
+		}
+		if err := f.moveEmail(row.UserID, row.OldEmail, row.NewEmail, now); err != nil {
+			return nil, err
+		}
+		f.ta.resets.DeleteByUser(ctx, row.UserID)
+		row.ConfirmedAt = &now
+		return row, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeEmailChanges) ApplyRevert(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChangeRequest, error) {
+	for _, row := range f.rows {
+		if row.RevertTokenHash != tokenHash || row.RevertedAt != nil || !row.RevertExpiresAt.After(now) {
+			continue
+		}
+		f.ta.tokens.rows = nil
+		if row.ConfirmedAt == nil {
+			if tf, ok := f.ta.twoFactor.byUser[row.UserID]; ok && !tf.CreatedAt.Before(row.CreatedAt) {
+				f.ta.twoFactor.DeleteByUser(ctx, row.UserID)
+			}
+		} else {
+			if err := f.moveEmail(row.UserID, row.NewEmail, row.OldEmail, now); err != nil {
+				return nil, err
+			}
+			f.ta.users.byID[row.UserID].PasswordHash = ""
+			f.ta.resets.DeleteByUser(ctx, row.UserID)
+			f.ta.twoFactor.DeleteByUser(ctx, row.UserID)
+		}
+		row.RevertedAt = &now
+		return row, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeEmailChanges) moveEmail(userID uuid.UUID, from, to string, at time.Time) error {
+	if existing, err := f.ta.users.GetByEmail(context.Background(), to); err == nil && existing.ID != userID {
+		return user.ErrEmailTaken
+	}
+	u := f.ta.users.byID[userID]
+	if !strings.EqualFold(u.Email, from) {
+		return gorm.ErrRecordNotFound
+	}
+	u.Email, u.EmailVerifiedAt = to, &at
+	return nil
+}
+
+type fakeInvitationClaimer struct {
+	claimed []string
+}
+
+func (f *fakeInvitationClaimer) ClaimInvitations(ctx context.Context, userID uuid.UUID, email string) error {
+	f.claimed = append(f.claimed, email)
+	return nil
+}
+
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (f *fakeMailer) Send(ctx context.Context, msg mailer.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+// waitSent waits for n messages, since some mail is sent in the background.
+func (f *fakeMailer) waitSent(t *testing.T, n int) []mailer.Message {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		f.mu.Lock()
+		sent := append([]mailer.Message(nil), f.sent...)
+		f.mu.Unlock()
+		if len(sent) >= n || time.Now().After(deadline) {
+			if len(sent) != n {
+				t.Fatalf("sent %d messages, want %d", len(sent), n)
+			}
+			return sent
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (f *fakeMailer) sentTo(address string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, msg := range f.sent {
+		if strings.EqualFold(strings.Join(msg.To, ","), address) {
+			n++
+		}
+	}
+	return n
+}
+
+type testAuth struct {
+	*authService
+	users     *fakeUsers
+	sessions  *fakeSessions
+	twoFactor *fakeTwoFactor
+	throttle  *fakeThrottle
+	events    *fakeEvents
+	resets    *fakeResets
+	members   *fakeMembers
+	tokens    *fakeAccessTokens
+	mail      *fakeMailer
+}
+
+// newTestAuth wires authService to the fakes above. Repositories a test
+// needs beyond these can be set on the returned service directly.
+func newTestAuth(t *testing.T) *testAuth {
+	t.Helper()
+	ta := &testAuth{
+		users:     newFakeUsers(),
+		sessions:  &fakeSessions{},
+		twoFactor: newFakeTwoFactor(),
+		throttle:  newFakeThrottle(),
+		events:    &fakeEvents{},
+		resets:    &fakeResets{},
+		members:   newFakeMembers(),
+		tokens:    &fakeAccessTokens{},
+		mail:      &fakeMailer{},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	revocations := NewRevocationStore(&fakeRevokedTokens{rows: map[string]time.Time{}}, logger)
+	svc := NewService(ta.users, ta.sessions, nil, nil, ta.events, ta.twoFactor, ta.resets, nil, ta.throttle, ta.tokens, revocations, nil, ta.mail, nil, ta.members, logger, Config{
+		Secret:          "test-secret",
+		Issuer:          "kerjakuy-test",
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+		AppURL:          "http://app.test",
+	})
+	ta.authService = svc.(*authService)
+	return ta
+}
@@ -2,9 +2,12 @@ package auth
 
 import (
 	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"kerjakuy/internal/user"
 )
 
@@ -42,6 +45,15 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 	resp, err := h.authService.Login(c.Request.Context(), req, h.metadataFromContext(c))
 	if err != nil {
+		if h.handleTwoFactorChallenge(c, err) {
+			return
+		}
+		var throttled *LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(throttled.RetryAfter.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
@@ -49,12 +61,11 @@ func (h *AuthHandler) Login(c *gin.Context) {
 }
 
 func (h *AuthHandler) Refresh(c *gin.Context) {
-	var req RefreshTokenRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	refreshToken, ok := h.refreshTokenFromRequest(c)
+	if !ok {
 		return
 	}
-	resp, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken, h.metadataFromContext(c))
+	resp, err := h.authService.Refresh(c.Request.Context(), refreshToken, h.metadataFromContext(c))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
@@ -63,12 +74,11 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 }
 
 func (h *AuthHandler) Logout(c *gin.Context) {
-	var req RefreshTokenRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	refreshToken, ok := h.refreshTokenFromRequest(c)
+	if !ok {
 		return
 	}
-	if err := h.authService.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+	if err := h.authService.Logout(c.Request.Context(), refreshToken, h.metadataFromContext(c)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -83,7 +93,14 @@ func (h *AuthHandler) OAuthRedirect(c *gin.Context) {
 	redirectURI := c.Query("redirect_uri")
 	resp, err := h.authService.BeginOAuth(c.Request.Context(), provider, redirectURI)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, ErrOAuthProviderNotConfigured):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrOAuthInvalidRedirect):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 	c.JSON(http.StatusOK, resp)
@@ -99,16 +116,374 @@ func (h *AuthHandler) OAuthCallback(c *gin.Context) {
 	}
 	resp, err := h.authService.HandleOAuthCallback(c.Request.Context(), provider, code, state, h.metadataFromContext(c))
 	if err != nil {
-		if errors.Is(err, ErrOAuthProviderNotConfigured) {
-			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		if h.handleTwoFactorChallenge(c, err) {
 			return
 		}
+		switch {
+		case errors.Is(err, ErrOAuthProviderNotConfigured):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrOAuthInvalidState), errors.Is(err, ErrOAuthEmailUnverified):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	h.handleAuthSuccess(c, http.StatusOK, resp)
+}
+
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	resp, err := h.authService.EnrollTwoFactor(c.Request.Context(), userID)
+	if err != nil {
+		h.handleTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	resp, err := h.authService.ConfirmTwoFactor(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		h.handleTwoFactorError(c, err)
+		return
+	}
 	c.JSON(http.StatusOK, resp)
 }
 
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if err := h.authService.DisableTwoFactor(c.Request.Context(), userID, req.Code); err != nil {
+		h.handleTwoFactorError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	resp, err := h.authService.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		h.handleTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.authService.VerifyTwoFactor(c.Request.Context(), req.ChallengeToken, req.Code, h.metadataFromContext(c))
+	if err != nil {
+		var throttled *LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(throttled.RetryAfter.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	h.handleAuthSuccess(c, http.StatusOK, resp)
+}
+
+func (h *AuthHandler) handleTwoFactorChallenge(c *gin.Context, err error) bool {
+	var challenge *TwoFactorChallengeError
+	if !errors.As(err, &challenge) {
+		return false
+	}
+	c.JSON(http.StatusAccepted, TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challenge.ChallengeToken,
+		ExpiresIn:         challenge.ExpiresIn,
+	})
+	return true
+}
+
+func (h *AuthHandler) handleTwoFactorError(c *gin.Context, err error) {
+	var throttled *LoginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		c.Header("Retry-After", strconv.Itoa(int(throttled.RetryAfter.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTwoFactorInvalidCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTwoFactorAlreadyEnabled), errors.Is(err, ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *AuthHandler) RequestMagicLink(c *gin.Context) {
+	var req MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.authService.RequestMagicLink(c.Request.Context(), req.Email, h.metadataFromContext(c)); err != nil {
+		var throttled *LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(throttled.RetryAfter.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "jika email terdaftar, tautan login telah dikirim"})
+}
+
+func (h *AuthHandler) ConsumeMagicLink(c *gin.Context) {
+	var req ConsumeMagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.authService.ConsumeMagicLink(c.Request.Context(), req.Token, h.metadataFromContext(c))
+	if err != nil {
+		if h.handleTwoFactorChallenge(c, err) {
+			return
+		}
+		if errors.Is(err, ErrMagicLinkInvalid) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.handleAuthSuccess(c, http.StatusOK, resp)
+}
+
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.authService.ForgotPassword(c.Request.Context(), req.Email, h.metadataFromContext(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "jika email terdaftar, tautan reset password telah dikirim"})
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.authService.ResetPassword(c.Request.Context(), req.Token, req.NewPassword, h.metadataFromContext(c)); err != nil {
+		h.handlePasswordError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	currentSessionID, _ := GetSessionID(c)
+
+	if err := h.authService.ChangePassword(c.Request.Context(), userID, currentSessionID, req, h.metadataFromContext(c)); err != nil {
+		h.handlePasswordError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userDTO, err := h.authService.VerifyEmail(c.Request.Context(), req.Token)
+	if err != nil {
+		h.handleEmailVerificationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, userDTO)
+}
+
+func (h *AuthHandler) ResendVerificationEmail(c *gin.Context) {
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if err := h.authService.ResendVerificationEmail(c.Request.Context(), userID); err != nil {
+		h.handleEmailVerificationError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "email verifikasi telah dikirim"})
+}
+
+func (h *AuthHandler) RequestEmailChange(c *gin.Context) {
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	sessionID, _ := GetSessionID(c)
+
+	if err := h.authService.RequestEmailChange(c.Request.Context(), userID, sessionID, req, h.metadataFromContext(c)); err != nil {
+		h.handleEmailChangeError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "tautan konfirmasi telah dikirim ke email baru"})
+}
+
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	var req EmailChangeTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userDTO, err := h.authService.ConfirmEmailChange(c.Request.Context(), req.Token, h.metadataFromContext(c))
+	if err != nil {
+		h.handleEmailChangeError(c, err)
+		return
+	}
+	h.cookieMgr.ClearTokens(c)
+	c.JSON(http.StatusOK, userDTO)
+}
+
+func (h *AuthHandler) RevertEmailChange(c *gin.Context) {
+	var req EmailChangeTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.RevertEmailChange(c.Request.Context(), req.Token, h.metadataFromContext(c)); err != nil {
+		h.handleEmailChangeError(c, err)
+		return
+	}
+	h.cookieMgr.ClearTokens(c)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) handleEmailChangeError(c *gin.Context, err error) {
+	var throttled *LoginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		c.Header("Retry-After", strconv.Itoa(int(throttled.RetryAfter.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrEmailChangeInvalid), errors.Is(err, ErrEmailUnchanged):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrCurrentPasswordInvalid), errors.Is(err, ErrTwoFactorInvalidCode), errors.Is(err, ErrRecentLoginRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, user.ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *AuthHandler) handleEmailVerificationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrEmailVerificationInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrEmailAlreadyVerified):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrVerificationEmailThrottled):
+		c.Header("Retry-After", strconv.Itoa(int(h.authService.VerificationResendInterval().Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *AuthHandler) handlePasswordError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrPasswordResetInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrCurrentPasswordInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// refreshTokenFromRequest reads the refresh token from the JSON body or, when
+// the body has none, from the refresh cookie. Cookie use requires the CSRF
+// header. It writes the error response itself and reports false on failure.
+func (h *AuthHandler) refreshTokenFromRequest(c *gin.Context) (string, bool) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", false
+	}
+	if req.RefreshToken != "" {
+		return req.RefreshToken, true
+	}
+
+	if h.cookieMgr != nil {
+		if token := h.cookieMgr.RefreshToken(c); token != "" {
+			if !h.cookieMgr.ValidCSRF(c) {
+				c.JSON(http.StatusForbidden, gin.H{"error": ErrCSRFTokenInvalid.Error()})
+				return "", false
+			}
+			return token, true
+		}
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token wajib diisi"})
+	return "", false
+}
+
 func (h *AuthHandler) metadataFromContext(c *gin.Context) Metadata {
 	return Metadata{
 		UserAgent: c.Request.UserAgent(),
@@ -123,11 +498,239 @@ func (h *AuthHandler) handleAuthSuccess(c *gin.Context, status int, resp *AuthRe
 	c.JSON(status, resp)
 }
 
+// JWKS publishes the public verification keys so other services can check
+// access tokens without the signing secret.
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
+
 func (h *AuthHandler) Me(c *gin.Context) {
 	userID, _ := GetUserID(c)
 	userEmail, _ := GetUserEmail(c)
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"user_id": userID,
 		"email":   userEmail,
-	})
+	}
+	if actorID, ok := GetActorID(c); ok {
+		resp["impersonated_by"] = actorID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AuthHandler) StartImpersonation(c *gin.Context) {
+	var req StartImpersonationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	actorID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	resp, err := h.authService.StartImpersonation(c.Request.Context(), actorID, req, h.metadataFromContext(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPlatformAdminRequired):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrImpersonationInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *AuthHandler) StopImpersonation(c *gin.Context) {
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	actorID, _ := GetActorID(c)
+	sessionID, _ := GetSessionID(c)
+
+	if err := h.authService.StopImpersonation(c.Request.Context(), userID, actorID, sessionID, h.metadataFromContext(c)); err != nil {
+		if errors.Is(err, ErrNotImpersonating) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	currentSessionID, _ := GetSessionID(c)
+
+	sessions, err := h.authService.ListSessions(c.Request.Context(), userID, currentSessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (h *AuthHandler) ListSecurityEvents(c *gin.Context) {
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var query SecurityEventQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := h.authService.ListSecurityEvents(c.Request.Context(), userID, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+func (h *AuthHandler) RenameSession(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("sessionID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+
+	var req RenameSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	session, err := h.authService.RenameSession(c.Request.Context(), userID, sessionID, req.Name)
+	if err != nil {
+		h.handleSessionError(c, err)
+		return
+	}
+	currentSessionID, _ := GetSessionID(c)
+	session.Current = session.ID == currentSessionID
+	c.JSON(http.StatusOK, session)
+}
+
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("sessionID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.authService.RevokeSession(c.Request.Context(), userID, sessionID); err != nil {
+		h.handleSessionError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	currentSessionID, _ := GetSessionID(c)
+
+	if err := h.authService.RevokeOtherSessions(c.Request.Context(), userID, currentSessionID); err != nil {
+		h.handleSessionError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) ListAccessTokens(c *gin.Context) {
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	tokens, err := h.authService.ListAccessTokens(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) CreateAccessToken(c *gin.Context) {
+	var req CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	resp, err := h.authService.CreateAccessToken(c.Request.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, ErrAccessTokenInvalidScope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *AuthHandler) RevokeAccessToken(c *gin.Context) {
+	tokenID, err := uuid.Parse(c.Param("tokenID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token id"})
+		return
+	}
+	userID, ok := GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if err := h.authService.RevokeAccessToken(c.Request.Context(), userID, tokenID); err != nil {
+		if errors.Is(err, ErrAccessTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) handleSessionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrSessionUnknown):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is one asymmetric key identified by its kid. Only the active
+// key carries a private part; retired keys are kept for verification until
+// the tokens they signed have expired.
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// KeySet holds the active signing key and every key that is still accepted
+// for verification, including the active one.
+type KeySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+	order  []string
+}
+
+// JWK is the public form of a verification key as published in the JWKS
+// document.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet reads the active private key and any additional public keys
+// from PEM files. RSA keys sign with RS256 and Ed25519 keys with EdDSA.
+func LoadKeySet(privateKeyFile string, publicKeyFiles []string) (*KeySet, error) {
+	data, err := os.ReadFile(privateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("baca private key jwt: %w", err)
+	}
+	active, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", privateKeyFile, err)
+	}
+
+	set := &KeySet{active: active, keys: map[string]*signingKey{}}
+	set.add(active)
+
+	for _, path := range publicKeyFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("baca public key jwt: %w", err)
+		}
+		key, err := parsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		set.add(key)
+	}
+	return set, nil
+}
+
+func (ks *KeySet) add(key *signingKey) {
+	if _, exists := ks.keys[key.kid]; exists {
+		return
+	}
+	ks.keys[key.kid] = key
+	ks.order = append(ks.order, key.kid)
+}
+
+// verificationKey resolves the key for a parsed token and makes sure the
+// token's alg matches the key, so an RSA public key can never be used as an
+// HMAC secret.
+func (ks *KeySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.public, nil
+}
+
+func (ks *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(ks.order))}
+	for _, kid := range ks.order {
+		set.Keys = append(set.Keys, ks.keys[kid].jwk())
+	}
+	return set
+}
+
+func (k *signingKey) jwk() JWK {
+	jwk := JWK{Kid: k.kid, Use: "sig", Alg: k.method.Alg()}
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return jwk
+}
+
+func parsePrivateKey(data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("bukan file PEM")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("tipe PEM tidak didukung: %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key tidak didukung")
+	}
+	key, err := newSigningKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.private = signer
+	return key, nil
+}
+
+// parsePublicKey also accepts a private key file and keeps only its public
+// half, which makes it easy to demote the previous signing key.
+func parsePublicKey(data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("bukan file PEM")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newSigningKey(pub)
+	case "RSA PUBLIC KEY":
+		pub, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newSigningKey(pub)
+	default:
+		key, err := parsePrivateKey(data)
+		if err != nil {
+			return nil, err
+		}
+		key.private = nil
+		return key, nil
+	}
+}
+
+func newSigningKey(pub crypto.PublicKey) (*signingKey, error) {
+	key := &signingKey{public: pub}
+	switch p := pub.(type) {
+	case *rsa.PublicKey:
+		if p.N.BitLen() < 2048 {
+			return nil, errors.New("rsa key minimal 2048 bit")
+		}
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("tipe key tidak didukung: %T", pub)
+	}
+	key.kid = thumbprint(key.jwk())
+	return key, nil
+}
+
+// thumbprint derives a stable kid from the key material (RFC 7638).
+func thumbprint(jwk JWK) string {
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	raw, _ := json.Marshal(members)
+	sum := sha256.Sum256(raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
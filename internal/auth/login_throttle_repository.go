@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"kerjakuy/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginThrottleRepository interface {
+	FindBlocked(ctx context.Context, scope, key string, now time.Time) (*models.LoginThrottle, error)
+	Increment(ctx context.Context, scope, key string, now, windowStart time.Time) (*models.LoginThrottle, error)
+	SetBlockedUntil(ctx context.Context, id uuid.UUID, until time.Time) error
+	Reset(ctx context.Context, scope, key string) error
+	DeleteStale(ctx context.Context, now, windowStart time.Time) error
+}
+
+type loginThrottleRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginThrottleRepository(db *gorm.DB) LoginThrottleRepository {
+	return &loginThrottleRepository{db: db}
+}
+
+func (r *loginThrottleRepository) FindBlocked(ctx context.Context, scope, key string, now time.Time) (*models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+	if err := r.db.WithContext(ctx).
+		Where("scope = ? AND throttle_key = ? AND blocked_until > ?", scope, key, now).
+		First(&throttle).Error; err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// Increment bumps the counter in a single upsert. A counter whose last hit
+// is older than windowStart starts over at one.
+func (r *loginThrottleRepository) Increment(ctx context.Context, scope, key string, now, windowStart time.Time) (*models.LoginThrottle, error) {
+	throttle := &models.LoginThrottle{
+		Scope:        scope,
+		Key:          key,
+		Failures:     1,
+		LastFailedAt: now,
+	}
+	err := r.db.WithContext(ctx).Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "scope"}, {Name: "throttle_key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":       gorm.Expr("CASE WHEN login_throttles.last_failed_at < ? THEN 1 ELSE login_throttles.failures + 1 END", windowStart),
+				"last_failed_at": now,
+				"updated_at":     now,
+			}),
+		},
+		clause.Returning{},
+	).Create(throttle).Error
+	if err != nil {
+		return nil, err
+	}
+	return throttle, nil
+}
+
+func (r *loginThrottleRepository) SetBlockedUntil(ctx context.Context, id uuid.UUID, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.LoginThrottle{}).Where("id = ?", id).Update("blocked_until", until).Error
+}
+
+func (r *loginThrottleRepository) Reset(ctx context.Context, scope, key string) error {
+	return r.db.WithContext(ctx).
+		Where("scope = ? AND throttle_key = ?", scope, key).
+		Delete(&models.LoginThrottle{}).Error
+}
+
+// DeleteStale removes counters that are outside the window and no longer
+// block anything.
+func (r *loginThrottleRepository) DeleteStale(ctx context.Context, now, windowStart time.Time) error {
+	return r.db.WithContext(ctx).
+		Where("last_failed_at < ? AND (blocked_until IS NULL OR blocked_until <= ?)", windowStart, now).
+		Delete(&models.LoginThrottle{}).Error
+}
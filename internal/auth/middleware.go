@@ -1,9 +1,14 @@
 package auth
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 
+	"kerjakuy/internal/models"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -11,50 +16,233 @@ import (
 const (
 	ContextUserIDKey    = "auth_user_id"
 	ContextUserEmailKey = "auth_user_email"
+	ContextSessionIDKey = "auth_session_id"
+	ContextActorIDKey   = "auth_actor_id"
+
+	// ImpersonatedByHeader is set on every response to an impersonated
+	// request so clients can show a banner.
+	ImpersonatedByHeader = "X-Impersonated-By"
 )
 
+var (
+	ErrCSRFTokenInvalid          = errors.New("csrf token tidak valid")
+	ErrAccessTokenNotAllowed     = errors.New("personal access token tidak diizinkan untuk endpoint ini")
+	ErrImpersonationAuditFailure = errors.New("gagal mencatat audit penyamaran, permintaan dibatalkan")
+)
+
+// ActivityRecorder stores the audit entries written for impersonated
+// requests.
+type ActivityRecorder interface {
+	Create(ctx context.Context, log *models.ActivityLog) error
+}
+
+// membershipLister is implemented by repository.WorkspaceMemberRepository.
+type membershipLister interface {
+	ListWorkspaceIDsByUser(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+}
+
 type AuthMiddleware struct {
 	authService Service
+	cookieMgr   CookieManager
+	activity    ActivityRecorder
+	members     membershipLister
+	logger      *slog.Logger
 }
 
-func NewAuthMiddleware(authService Service) *AuthMiddleware {
-	return &AuthMiddleware{authService: authService}
+func NewAuthMiddleware(authService Service, cookieMgr CookieManager, activity ActivityRecorder, members membershipLister, logger *slog.Logger) *AuthMiddleware {
+	return &AuthMiddleware{authService: authService, cookieMgr: cookieMgr, activity: activity, members: members, logger: logger}
 }
 
+// RequireAuth accepts a JWT access token (header or cookie). Personal access
+// tokens are refused, since their scope is only enforced by workspace
+// permission checks.
 func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
+	return m.requireAuth(false, true)
+}
+
+// RequireWorkspaceAuth is RequireAuth that also accepts a personal access
+// token. Only mount it on routes whose services check every request through
+// PermissionService, which applies the token scope.
+func (m *AuthMiddleware) RequireWorkspaceAuth() gin.HandlerFunc {
+	return m.requireAuth(true, true)
+}
+
+// RequireSessionAuth is RequireAuth for account management endpoints, which
+// a personal access token or an impersonating admin must never reach (e.g.
+// minting more tokens).
+func (m *AuthMiddleware) RequireSessionAuth() gin.HandlerFunc {
+	return m.requireAuth(false, false)
+}
+
+func (m *AuthMiddleware) requireAuth(allowAccessTokens, allowImpersonation bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token := extractBearerToken(c.GetHeader("Authorization"))
+		token, fromCookie := m.extractToken(c)
 		if token == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header missing"})
 			return
 		}
-		claims, err := m.authService.ValidateAccessToken(token)
+		if fromCookie && !m.csrfSatisfied(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": ErrCSRFTokenInvalid.Error()})
+			return
+		}
+		if !fromCookie && isPersonalAccessToken(token) && !allowAccessTokens {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": ErrAccessTokenNotAllowed.Error()})
+			return
+		}
+		claims, err := m.validate(c, token, fromCookie)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
-		c.Set(ContextUserIDKey, claims.UserID)
-		c.Set(ContextUserEmailKey, claims.Email)
+		if claims.ActorID != uuid.Nil && !allowImpersonation {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": ErrImpersonationNotAllowed.Error()})
+			return
+		}
+		setClaims(c, claims)
+		impersonatedWrite := claims.ActorID != uuid.Nil && !isSafeMethod(c.Request.Method)
+		if impersonatedWrite && !m.auditImpersonatedWrite(c) {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": ErrImpersonationAuditFailure.Error()})
+			return
+		}
 		c.Next()
+		if impersonatedWrite {
+			m.logImpersonatedActivity(c)
+		}
 	}
 }
 
+// auditImpersonatedWrite records the write in the subject's security log
+// before the handler runs. A write that cannot be audited is not made.
+func (m *AuthMiddleware) auditImpersonatedWrite(c *gin.Context) bool {
+	imp := ImpersonationFromContext(c.Request.Context())
+	err := m.authService.AuditImpersonatedWrite(c.Request.Context(), imp, Metadata{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}, map[string]interface{}{
+		"method": c.Request.Method,
+		"path":   c.Request.URL.Path,
+		"route":  c.FullPath(),
+	})
+	if err != nil {
+		m.logger.Error("failed to audit impersonated write", "error", err, "actor_id", imp.ActorID, "user_id", imp.SubjectID)
+		return false
+	}
+	return true
+}
+
+// logImpersonatedActivity also files the write in the activity log, so
+// workspace admins see it: under the workspace whose write permission was
+// checked, or, for writes that never got that far (profile changes,
+// creating a workspace, rejected requests), under every workspace the
+// subject belongs to.
+func (m *AuthMiddleware) logImpersonatedActivity(c *gin.Context) {
+	if m.activity == nil {
+		return
+	}
+	ctx := context.WithoutCancel(c.Request.Context())
+	imp := ImpersonationFromContext(ctx)
+	workspaceIDs := []uuid.UUID{imp.WorkspaceID}
+	if imp.WorkspaceID == uuid.Nil {
+		ids, err := m.members.ListWorkspaceIDsByUser(ctx, imp.SubjectID)
+		if err != nil {
+			m.logger.Error("failed to list workspaces for impersonated write", "error", err, "actor_id", imp.ActorID, "user_id", imp.SubjectID)
+			return
+		}
+		workspaceIDs = ids
+	}
+
+	actorID := imp.ActorID
+	subjectID := imp.SubjectID
+	for _, workspaceID := range workspaceIDs {
+		err := m.activity.Create(ctx, &models.ActivityLog{
+			WorkspaceID: workspaceID,
+			UserID:      &actorID,
+			Action:      "impersonation.write",
+			TargetType:  "user",
+			TargetID:    &subjectID,
+			Metadata: map[string]interface{}{
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+				"route":  c.FullPath(),
+				"status": c.Writer.Status(),
+			},
+		})
+		if err != nil {
+			m.logger.Error("failed to log impersonated write", "error", err, "actor_id", actorID, "workspace_id", workspaceID)
+		}
+	}
+}
+
+func (m *AuthMiddleware) validate(c *gin.Context, token string, fromCookie bool) (*Claims, error) {
+	if !fromCookie && isPersonalAccessToken(token) {
+		return m.authService.ValidatePersonalAccessToken(c.Request.Context(), token)
+	}
+	return m.authService.ValidateAccessToken(token)
+}
+
 func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token := extractBearerToken(c.GetHeader("Authorization"))
-		if token == "" {
+		token, fromCookie := m.extractToken(c)
+		if token == "" || (fromCookie && !m.csrfSatisfied(c)) {
 			c.Next()
 			return
 		}
-		claims, err := m.authService.ValidateAccessToken(token)
+		claims, err := m.validate(c, token, fromCookie)
 		if err == nil {
-			c.Set(ContextUserIDKey, claims.UserID)
-			c.Set(ContextUserEmailKey, claims.Email)
+			setClaims(c, claims)
 		}
 		c.Next()
 	}
 }
 
+// extractToken prefers the Authorization header and falls back to the access
+// cookie. It reports whether the token came from the cookie.
+func (m *AuthMiddleware) extractToken(c *gin.Context) (string, bool) {
+	if token := extractBearerToken(c.GetHeader("Authorization")); token != "" {
+		return token, false
+	}
+	if m.cookieMgr == nil {
+		return "", false
+	}
+	return m.cookieMgr.AccessToken(c), true
+}
+
+// csrfSatisfied only demands the CSRF header for unsafe methods; safe
+// requests must not change state anyway.
+func (m *AuthMiddleware) csrfSatisfied(c *gin.Context) bool {
+	if isSafeMethod(c.Request.Method) {
+		return true
+	}
+	return m.cookieMgr.ValidCSRF(c)
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func setClaims(c *gin.Context, claims *Claims) {
+	c.Set(ContextUserIDKey, claims.UserID)
+	c.Set(ContextUserEmailKey, claims.Email)
+	if claims.SessionID != uuid.Nil {
+		c.Set(ContextSessionIDKey, claims.SessionID)
+	}
+	if claims.Scope != nil {
+		c.Request = c.Request.WithContext(WithTokenScope(c.Request.Context(), claims.Scope))
+	}
+	if claims.ActorID != uuid.Nil {
+		c.Set(ContextActorIDKey, claims.ActorID)
+		c.Header(ImpersonatedByHeader, claims.ActorID.String())
+		c.Request = c.Request.WithContext(WithImpersonation(c.Request.Context(), &Impersonation{
+			ActorID:   claims.ActorID,
+			SubjectID: claims.UserID,
+		}))
+	}
+}
+
 func GetUserID(c *gin.Context) (uuid.UUID, bool) {
 	v, exists := c.Get(ContextUserIDKey)
 	if !exists {
@@ -73,6 +261,25 @@ func GetUserEmail(c *gin.Context) (string, bool) {
 	return email, ok
 }
 
+func GetSessionID(c *gin.Context) (uuid.UUID, bool) {
+	v, exists := c.Get(ContextSessionIDKey)
+	if !exists {
+		return uuid.Nil, false
+	}
+	id, ok := v.(uuid.UUID)
+	return id, ok
+}
+
+// GetActorID returns the platform admin behind an impersonated request.
+func GetActorID(c *gin.Context) (uuid.UUID, bool) {
+	v, exists := c.Get(ContextActorIDKey)
+	if !exists {
+		return uuid.Nil, false
+	}
+	id, ok := v.(uuid.UUID)
+	return id, ok
+}
+
 func extractBearerToken(header string) string {
 	if header == "" {
 		return ""
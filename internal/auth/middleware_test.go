@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestRequireAuthRejectsAccessTokensByDefault(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ta := newTestAuth(t)
+	u := ta.users.add(t, "ana@example.com", "rahasia123")
+	workspaceID := uuid.New()
+	ta.members.add(u.ID, workspaceID, string(rbac.RoleMember))
+
+	login, err := ta.Login(context.Background(), LoginRequest{Email: u.Email, Password: "rahasia123"}, Metadata{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	pat, err := ta.CreateAccessToken(context.Background(), u.ID, CreateAccessTokenRequest{
+		Name:         "ci",
+		WorkspaceIDs: []uuid.UUID{workspaceID},
+		Permissions:  []string{string(rbac.PermissionCreateTask)},
+	})
+	if err != nil {
+		t.Fatalf("CreateAccessToken: %v", err)
+	}
+
+	m := NewAuthMiddleware(ta, nil, nil, ta.members, ta.logger)
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router.GET("/account", m.RequireAuth(), ok)
+	router.GET("/workspace", m.RequireWorkspaceAuth(), ok)
+	router.GET("/settings", m.RequireSessionAuth(), ok)
+
+	tests := []struct {
+		path  string
+		token string
+		want  int
+	}{
+		{path: "/account", token: login.Tokens.AccessToken, want: http.StatusNoContent},
+		{path: "/account", token: pat.Token, want: http.StatusForbidden},
+		{path: "/workspace", token: login.Tokens.AccessToken, want: http.StatusNoContent},
+		{path: "/workspace", token: pat.Token, want: http.StatusNoContent},
+		{path: "/settings", token: login.Tokens.AccessToken, want: http.StatusNoContent},
+		{path: "/settings", token: pat.Token, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("GET %s with %s: status = %d, want %d", tt.path, tt.token[:8], rec.Code, tt.want)
+		}
+	}
+}
+
+func TestImpersonatedWritesFailClosed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name      string
+		auditErr  error
+		want      int
+		wantWrite bool
+	}{
+		{name: "audited write goes through", want: http.StatusNoContent, wantWrite: true},
+		{name: "write refused when audit fails", auditErr: errors.New("db down"), want: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			admin := ta.users.add(t, "admin@example.com", "rahasia123")
+			admin.IsPlatformAdmin = true
+			subject := ta.users.add(t, "ana@example.com", "rahasia123")
+			imp, err := ta.StartImpersonation(context.Background(), admin.ID, StartImpersonationRequest{UserID: subject.ID, Reason: "tiket #1"}, Metadata{})
+			if err != nil {
+				t.Fatalf("StartImpersonation: %v", err)
+			}
+			ta.events.err = tt.auditErr
+
+			wrote := false
+			router := gin.New()
+			router.PATCH("/users/me", NewAuthMiddleware(ta, nil, nil, ta.members, ta.logger).RequireAuth(), func(c *gin.Context) {
+				wrote = true
+				c.Status(http.StatusNoContent)
+			})
+			req := httptest.NewRequest(http.MethodPatch, "/users/me", nil)
+			req.Header.Set("Authorization", "Bearer "+imp.AccessToken)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want || wrote != tt.wantWrite {
+				t.Fatalf("status = %d, handler ran = %v; want %d, %v", rec.Code, wrote, tt.want, tt.wantWrite)
+			}
+			if tt.wantWrite && ta.events.count(SecurityEventImpersonation) != 2 {
+				t.Errorf("impersonation events = %d, want start and write", ta.events.count(SecurityEventImpersonation))
+			}
+		})
+	}
+}
+
+func TestImpersonatedWritesReachActivityLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ta := newTestAuth(t)
+	admin := ta.users.add(t, "admin@example.com", "rahasia123")
+	admin.IsPlatformAdmin = true
+	subject := ta.users.add(t, "ana@example.com", "rahasia123")
+	first, second := uuid.New(), uuid.New()
+	ta.members.add(subject.ID, first, string(rbac.RoleMember))
+	ta.members.add(subject.ID, second, string(rbac.RoleMember))
+	imp, err := ta.StartImpersonation(context.Background(), admin.ID, StartImpersonationRequest{UserID: subject.ID, Reason: "tiket #1"}, Metadata{})
+	if err != nil {
+		t.Fatalf("StartImpersonation: %v", err)
+	}
+	permissions := NewPermissionService(ta.members, &fakeWorkspaces{byID: map[uuid.UUID]*models.Workspace{
+		first:  {ID: first},
+		second: {ID: second},
+	}}, newFakeTwoFactor(), newFakeUsers())
+
+	tests := []struct {
+		name  string
+		path  string
+		check bool
+		want  []uuid.UUID
+	}{
+		{name: "write checked against a workspace is filed there", path: "/tasks", check: true, want: []uuid.UUID{first}},
+		{name: "write outside a workspace is filed in every workspace of the subject", path: "/users/me", want: []uuid.UUID{first, second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activity := &fakeActivity{}
+			router := gin.New()
+			router.POST(tt.path, NewAuthMiddleware(ta, nil, activity, ta.members, ta.logger).RequireWorkspaceAuth(), func(c *gin.Context) {
+				if tt.check {
+					permissions.HasPermission(c.Request.Context(), subject.ID, first, rbac.PermissionCreateTask)
+				}
+				c.Status(http.StatusNoContent)
+			})
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+imp.AccessToken)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			got := map[uuid.UUID]bool{}
+			for _, row := range activity.rows {
+				if row.UserID == nil || *row.UserID != admin.ID || row.TargetID == nil || *row.TargetID != subject.ID {
+					t.Errorf("entry %+v not filed as the admin acting on the subject", row)
+				}
+				got[row.WorkspaceID] = true
+			}
+			if len(activity.rows) != len(tt.want) {
+				t.Fatalf("activity entries = %d, want %d", len(activity.rows), len(tt.want))
+			}
+			for _, id := range tt.want {
+				if !got[id] {
+					t.Errorf("no entry for workspace %s", id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"kerjakuy/pkg/config"
+)
+
+const (
+	googleIssuerURL     = "https://accounts.google.com"
+	githubAuthorizeURL  = "https://github.com/login/oauth/authorize"
+	githubTokenURL      = "https://github.com/login/oauth/access_token"
+	githubAPIURL        = "https://api.github.com"
+	oidcDiscoveryPath   = "/.well-known/openid-configuration"
+	oauthResponseMaxLen = 1 << 20
+)
+
+// OAuthUserInfo is the normalized identity returned by a provider after a
+// successful code exchange.
+type OAuthUserInfo struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	AvatarURL     *string
+}
+
+type OAuthProvider interface {
+	Name() string
+	RedirectURL() string
+	AuthCodeURL(ctx context.Context, state, codeChallenge, redirectURI string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, redirectURI string) (*OAuthUserInfo, error)
+}
+
+type OAuthRegistry struct {
+	providers map[string]OAuthProvider
+}
+
+func NewOAuthRegistry(providers ...OAuthProvider) *OAuthRegistry {
+	registry := &OAuthRegistry{providers: make(map[string]OAuthProvider, len(providers))}
+	for _, p := range providers {
+		registry.providers[p.Name()] = p
+	}
+	return registry
+}
+
+// NewOAuthRegistryFromConfig builds providers for every configured preset.
+func NewOAuthRegistryFromConfig(cfgs []config.OAuthProviderConfig, client *http.Client) (*OAuthRegistry, error) {
+	providers := make([]OAuthProvider, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		switch cfg.Kind {
+		case "google":
+			if cfg.IssuerURL == "" {
+				cfg.IssuerURL = googleIssuerURL
+			}
+			providers = append(providers, NewOIDCProvider(cfg, client))
+		case "oidc":
+			providers = append(providers, NewOIDCProvider(cfg, client))
+		case "github":
+			providers = append(providers, NewGitHubProvider(cfg, client))
+		default:
+			return nil, fmt.Errorf("jenis oauth provider tidak dikenal: %s", cfg.Kind)
+		}
+	}
+	return NewOAuthRegistry(providers...), nil
+}
+
+func (r *OAuthRegistry) Get(name string) (OAuthProvider, error) {
+	if r != nil {
+		if p, ok := r.providers[name]; ok {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrOAuthProviderNotConfigured, name)
+}
+
+type oauthEndpoints struct {
+	Issuer      string `json:"issuer"`
+	AuthURL     string `json:"authorization_endpoint"`
+	TokenURL    string `json:"token_endpoint"`
+	UserInfoURL string `json:"userinfo_endpoint"`
+}
+
+type oauthClient struct {
+	name         string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+	http         *http.Client
+}
+
+func newOAuthClient(cfg config.OAuthProviderConfig, client *http.Client, defaultScopes []string) oauthClient {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+	return oauthClient{
+		name:         cfg.Name,
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		redirectURL:  cfg.RedirectURL,
+		scopes:       scopes,
+		http:         client,
+	}
+}
+
+func (c *oauthClient) Name() string {
+	return c.name
+}
+
+func (c *oauthClient) RedirectURL() string {
+	return c.redirectURL
+}
+
+func (c *oauthClient) authCodeURL(authURL, state, codeChallenge, redirectURI string) (string, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", c.clientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("scope", strings.Join(c.scopes, " "))
+	q.Set("state", state)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func (c *oauthClient) exchangeCode(ctx context.Context, tokenURL, code, codeVerifier, redirectURI string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("client_id", c.clientID)
+	form.Set("client_secret", c.clientSecret)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var payload struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := c.doJSON(req, &payload); err != nil {
+		return "", err
+	}
+	if payload.Error != "" {
+		return "", fmt.Errorf("oauth token exchange gagal: %s %s", payload.Error, payload.ErrorDescription)
+	}
+	if payload.AccessToken == "" {
+		return "", errors.New("oauth token exchange tidak mengembalikan access token")
+	}
+	return payload.AccessToken, nil
+}
+
+func (c *oauthClient) getJSON(ctx context.Context, endpoint, accessToken string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return c.doJSON(req, out)
+}
+
+func (c *oauthClient) doJSON(req *http.Request, out interface{}) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oauthResponseMaxLen))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("oauth provider %s mengembalikan status %d", c.name, resp.StatusCode)
+	}
+	return json.Unmarshal(body, out)
+}
+
+// oidcProvider implements the authorization code flow against any OpenID
+// Connect issuer. Endpoints are discovered lazily and cached.
+type oidcProvider struct {
+	oauthClient
+	issuerURL string
+
+	mu        sync.Mutex
+	endpoints *oauthEndpoints
+}
+
+func NewOIDCProvider(cfg config.OAuthProviderConfig, client *http.Client) OAuthProvider {
+	return &oidcProvider{
+		oauthClient: newOAuthClient(cfg, client, []string{"openid", "email", "profile"}),
+		issuerURL:   strings.TrimRight(cfg.IssuerURL, "/"),
+	}
+}
+
+func (p *oidcProvider) AuthCodeURL(ctx context.Context, state, codeChallenge, redirectURI string) (string, error) {
+	endpoints, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.authCodeURL(endpoints.AuthURL, state, codeChallenge, redirectURI)
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code, codeVerifier, redirectURI string) (*OAuthUserInfo, error) {
+	endpoints, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := p.exchangeCode(ctx, endpoints.TokenURL, code, codeVerifier, redirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims struct {
+		Subject       string          `json:"sub"`
+		Email         string          `json:"email"`
+		EmailVerified json.RawMessage `json:"email_verified"`
+		Name          string          `json:"name"`
+		Picture       string          `json:"picture"`
+	}
+	if err := p.getJSON(ctx, endpoints.UserInfoURL, accessToken, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("userinfo oidc tidak memiliki sub")
+	}
+
+	info := &OAuthUserInfo{
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(claims.Email),
+		EmailVerified: parseLooseBool(claims.EmailVerified),
+		Name:          claims.Name,
+	}
+	if claims.Picture != "" {
+		info.AvatarURL = &claims.Picture
+	}
+	return info, nil
+}
+
+func (p *oidcProvider) discover(ctx context.Context) (*oauthEndpoints, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.endpoints != nil {
+		return p.endpoints, nil
+	}
+
+	var endpoints oauthEndpoints
+	if err := p.getJSON(ctx, p.issuerURL+oidcDiscoveryPath, "", &endpoints); err != nil {
+		return nil, fmt.Errorf("oidc discovery %s gagal: %w", p.name, err)
+	}
+	if strings.TrimRight(endpoints.Issuer, "/") != p.issuerURL {
+		return nil, fmt.Errorf("oidc issuer tidak cocok: %s", endpoints.Issuer)
+	}
+	if endpoints.AuthURL == "" || endpoints.TokenURL == "" || endpoints.UserInfoURL == "" {
+		return nil, fmt.Errorf("oidc discovery %s tidak lengkap", p.name)
+	}
+	p.endpoints = &endpoints
+	return p.endpoints, nil
+}
+
+// githubProvider uses GitHub's OAuth apps flow, which is not OIDC compliant,
+// so the profile and primary email are read from the REST API.
+type githubProvider struct {
+	oauthClient
+	authURL  string
+	tokenURL string
+	apiURL   string
+}
+
+func NewGitHubProvider(cfg config.OAuthProviderConfig, client *http.Client) OAuthProvider {
+	p := &githubProvider{
+		oauthClient: newOAuthClient(cfg, client, []string{"read:user", "user:email"}),
+		authURL:     githubAuthorizeURL,
+		tokenURL:    githubTokenURL,
+		apiURL:      githubAPIURL,
+	}
+	if cfg.IssuerURL != "" {
+		// Allows GitHub Enterprise or a local stand-in server.
+		base := strings.TrimRight(cfg.IssuerURL, "/")
+		p.authURL = base + "/login/oauth/authorize"
+		p.tokenURL = base + "/login/oauth/access_token"
+		p.apiURL = base + "/api/v3"
+	}
+	return p
+}
+
+func (p *githubProvider) AuthCodeURL(ctx context.Context, state, codeChallenge, redirectURI string) (string, error) {
+	return p.authCodeURL(p.authURL, state, codeChallenge, redirectURI)
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code, codeVerifier, redirectURI string) (*OAuthUserInfo, error) {
+	accessToken, err := p.exchangeCode(ctx, p.tokenURL, code, codeVerifier, redirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := p.getJSON(ctx, p.apiURL+"/user", accessToken, &profile); err != nil {
+		return nil, err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.getJSON(ctx, p.apiURL+"/user/emails", accessToken, &emails); err != nil {
+		return nil, err
+	}
+
+	info := &OAuthUserInfo{
+		Subject: strconv.FormatInt(profile.ID, 10),
+		Name:    profile.Name,
+	}
+	if info.Name == "" {
+		info.Name = profile.Login
+	}
+	if profile.AvatarURL != "" {
+		info.AvatarURL = &profile.AvatarURL
+	}
+	for _, e := range emails {
+		if e.Primary {
+			info.Email = strings.ToLower(e.Email)
+			info.EmailVerified = e.Verified
+			break
+		}
+	}
+	return info, nil
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// parseLooseBool accepts both JSON booleans and the "true"/"false" strings
+// some providers return for email_verified.
+func parseLooseBool(raw json.RawMessage) bool {
+	if len(raw) == 0 {
+		return false
+	}
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.EqualFold(s, "true")
+	}
+	return false
+}
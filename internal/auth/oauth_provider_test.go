@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"kerjakuy/pkg/config"
+)
+
+// fakeOIDCIssuer serves discovery, token and userinfo endpoints for a single
+// authorization code.
+type fakeOIDCIssuer struct {
+	server   *httptest.Server
+	issuer   string
+	code     string
+	verifier string
+	userinfo map[string]interface{}
+	tokenErr string
+}
+
+func newFakeOIDCIssuer(t *testing.T) *fakeOIDCIssuer {
+	t.Helper()
+	f := &fakeOIDCIssuer{code: "auth-code", verifier: "pkce-verifier"}
+	mux := http.NewServeMux()
+	mux.HandleFunc(oidcDiscoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 f.issuer,
+			"authorization_endpoint": f.server.URL + "/authorize",
+			"token_endpoint":         f.server.URL + "/token",
+			"userinfo_endpoint":      f.server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if f.tokenErr != "" {
+			writeJSON(w, map[string]string{"error": f.tokenErr})
+			return
+		}
+		if r.PostForm.Get("code") != f.code || r.PostForm.Get("code_verifier") != f.verifier {
+			writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		writeJSON(w, map[string]string{"access_token": "provider-token", "token_type": "Bearer"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer provider-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, f.userinfo)
+	})
+	f.server = httptest.NewServer(mux)
+	f.issuer = f.server.URL
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeOIDCIssuer) provider() OAuthProvider {
+	return NewOIDCProvider(config.OAuthProviderConfig{
+		Name:         "sso",
+		Kind:         "oidc",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		IssuerURL:    f.server.URL + "/",
+		RedirectURL:  "http://app.test/callback",
+	}, f.server.Client())
+}
+
+func TestOIDCProviderAuthCodeURL(t *testing.T) {
+	f := newFakeOIDCIssuer(t)
+	raw, err := f.provider().AuthCodeURL(context.Background(), "state-1", pkceChallenge(f.verifier), "http://app.test/callback")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse url: %v", err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != f.server.URL+"/authorize" {
+		t.Errorf("authorize endpoint = %s", got)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             "client",
+		"redirect_uri":          "http://app.test/callback",
+		"scope":                 "openid email profile",
+		"state":                 "state-1",
+		"code_challenge":        pkceChallenge(f.verifier),
+		"code_challenge_method": "S256",
+	}
+	for key, value := range want {
+		if q.Get(key) != value {
+			t.Errorf("%s = %q, want %q", key, q.Get(key), value)
+		}
+	}
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	tests := []struct {
+		name     string
+		issuer   string
+		code     string
+		tokenErr string
+		userinfo map[string]interface{}
+		want     *OAuthUserInfo
+		wantErr  bool
+	}{
+		{
+			name:     "verified boolean",
+			userinfo: map[string]interface{}{"sub": "123", "email": "Ana@Example.com", "email_verified": true, "name": "Ana", "picture": "https://img.test/a.png"},
+			want:     &OAuthUserInfo{Subject: "123", Email: "ana@example.com", EmailVerified: true, Name: "Ana"},
+		},
+		{
+			name:     "verified string",
+			userinfo: map[string]interface{}{"sub": "123", "email": "ana@example.com", "email_verified": "true"},
+			want:     &OAuthUserInfo{Subject: "123", Email: "ana@example.com", EmailVerified: true},
+		},
+		{
+			name:     "unverified email",
+			userinfo: map[string]interface{}{"sub": "123", "email": "ana@example.com"},
+			want:     &OAuthUserInfo{Subject: "123", Email: "ana@example.com"},
+		},
+		{
+			name:     "missing subject",
+			userinfo: map[string]interface{}{"email": "ana@example.com", "email_verified": true},
+			wantErr:  true,
+		},
+		{
+			name:     "wrong code",
+			code:     "other-code",
+			userinfo: map[string]interface{}{"sub": "123"},
+			wantErr:  true,
+		},
+		{
+			name:     "token endpoint error",
+			tokenErr: "invalid_client",
+			userinfo: map[string]interface{}{"sub": "123"},
+			wantErr:  true,
+		},
+		{
+			name:     "issuer mismatch",
+			issuer:   "https://evil.test",
+			userinfo: map[string]interface{}{"sub": "123"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeOIDCIssuer(t)
+			if tt.issuer != "" {
+				f.issuer = tt.issuer
+			}
+			f.tokenErr = tt.tokenErr
+			f.userinfo = tt.userinfo
+			code := f.code
+			if tt.code != "" {
+				code = tt.code
+			}
+
+			info, err := f.provider().Exchange(context.Background(), code, f.verifier, "http://app.test/callback")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Exchange() = %+v, want error", info)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if info.Subject != tt.want.Subject || info.Email != tt.want.Email || info.EmailVerified != tt.want.EmailVerified || info.Name != tt.want.Name {
+				t.Errorf("Exchange() = %+v, want %+v", info, tt.want)
+			}
+			if picture, ok := tt.userinfo["picture"].(string); ok && (info.AvatarURL == nil || *info.AvatarURL != picture) {
+				t.Errorf("AvatarURL = %v, want %s", info.AvatarURL, picture)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"kerjakuy/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OAuthStateRepository interface {
+	Create(ctx context.Context, state *models.OAuthState) error
+	Consume(ctx context.Context, stateHash string) (*models.OAuthState, error)
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+type UserIdentityRepository interface {
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	FindByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+}
+
+type oauthStateRepository struct {
+	db *gorm.DB
+}
+
+type userIdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthStateRepository(db *gorm.DB) OAuthStateRepository {
+	return &oauthStateRepository{db: db}
+}
+
+func NewUserIdentityRepository(db *gorm.DB) UserIdentityRepository {
+	return &userIdentityRepository{db: db}
+}
+
+func (r *oauthStateRepository) Create(ctx context.Context, state *models.OAuthState) error {
+	return r.db.WithContext(ctx).Create(state).Error
+}
+
+// Consume deletes the state row and returns it, so a state value can only be
+// redeemed once even when two callbacks race.
+func (r *oauthStateRepository) Consume(ctx context.Context, stateHash string) (*models.OAuthState, error) {
+	var states []models.OAuthState
+	result := r.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("state_hash = ?", stateHash).
+		Delete(&states)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(states) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &states[0], nil
+}
+
+func (r *oauthStateRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.OAuthState{}).Error
+}
+
+func (r *userIdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+func (r *userIdentityRepository) FindByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
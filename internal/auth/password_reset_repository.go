@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"kerjakuy/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, token *models.PasswordResetToken) error
+	Consume(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error)
+	DeleteByUser(ctx context.Context, userID uuid.UUID) error
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+func (r *passwordResetRepository) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// Consume marks an unused, unexpired token as used and returns it. The
+// conditional update makes the token single-use even under concurrent calls.
+func (r *passwordResetRepository) Consume(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	var tokens []models.PasswordResetToken
+	result := r.db.WithContext(ctx).Model(&tokens).
+		Clauses(clause.Returning{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(tokens) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &tokens[0], nil
+}
+
+func (r *passwordResetRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.PasswordResetToken{}).Error
+}
+
+func (r *passwordResetRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.PasswordResetToken{}).Error
+}
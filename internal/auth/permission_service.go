@@ -2,31 +2,161 @@ package auth
 
 import (
 	"context"
+	"errors"
 
+	"kerjakuy/internal/models"
 	"kerjakuy/internal/pkg/rbac"
 	"kerjakuy/internal/repository"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrWorkspaceRequiresTwoFactor     = errors.New("workspace ini mewajibkan verifikasi dua langkah")
+	ErrWorkspaceRequiresVerifiedEmail = errors.New("workspace ini mewajibkan email yang sudah terverifikasi")
+	ErrWorkspacePendingDeletion       = errors.New("workspace ini dijadwalkan untuk dihapus")
+)
+
+// readPermissions are checked through HasPermission but only read data, so
+// they keep working while a workspace waits to be deleted.
+var readPermissions = map[rbac.Permission]bool{
+	rbac.PermissionViewSecurityLog: true,
+}
+
 type PermissionService interface {
 	HasPermission(ctx context.Context, userID uuid.UUID, workspaceID uuid.UUID, perm rbac.Permission) (bool, error)
+	CanViewWorkspace(ctx context.Context, userID uuid.UUID, workspaceID uuid.UUID) (bool, error)
+	CanJoinWorkspace(ctx context.Context, userID uuid.UUID, workspaceID uuid.UUID) error
+}
+
+type workspaceFinder interface {
+	FindByID(ctx context.Context, id uuid.UUID) (*models.Workspace, error)
+}
+
+type twoFactorChecker interface {
+	IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error)
+}
+
+type emailVerificationChecker interface {
+	IsEmailVerified(ctx context.Context, userID uuid.UUID) (bool, error)
 }
 
 type permissionService struct {
-	memberRepo repository.WorkspaceMemberRepository
+	memberRepo    repository.WorkspaceMemberRepository
+	workspaceRepo workspaceFinder
+	twoFactor     twoFactorChecker
+	users         emailVerificationChecker
 }
 
-func NewPermissionService(memberRepo repository.WorkspaceMemberRepository) PermissionService {
-	return &permissionService{memberRepo: memberRepo}
+func NewPermissionService(memberRepo repository.WorkspaceMemberRepository, workspaceRepo workspaceFinder, twoFactor twoFactorChecker, users emailVerificationChecker) PermissionService {
+	return &permissionService{
+		memberRepo:    memberRepo,
+		workspaceRepo: workspaceRepo,
+		twoFactor:     twoFactor,
+		users:         users,
+	}
 }
 
 func (s *permissionService) HasPermission(ctx context.Context, userID uuid.UUID, workspaceID uuid.UUID, perm rbac.Permission) (bool, error) {
+	member, workspace, err := s.activeMember(ctx, userID, workspaceID)
+	if member == nil || err != nil {
+		return false, err
+	}
+	// A workspace waiting to be deleted is read-only.
+	if workspace.DeletionScheduledAt != nil && !readPermissions[perm] {
+		return false, ErrWorkspacePendingDeletion
+	}
+	// A write check names the workspace an impersonated write lands in, so
+	// the middleware can file it in that workspace's activity log.
+	if imp := ImpersonationFromContext(ctx); imp != nil {
+		imp.WorkspaceID = workspaceID
+	}
+
+	role := rbac.Role(member.Role)
+	if !rbac.HasPermission(role, perm) {
+		return false, nil
+	}
+	// A personal access token can only narrow what the user's role allows.
+	if scope := TokenScopeFromContext(ctx); scope != nil && !scope.Allows(workspaceID, perm) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// CanViewWorkspace checks read access: any member may read, also while the
+// workspace is pending deletion, but a personal access token must still
+// name the workspace.
+func (s *permissionService) CanViewWorkspace(ctx context.Context, userID uuid.UUID, workspaceID uuid.UUID) (bool, error) {
+	member, _, err := s.activeMember(ctx, userID, workspaceID)
+	if member == nil || err != nil {
+		return false, err
+	}
+	if scope := TokenScopeFromContext(ctx); scope != nil && !scope.AllowsWorkspace(workspaceID) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// activeMember returns the membership of userID and the workspace once the
+// workspace policy is satisfied, or a nil member if they are not a member.
+func (s *permissionService) activeMember(ctx context.Context, userID, workspaceID uuid.UUID) (*models.WorkspaceMember, *models.Workspace, error) {
 	member, err := s.memberRepo.FindByUserAndWorkspace(ctx, userID, workspaceID)
 	if err != nil {
-		return false, nil
+		return nil, nil, nil
 	}
 
-	role := rbac.Role(member.Role)
-	return rbac.HasPermission(role, perm), nil
+	workspace, err := s.checkWorkspacePolicy(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return member, workspace, nil
+}
+
+// CanJoinWorkspace checks whether userID may become a member of the
+// workspace, e.g. when being added or accepting an invitation.
+func (s *permissionService) CanJoinWorkspace(ctx context.Context, userID uuid.UUID, workspaceID uuid.UUID) error {
+	workspace, err := s.workspaceRepo.FindByID(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if workspace.DeletionScheduledAt != nil {
+		return ErrWorkspacePendingDeletion
+	}
+	return s.checkVerifiedEmail(ctx, userID, workspace)
+}
+
+// checkWorkspacePolicy enforces workspace-level requirements that apply to
+// every member regardless of role.
+func (s *permissionService) checkWorkspacePolicy(ctx context.Context, userID, workspaceID uuid.UUID) (*models.Workspace, error) {
+	workspace, err := s.workspaceRepo.FindByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if workspace.RequireTwoFactor {
+		enabled, err := s.twoFactor.IsEnabled(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !enabled {
+			return nil, ErrWorkspaceRequiresTwoFactor
+		}
+	}
+	if err := s.checkVerifiedEmail(ctx, userID, workspace); err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+func (s *permissionService) checkVerifiedEmail(ctx context.Context, userID uuid.UUID, workspace *models.Workspace) error {
+	if !workspace.RequireVerifiedEmail {
+		return nil
+	}
+	verified, err := s.users.IsEmailVerified(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return ErrWorkspaceRequiresVerifiedEmail
+	}
+	return nil
 }
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/rbac"
+
+	"github.com/google/uuid"
+)
+
+func TestPermissionServiceTokenScope(t *testing.T) {
+	userID := uuid.New()
+	own := uuid.New()
+	other := uuid.New()
+	members := newFakeMembers()
+	members.add(userID, own, string(rbac.RoleAdmin))
+	workspaces := &fakeWorkspaces{byID: map[uuid.UUID]*models.Workspace{
+		own:   {ID: own},
+		other: {ID: other},
+	}}
+	svc := NewPermissionService(members, workspaces, newFakeTwoFactor(), newFakeUsers())
+
+	readOnly := &TokenScope{WorkspaceIDs: []uuid.UUID{own}}
+	canUpdate := &TokenScope{WorkspaceIDs: []uuid.UUID{own}, Permissions: []rbac.Permission{rbac.PermissionUpdateTask}}
+	elsewhere := &TokenScope{WorkspaceIDs: []uuid.UUID{other}, Permissions: []rbac.Permission{rbac.PermissionUpdateTask}}
+
+	tests := []struct {
+		name      string
+		workspace uuid.UUID
+		scope     *TokenScope
+		wantView  bool
+		wantWrite bool
+	}{
+		{name: "session in own workspace", workspace: own, wantView: true, wantWrite: true},
+		{name: "session outside membership", workspace: other},
+		{name: "token without permissions can only read", workspace: own, scope: readOnly, wantView: true},
+		{name: "token with permission", workspace: own, scope: canUpdate, wantView: true, wantWrite: true},
+		{name: "token scoped to another workspace", workspace: own, scope: elsewhere},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.scope != nil {
+				ctx = WithTokenScope(ctx, tt.scope)
+			}
+			view, err := svc.CanViewWorkspace(ctx, userID, tt.workspace)
+			if err != nil || view != tt.wantView {
+				t.Errorf("CanViewWorkspace = %v, %v; want %v", view, err, tt.wantView)
+			}
+			write, err := svc.HasPermission(ctx, userID, tt.workspace, rbac.PermissionUpdateTask)
+			if err != nil || write != tt.wantWrite {
+				t.Errorf("HasPermission = %v, %v; want %v", write, err, tt.wantWrite)
+			}
+		})
+	}
+}
+
+func TestPermissionServicePendingDeletionIsReadOnly(t *testing.T) {
+	userID := uuid.New()
+	workspaceID := uuid.New()
+	members := newFakeMembers()
+	members.add(userID, workspaceID, string(rbac.RoleOwner))
+	scheduled := time.Now().Add(24 * time.Hour)
+	workspaces := &fakeWorkspaces{byID: map[uuid.UUID]*models.Workspace{
+		workspaceID: {ID: workspaceID, DeletionScheduledAt: &scheduled},
+	}}
+	svc := NewPermissionService(members, workspaces, newFakeTwoFactor(), newFakeUsers())
+	ctx := context.Background()
+
+	if view, err := svc.CanViewWorkspace(ctx, userID, workspaceID); err != nil || !view {
+		t.Errorf("CanViewWorkspace = %v, %v; want true", view, err)
+	}
+	if allowed, err := svc.HasPermission(ctx, userID, workspaceID, rbac.PermissionViewSecurityLog); err != nil || !allowed {
+		t.Errorf("HasPermission(view security log) = %v, %v; want true", allowed, err)
+	}
+	for _, perm := range []rbac.Permission{rbac.PermissionCreateTask, rbac.PermissionUpdateProject, rbac.PermissionUpdateWorkspace} {
+		if allowed, err := svc.HasPermission(ctx, userID, workspaceID, perm); allowed || !errors.Is(err, ErrWorkspacePendingDeletion) {
+			t.Errorf("HasPermission(%s) = %v, %v; want %v", perm, allowed, err, ErrWorkspacePendingDeletion)
+		}
+	}
+}
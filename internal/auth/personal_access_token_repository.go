@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"kerjakuy/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PersonalAccessTokenRepository interface {
+	Create(ctx context.Context, token *models.PersonalAccessToken) error
+	FindByTokenHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at, staleBefore time.Time) error
+}
+
+type personalAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPersonalAccessTokenRepository(db *gorm.DB) PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{db: db}
+}
+
+func (r *personalAccessTokenRepository) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *personalAccessTokenRepository) FindByTokenHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *personalAccessTokenRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at desc").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *personalAccessTokenRepository) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.PersonalAccessToken{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// TouchLastUsed only writes when the stored value is older than
+// staleBefore, so a busy token does not cause a write per request.
+func (r *personalAccessTokenRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, at, staleBefore time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, staleBefore).
+		Update("last_used_at", at).Error
+}
@@ -13,7 +13,15 @@ import (
 type UserSessionRepository interface {
 	Create(ctx context.Context, session *models.UserSession) error
 	FindByTokenHash(ctx context.Context, hash string) (*models.UserSession, error)
+	FindActiveByFamily(ctx context.Context, userID, familyID uuid.UUID, now time.Time) (*models.UserSession, error)
+	ListActiveByUser(ctx context.Context, userID uuid.UUID, now time.Time) ([]models.UserSession, error)
+	DeviceHistory(ctx context.Context, userID uuid.UUID, userAgent, ip string, since time.Time) (total, matching int64, err error)
+	UpdateName(ctx context.Context, userID, familyID uuid.UUID, name *string) error
+	MarkRotated(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	RevokeFamily(ctx context.Context, familyID uuid.UUID, at time.Time) error
+	RevokeAllExcept(ctx context.Context, userID, keepFamilyID uuid.UUID, at time.Time) error
 	DeleteByID(ctx context.Context, id uuid.UUID) error
+	DeleteFamily(ctx context.Context, familyID uuid.UUID) error
 	DeleteExpired(ctx context.Context, now time.Time) error
 }
 
@@ -37,10 +45,97 @@ func (r *userSessionRepository) FindByTokenHash(ctx context.Context, hash string
 	return &session, nil
 }
 
+// FindActiveByFamily returns the live (not rotated, revoked or expired) row
+// of a session family owned by userID.
+func (r *userSessionRepository) FindActiveByFamily(ctx context.Context, userID, familyID uuid.UUID, now time.Time) (*models.UserSession, error) {
+	var session models.UserSession
+	if err := r.activeScope(ctx, now).
+		Where("user_id = ? AND family_id = ?", userID, familyID).
+		First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *userSessionRepository) ListActiveByUser(ctx context.Context, userID uuid.UUID, now time.Time) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	if err := r.activeScope(ctx, now).
+		Where("user_id = ?", userID).
+		Order("created_at desc").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// DeviceHistory counts the user's sessions created since the given time and
+// how many of them came from exactly this user agent and IP.
+func (r *userSessionRepository) DeviceHistory(ctx context.Context, userID uuid.UUID, userAgent, ip string, since time.Time) (int64, int64, error) {
+	var ua, addr *string
+	if userAgent != "" {
+		ua = &userAgent
+	}
+	if ip != "" {
+		addr = &ip
+	}
+	var row struct {
+		Total    int64
+		Matching int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.UserSession{}).
+		Select("COUNT(*) AS total, COUNT(*) FILTER (WHERE user_agent IS NOT DISTINCT FROM ? AND ip_address IS NOT DISTINCT FROM ?) AS matching", ua, addr).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Scan(&row).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	return row.Total, row.Matching, nil
+}
+
+func (r *userSessionRepository) UpdateName(ctx context.Context, userID, familyID uuid.UUID, name *string) error {
+	return r.db.WithContext(ctx).Model(&models.UserSession{}).
+		Where("user_id = ? AND family_id = ?", userID, familyID).
+		Update("name", name).Error
+}
+
+// MarkRotated flags a live session as rotated. It reports false when the
+// session was already rotated or revoked, which means the refresh token has
+// been presented twice.
+func (r *userSessionRepository) MarkRotated(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.UserSession{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		Update("rotated_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *userSessionRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.UserSession{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+func (r *userSessionRepository) RevokeAllExcept(ctx context.Context, userID, keepFamilyID uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.UserSession{}).
+		Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", userID, keepFamilyID).
+		Update("revoked_at", at).Error
+}
+
 func (r *userSessionRepository) DeleteByID(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&models.UserSession{}, "id = ?", id).Error
 }
 
+func (r *userSessionRepository) DeleteFamily(ctx context.Context, familyID uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.UserSession{}, "family_id = ?", familyID).Error
+}
+
 func (r *userSessionRepository) DeleteExpired(ctx context.Context, now time.Time) error {
 	return r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.UserSession{}).Error
 }
+
+func (r *userSessionRepository) activeScope(ctx context.Context, now time.Time) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Where("rotated_at IS NULL AND revoked_at IS NULL AND expires_at > ?", now)
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	revokedKindToken   = "token"
+	revokedKindSession = "session"
+
+	// revocationSyncOverlap re-reads a little history on every sync so rows
+	// committed out of order by other instances are not missed.
+	revocationSyncOverlap = 5 * time.Second
+)
+
+// RevocationStore answers "is this access token revoked?" from memory so
+// RequireAuth does not hit the database. Revocations are written through to
+// Postgres, and Start keeps the cache in step with other instances, which
+// therefore see a revocation within one sync interval.
+type RevocationStore interface {
+	RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	ConsumeToken(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error)
+	RevokeSession(ctx context.Context, sessionID uuid.UUID, expiresAt time.Time) error
+	IsRevoked(claims *Claims) bool
+	Start(ctx context.Context, interval time.Duration)
+}
+
+type revocationStore struct {
+	repo   RevokedTokenRepository
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	entries  map[string]time.Time
+	lastSync time.Time
+}
+
+func NewRevocationStore(repo RevokedTokenRepository, logger *slog.Logger) RevocationStore {
+	return &revocationStore{
+		repo:    repo,
+		logger:  logger,
+		entries: map[string]time.Time{},
+	}
+}
+
+func (s *revocationStore) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	return s.revoke(ctx, revokedKindToken, tokenID, expiresAt)
+}
+
+// ConsumeToken revokes a single-use token and reports whether this call was
+// the first to do so. The database decides, so two instances racing on the
+// same token cannot both win.
+func (s *revocationStore) ConsumeToken(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error) {
+	inserted, err := s.repo.InsertOnce(ctx, revokedKindToken, tokenID, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	s.put(revokedKindToken+":"+tokenID, expiresAt)
+	s.mu.Unlock()
+	return inserted, nil
+}
+
+func (s *revocationStore) RevokeSession(ctx context.Context, sessionID uuid.UUID, expiresAt time.Time) error {
+	return s.revoke(ctx, revokedKindSession, sessionID.String(), expiresAt)
+}
+
+func (s *revocationStore) revoke(ctx context.Context, kind, value string, expiresAt time.Time) error {
+	if err := s.repo.Upsert(ctx, kind, value, expiresAt); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.put(kind+":"+value, expiresAt)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *revocationStore) IsRevoked(claims *Claims) bool {
+	now := time.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if claims.TokenID != "" && s.active(revokedKindToken+":"+claims.TokenID, now) {
+		return true
+	}
+	if claims.SessionID != uuid.Nil && s.active(revokedKindSession+":"+claims.SessionID.String(), now) {
+		return true
+	}
+	return false
+}
+
+// Start loads the current revocations and then, every interval, pulls rows
+// written by other instances and purges expired rows from memory and from
+// the table.
+func (s *revocationStore) Start(ctx context.Context, interval time.Duration) {
+	if err := s.sync(ctx); err != nil {
+		s.logger.Error("failed to load token revocations", "error", err)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.sync(ctx); err != nil {
+					s.logger.Error("failed to sync token revocations", "error", err)
+				}
+				s.cleanup(ctx)
+			}
+		}
+	}()
+}
+
+func (s *revocationStore) sync(ctx context.Context) error {
+	now := time.Now()
+	s.mu.RLock()
+	since := s.lastSync
+	s.mu.RUnlock()
+	if !since.IsZero() {
+		since = since.Add(-revocationSyncOverlap)
+	}
+
+	rows, err := s.repo.ListUpdatedSince(ctx, since, now)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	for _, row := range rows {
+		s.put(row.Kind+":"+row.Value, row.ExpiresAt)
+	}
+	s.lastSync = now
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *revocationStore) cleanup(ctx context.Context) {
+	now := time.Now()
+	s.mu.Lock()
+	for key, expiresAt := range s.entries {
+		if !expiresAt.After(now) {
+			delete(s.entries, key)
+		}
+	}
+	s.mu.Unlock()
+	if err := s.repo.DeleteExpired(ctx, now); err != nil {
+		s.logger.Error("failed to delete expired token revocations", "error", err)
+	}
+}
+
+// put must be called with mu held for writing.
+func (s *revocationStore) put(key string, expiresAt time.Time) {
+	if current, ok := s.entries[key]; ok && current.After(expiresAt) {
+		return
+	}
+	s.entries[key] = expiresAt
+}
+
+func (s *revocationStore) active(key string, now time.Time) bool {
+	expiresAt, ok := s.entries[key]
+	return ok && expiresAt.After(now)
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"kerjakuy/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevokedTokenRepository interface {
+	Upsert(ctx context.Context, kind, value string, expiresAt time.Time) error
+	InsertOnce(ctx context.Context, kind, value string, expiresAt time.Time) (bool, error)
+	ListUpdatedSince(ctx context.Context, since, now time.Time) ([]models.RevokedToken, error)
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+type revokedTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRevokedTokenRepository(db *gorm.DB) RevokedTokenRepository {
+	return &revokedTokenRepository{db: db}
+}
+
+// Upsert keeps the later of the existing and the new expiry.
+func (r *revokedTokenRepository) Upsert(ctx context.Context, kind, value string, expiresAt time.Time) error {
+	row := &models.RevokedToken{Kind: kind, Value: value, ExpiresAt: expiresAt}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "kind"}, {Name: "value"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"expires_at": gorm.Expr("GREATEST(revoked_tokens.expires_at, ?)", expiresAt),
+			"updated_at": time.Now(),
+		}),
+	}).Create(row).Error
+}
+
+// InsertOnce reports false when the row already existed.
+func (r *revokedTokenRepository) InsertOnce(ctx context.Context, kind, value string, expiresAt time.Time) (bool, error) {
+	row := &models.RevokedToken{Kind: kind, Value: value, ExpiresAt: expiresAt}
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(row)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *revokedTokenRepository) ListUpdatedSince(ctx context.Context, since, now time.Time) ([]models.RevokedToken, error) {
+	var rows []models.RevokedToken
+	if err := r.db.WithContext(ctx).
+		Where("updated_at >= ? AND expires_at > ?", since, now).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *revokedTokenRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.RevokedToken{}).Error
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"kerjakuy/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SecurityEventRepository interface {
+	Create(ctx context.Context, event *models.SecurityEvent) error
+	ListByUsers(ctx context.Context, since map[uuid.UUID]time.Time, before *time.Time, limit int) ([]models.SecurityEvent, error)
+}
+
+type securityEventRepository struct {
+	db *gorm.DB
+}
+
+func NewSecurityEventRepository(db *gorm.DB) SecurityEventRepository {
+	return &securityEventRepository{db: db}
+}
+
+func (r *securityEventRepository) Create(ctx context.Context, event *models.SecurityEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// ListByUsers returns the newest events of the users in since, each from
+// their own start time on. before pages back through older events.
+func (r *securityEventRepository) ListByUsers(ctx context.Context, since map[uuid.UUID]time.Time, before *time.Time, limit int) ([]models.SecurityEvent, error) {
+	var events []models.SecurityEvent
+	if len(since) == 0 {
+		return events, nil
+	}
+	users := r.db.Session(&gorm.Session{NewDB: true})
+	for userID, from := range since {
+		users = users.Or("user_id = ? AND created_at >= ?", userID, from)
+	}
+	query := r.db.WithContext(ctx).Where(users)
+	if before != nil {
+		query = query.Where("created_at < ?", *before)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Order("created_at DESC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
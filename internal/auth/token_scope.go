@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+
+	"kerjakuy/internal/pkg/rbac"
+
+	"github.com/google/uuid"
+)
+
+// TokenScope limits what a request authenticated with a personal access
+// token may do, on top of the user's own role.
+type TokenScope struct {
+	TokenID      uuid.UUID
+	WorkspaceIDs []uuid.UUID
+	Permissions  []rbac.Permission
+}
+
+func (s *TokenScope) Allows(workspaceID uuid.UUID, perm rbac.Permission) bool {
+	if !s.AllowsWorkspace(workspaceID) {
+		return false
+	}
+	for _, p := range s.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsWorkspace reports whether the token may see workspaceID at all,
+// which is all a read needs.
+func (s *TokenScope) AllowsWorkspace(workspaceID uuid.UUID) bool {
+	for _, id := range s.WorkspaceIDs {
+		if id == workspaceID {
+			return true
+		}
+	}
+	return false
+}
+
+type tokenScopeContextKey struct{}
+
+// WithTokenScope attaches scope to ctx so PermissionService can see it
+// without every service signature carrying it.
+func WithTokenScope(ctx context.Context, scope *TokenScope) context.Context {
+	return context.WithValue(ctx, tokenScopeContextKey{}, scope)
+}
+
+func TokenScopeFromContext(ctx context.Context) *TokenScope {
+	scope, _ := ctx.Value(tokenScopeContextKey{}).(*TokenScope)
+	return scope
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"kerjakuy/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TwoFactorRepository interface {
+	FindByUser(ctx context.Context, userID uuid.UUID) (*models.UserTwoFactor, error)
+	IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error)
+	Create(ctx context.Context, tf *models.UserTwoFactor) error
+	Confirm(ctx context.Context, id uuid.UUID, step int64, at time.Time) error
+	MarkUsedStep(ctx context.Context, id uuid.UUID, step int64) (bool, error)
+	DeleteByUser(ctx context.Context, userID uuid.UUID) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []models.UserRecoveryCode) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string, at time.Time) (bool, error)
+}
+
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+func (r *twoFactorRepository) FindByUser(ctx context.Context, userID uuid.UUID) (*models.UserTwoFactor, error) {
+	var tf models.UserTwoFactor
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&tf).Error; err != nil {
+		return nil, err
+	}
+	return &tf, nil
+}
+
+func (r *twoFactorRepository) IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.UserTwoFactor{}).
+		Where("user_id = ? AND confirmed_at IS NOT NULL", userID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *twoFactorRepository) Create(ctx context.Context, tf *models.UserTwoFactor) error {
+	return r.db.WithContext(ctx).Create(tf).Error
+}
+
+func (r *twoFactorRepository) Confirm(ctx context.Context, id uuid.UUID, step int64, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.UserTwoFactor{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"confirmed_at":   at,
+		"last_used_step": step,
+	}).Error
+}
+
+// MarkUsedStep records the TOTP step that was just accepted. It reports false
+// when the same or a later step was already used, i.e. the code is replayed.
+func (r *twoFactorRepository) MarkUsedStep(ctx context.Context, id uuid.UUID, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.UserTwoFactor{}).
+		Where("id = ? AND last_used_step < ?", id, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *twoFactorRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserTwoFactor{}).Error
+	})
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []models.UserRecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,64 @@
+package auth
+
+import "strings"
+
+// DeviceInfo is a coarse, best-effort reading of a User-Agent header. It is
+// meant for display in the session list, not for any security decision.
+type DeviceInfo struct {
+	Browser    string `json:"browser"`
+	OS         string `json:"os"`
+	DeviceType string `json:"device_type"`
+}
+
+func parseUserAgent(ua string) DeviceInfo {
+	info := DeviceInfo{Browser: "Unknown", OS: "Unknown", DeviceType: "desktop"}
+	if ua == "" {
+		info.DeviceType = "unknown"
+		return info
+	}
+	lower := strings.ToLower(ua)
+
+	switch {
+	case strings.Contains(lower, "bot"), strings.Contains(lower, "spider"), strings.Contains(lower, "crawl"):
+		info.DeviceType = "bot"
+	case strings.Contains(lower, "ipad"), strings.Contains(lower, "tablet"):
+		info.DeviceType = "tablet"
+	case strings.Contains(lower, "mobi"), strings.Contains(lower, "iphone"), strings.Contains(lower, "android"):
+		info.DeviceType = "mobile"
+	}
+
+	switch {
+	case strings.Contains(lower, "windows"):
+		info.OS = "Windows"
+	case strings.Contains(lower, "iphone"), strings.Contains(lower, "ipad"):
+		info.OS = "iOS"
+	case strings.Contains(lower, "mac os x"), strings.Contains(lower, "macintosh"):
+		info.OS = "macOS"
+	case strings.Contains(lower, "android"):
+		info.OS = "Android"
+	case strings.Contains(lower, "cros"):
+		info.OS = "ChromeOS"
+	case strings.Contains(lower, "linux"):
+		info.OS = "Linux"
+	}
+
+	// Order matters: most browsers also advertise "Chrome" and "Safari".
+	switch {
+	case strings.Contains(lower, "edg/"), strings.Contains(lower, "edge/"):
+		info.Browser = "Edge"
+	case strings.Contains(lower, "opr/"), strings.Contains(lower, "opera"):
+		info.Browser = "Opera"
+	case strings.Contains(lower, "firefox/"), strings.Contains(lower, "fxios/"):
+		info.Browser = "Firefox"
+	case strings.Contains(lower, "chrome/"), strings.Contains(lower, "crios/"):
+		info.Browser = "Chrome"
+	case strings.Contains(lower, "safari/"):
+		info.Browser = "Safari"
+	case strings.Contains(lower, "curl/"):
+		info.Browser = "curl"
+	case strings.Contains(lower, "postman"):
+		info.Browser = "Postman"
+	}
+
+	return info
+}
@@ -11,8 +11,8 @@ func CORSMiddleware() gin.HandlerFunc {
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true // For development, restrict in production
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
-	config.ExposeHeaders = []string{"Content-Length"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-CSRF-Token"}
+	config.ExposeHeaders = []string{"Content-Length", "X-Impersonated-By"}
 	config.AllowCredentials = true
 	config.MaxAge = 12 * time.Hour
 
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AccountDeletionTransfer is the new owner a user picked for one of their
+// workspaces when scheduling account deletion. Ownership only moves when
+// the account is purged, so cancelling the deletion changes nothing.
+type AccountDeletionTransfer struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_account_deletion_transfer" json:"user_id"`
+	WorkspaceID uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_account_deletion_transfer" json:"workspace_id"`
+	NewOwnerID  uuid.UUID `gorm:"type:uuid;column:new_owner_id" json:"new_owner_id"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (t *AccountDeletionTransfer) BeforeCreate(tx *gorm.DB) error {
+	t.ID = uuid.New()
+	return nil
+}
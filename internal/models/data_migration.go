@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// DataMigration records a one-off data change made by cmd/migrate, so the
+// change is applied once and never repeated on later runs.
+type DataMigration struct {
+	Name      string    `gorm:"type:varchar(100);primaryKey" json:"name"`
+	AppliedAt time.Time `gorm:"autoCreateTime" json:"applied_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EmailChangeRequest tracks a pending or completed change of login email.
+// The confirm token goes to NewEmail; the revert token goes to OldEmail and
+// stays usable until RevertExpiresAt, even after the change is confirmed.
+type EmailChangeRequest struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID           uuid.UUID  `gorm:"type:uuid;index" json:"user_id"`
+	OldEmail         string     `gorm:"type:varchar(150);column:old_email" json:"old_email"`
+	NewEmail         string     `gorm:"type:varchar(150);column:new_email" json:"new_email"`
+	ConfirmTokenHash string     `gorm:"type:text;uniqueIndex;column:confirm_token_hash" json:"-"`
+	RevertTokenHash  string     `gorm:"type:text;uniqueIndex;column:revert_token_hash" json:"-"`
+	RequestedIP      *string    `gorm:"type:inet;column:requested_ip" json:"requested_ip,omitempty"`
+	ExpiresAt        time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	RevertExpiresAt  time.Time  `gorm:"column:revert_expires_at;index" json:"revert_expires_at"`
+	ConfirmedAt      *time.Time `gorm:"column:confirmed_at" json:"confirmed_at,omitempty"`
+	RevertedAt       *time.Time `gorm:"column:reverted_at" json:"reverted_at,omitempty"`
+	CreatedAt        time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (r *EmailChangeRequest) BeforeCreate(tx *gorm.DB) error {
+	r.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LoginThrottle counts recent failed logins for one email address or one
+// client IP (Scope) and how long further attempts are refused.
+type LoginThrottle struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Scope        string     `gorm:"type:varchar(10);uniqueIndex:idx_login_throttle_scope_key" json:"scope"`
+	Key          string     `gorm:"type:varchar(255);column:throttle_key;uniqueIndex:idx_login_throttle_scope_key" json:"key"`
+	Failures     int        `gorm:"not null;default:0" json:"failures"`
+	LastFailedAt time.Time  `gorm:"column:last_failed_at;index" json:"last_failed_at"`
+	BlockedUntil *time.Time `gorm:"column:blocked_until" json:"blocked_until,omitempty"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (lt *LoginThrottle) BeforeCreate(tx *gorm.DB) error {
+	lt.ID = uuid.New()
+	return nil
+}
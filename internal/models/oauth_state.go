@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OAuthState struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	StateHash    string    `gorm:"type:text;uniqueIndex;column:state_hash" json:"-"`
+	Provider     string    `gorm:"type:varchar(50)" json:"provider"`
+	CodeVerifier string    `gorm:"type:text;column:code_verifier" json:"-"`
+	RedirectURI  string    `gorm:"type:text;column:redirect_uri" json:"redirect_uri"`
+	ExpiresAt    time.Time `gorm:"column:expires_at;index" json:"expires_at"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (st *OAuthState) BeforeCreate(tx *gorm.DB) error {
+	st.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PasswordResetToken struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;index" json:"user_id"`
+	TokenHash   string     `gorm:"type:text;uniqueIndex;column:token_hash" json:"-"`
+	RequestedIP *string    `gorm:"type:inet;column:requested_ip" json:"requested_ip,omitempty"`
+	ExpiresAt   time.Time  `gorm:"column:expires_at;index" json:"expires_at"`
+	UsedAt      *time.Time `gorm:"column:used_at" json:"used_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (t *PasswordResetToken) BeforeCreate(tx *gorm.DB) error {
+	t.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// PersonalAccessToken is a long-lived API token restricted to a set of
+// workspaces and permissions. Only the SHA-256 of the token is stored.
+type PersonalAccessToken struct {
+	ID           uuid.UUID                      `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID       uuid.UUID                      `gorm:"type:uuid;index" json:"user_id"`
+	Name         string                         `gorm:"type:varchar(100)" json:"name"`
+	TokenPrefix  string                         `gorm:"type:varchar(20);column:token_prefix" json:"token_prefix"`
+	TokenHash    string                         `gorm:"type:text;uniqueIndex;column:token_hash" json:"-"`
+	WorkspaceIDs datatypes.JSONSlice[uuid.UUID] `gorm:"type:jsonb;column:workspace_ids" json:"workspace_ids"`
+	Permissions  datatypes.JSONSlice[string]    `gorm:"type:jsonb" json:"permissions"`
+	ExpiresAt    *time.Time                     `gorm:"column:expires_at" json:"expires_at,omitempty"`
+	LastUsedAt   *time.Time                     `gorm:"column:last_used_at" json:"last_used_at,omitempty"`
+	CreatedAt    time.Time                      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (t *PersonalAccessToken) BeforeCreate(tx *gorm.DB) error {
+	t.ID = uuid.New()
+	return nil
+}
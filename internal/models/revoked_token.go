@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RevokedToken blocks access tokens before their natural expiry, either a
+// single token by jti (Kind "token") or every token of a session family by
+// sid (Kind "session"). Rows are useless once ExpiresAt has passed.
+type RevokedToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	Kind      string    `gorm:"type:varchar(10);uniqueIndex:idx_revoked_token_kind_value" json:"kind"`
+	Value     string    `gorm:"type:varchar(64);uniqueIndex:idx_revoked_token_kind_value" json:"value"`
+	ExpiresAt time.Time `gorm:"column:expires_at;index" json:"expires_at"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime;index" json:"updated_at"`
+}
+
+func (rt *RevokedToken) BeforeCreate(tx *gorm.DB) error {
+	rt.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type SecurityEvent struct {
+	ID        uuid.UUID         `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    *uuid.UUID        `gorm:"type:uuid;index;index:idx_security_event_user_created,priority:1" json:"user_id,omitempty"`
+	Type      string            `gorm:"type:varchar(50);index" json:"type"`
+	Outcome   string            `gorm:"type:varchar(20)" json:"outcome"`
+	IPAddress *string           `gorm:"type:inet;column:ip_address" json:"ip_address,omitempty"`
+	UserAgent *string           `gorm:"type:text;column:user_agent" json:"user_agent,omitempty"`
+	Metadata  datatypes.JSONMap `gorm:"type:jsonb" json:"metadata,omitempty"`
+	CreatedAt time.Time         `gorm:"autoCreateTime;index;index:idx_security_event_user_created,priority:2" json:"created_at"`
+}
+
+func (se *SecurityEvent) BeforeCreate(tx *gorm.DB) error {
+	se.ID = uuid.New()
+	return nil
+}
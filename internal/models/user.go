@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -13,8 +14,28 @@ type User struct {
 	Email        string    `gorm:"type:varchar(150);uniqueIndex" json:"email"`
 	PasswordHash string    `gorm:"type:text;column:password_hash" json:"-"`
 	AvatarURL    *string   `gorm:"type:text;column:avatar_url" json:"avatar_url,omitempty"`
-	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	// AvatarKey names the uploaded avatar, "<version>.<ext>". It is nil when
+	// AvatarURL is hosted elsewhere or unset.
+	AvatarKey               *string    `gorm:"type:varchar(64);column:avatar_key" json:"-"`
+	EmailVerifiedAt         *time.Time `gorm:"column:email_verified_at" json:"email_verified_at,omitempty"`
+	EmailVerificationSentAt *time.Time `gorm:"column:email_verification_sent_at" json:"-"`
+	// Timezone is an IANA name used to render dates and schedule reminders
+	// for the user.
+	Timezone string `gorm:"type:varchar(64);default:'Asia/Jakarta'" json:"timezone"`
+	Locale   string `gorm:"type:varchar(35);default:'id-ID'" json:"locale"`
+	// WorkingHoursStart and WorkingHoursEnd are "HH:MM" in Timezone.
+	WorkingHoursStart *string           `gorm:"type:varchar(5);column:working_hours_start" json:"working_hours_start,omitempty"`
+	WorkingHoursEnd   *string           `gorm:"type:varchar(5);column:working_hours_end" json:"working_hours_end,omitempty"`
+	Preferences       datatypes.JSONMap `gorm:"type:jsonb" json:"preferences,omitempty"`
+	// IsPlatformAdmin marks KerjaKuy support staff. It is only set directly
+	// in the database.
+	IsPlatformAdmin bool `gorm:"column:is_platform_admin;default:false" json:"-"`
+	// DeletionScheduledAt is when the account will be anonymized; set while
+	// a self-service deletion is in its grace period.
+	DeletionScheduledAt *time.Time `gorm:"column:deletion_scheduled_at;index" json:"deletion_scheduled_at,omitempty"`
+	AnonymizedAt        *time.Time `gorm:"column:anonymized_at" json:"-"`
+	CreatedAt           time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt           time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UserIdentity struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;index" json:"user_id"`
+	Provider  string    `gorm:"type:varchar(50);uniqueIndex:idx_identity_provider_subject" json:"provider"`
+	Subject   string    `gorm:"type:varchar(255);uniqueIndex:idx_identity_provider_subject" json:"subject"`
+	Email     string    `gorm:"type:varchar(150)" json:"email"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (ui *UserIdentity) BeforeCreate(tx *gorm.DB) error {
+	ui.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UserRecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;index" json:"user_id"`
+	CodeHash  string     `gorm:"type:text;column:code_hash" json:"-"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (rc *UserRecoveryCode) BeforeCreate(tx *gorm.DB) error {
+	rc.ID = uuid.New()
+	return nil
+}
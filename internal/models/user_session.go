@@ -7,17 +7,34 @@ import (
 	"gorm.io/gorm"
 )
 
+// UserSession is one refresh token. Rotation creates a new row in the same
+// family, so SignedInAt (inherited) marks the original login while CreatedAt
+// of the live row is the last time the session was refreshed.
 type UserSession struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	UserID    uuid.UUID `gorm:"type:uuid;index" json:"user_id"`
-	TokenHash string    `gorm:"type:text;column:token_hash" json:"-"`
-	UserAgent *string   `gorm:"type:text;column:user_agent" json:"user_agent,omitempty"`
-	IPAddress *string   `gorm:"type:inet;column:ip_address" json:"ip_address,omitempty"`
-	ExpiresAt time.Time `gorm:"column:expires_at" json:"expires_at"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;index" json:"user_id"`
+	FamilyID   uuid.UUID  `gorm:"type:uuid;index;column:family_id" json:"family_id"`
+	ParentID   *uuid.UUID `gorm:"type:uuid;column:parent_id" json:"parent_id,omitempty"`
+	TokenHash  string     `gorm:"type:text;column:token_hash" json:"-"`
+	Name       *string    `gorm:"type:varchar(100)" json:"name,omitempty"`
+	UserAgent  *string    `gorm:"type:text;column:user_agent" json:"user_agent,omitempty"`
+	IPAddress  *string    `gorm:"type:inet;column:ip_address" json:"ip_address,omitempty"`
+	ExpiresAt  time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	RotatedAt  *time.Time `gorm:"column:rotated_at" json:"rotated_at,omitempty"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at" json:"revoked_at,omitempty"`
+	SignedInAt time.Time  `gorm:"column:signed_in_at" json:"signed_in_at"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
 }
 
 func (us *UserSession) BeforeCreate(tx *gorm.DB) error {
 	us.ID = uuid.New()
+	// The first session of a login starts its own family; rotated sessions
+	// inherit the family of their parent.
+	if us.FamilyID == uuid.Nil {
+		us.FamilyID = us.ID
+	}
+	if us.SignedInAt.IsZero() {
+		us.SignedInAt = time.Now()
+	}
 	return nil
 }
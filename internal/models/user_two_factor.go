@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UserTwoFactor struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID       uuid.UUID  `gorm:"type:uuid;uniqueIndex" json:"user_id"`
+	Secret       string     `gorm:"type:text" json:"-"`
+	ConfirmedAt  *time.Time `gorm:"column:confirmed_at" json:"confirmed_at,omitempty"`
+	LastUsedStep int64      `gorm:"column:last_used_step;default:0" json:"-"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (tf *UserTwoFactor) BeforeCreate(tx *gorm.DB) error {
+	tf.ID = uuid.New()
+	return nil
+}
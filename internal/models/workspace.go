@@ -8,13 +8,25 @@ import (
 )
 
 type Workspace struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	Name      string    `gorm:"type:varchar(100)" json:"name"`
-	Slug      string    `gorm:"type:varchar(100);uniqueIndex" json:"slug"`
-	OwnerID   uuid.UUID `gorm:"type:uuid" json:"owner_id"`
-	Plan      string    `gorm:"type:varchar(50);default:free" json:"plan"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID                   uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	Name                 string    `gorm:"type:varchar(100)" json:"name"`
+	Slug                 string    `gorm:"type:varchar(100);uniqueIndex" json:"slug"`
+	OwnerID              uuid.UUID `gorm:"type:uuid" json:"owner_id"`
+	Plan                 string    `gorm:"type:varchar(50);default:free" json:"plan"`
+	RequireTwoFactor     bool      `gorm:"column:require_two_factor;default:false" json:"require_two_factor"`
+	RequireVerifiedEmail bool      `gorm:"column:require_verified_email;default:false" json:"require_verified_email"`
+	// PlanChangedAt is when a platform admin last set the plan. It is nil
+	// for plans picked by the workspace itself before plans were enforced.
+	PlanChangedAt *time.Time `gorm:"column:plan_changed_at" json:"plan_changed_at,omitempty"`
+	// PendingOwnerID is the admin the owner nominated to take over; the
+	// transfer happens once they confirm.
+	PendingOwnerID       *uuid.UUID `gorm:"type:uuid;column:pending_owner_id" json:"pending_owner_id,omitempty"`
+	OwnershipNominatedAt *time.Time `gorm:"column:ownership_nominated_at" json:"ownership_nominated_at,omitempty"`
+	// DeletionScheduledAt is set while the workspace waits to be purged;
+	// it is read-only until then and can still be restored.
+	DeletionScheduledAt *time.Time `gorm:"column:deletion_scheduled_at;index" json:"deletion_scheduled_at,omitempty"`
+	CreatedAt           time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt           time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 func (w *Workspace) BeforeCreate(tx *gorm.DB) error {
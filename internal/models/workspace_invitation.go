@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WorkspaceInvitation is an emailed invitation to join a workspace. Email
+// is stored lowercased; only the hash of the token in the link is kept.
+// At most one invitation per address and workspace is pending at a time.
+type WorkspaceInvitation struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	WorkspaceID uuid.UUID  `gorm:"type:uuid;index:idx_workspace_invitation_pending,unique,priority:2,where:status = 'pending'" json:"workspace_id"`
+	Email       string     `gorm:"type:varchar(150);index:idx_workspace_invitation_pending,unique,priority:1,where:status = 'pending'" json:"email"`
+	Role        string     `gorm:"type:varchar(20);default:member" json:"role"`
+	InvitedBy   uuid.UUID  `gorm:"type:uuid;column:invited_by" json:"invited_by"`
+	TokenHash   string     `gorm:"type:text;uniqueIndex;column:token_hash" json:"-"`
+	Status      string     `gorm:"type:varchar(20);default:pending" json:"status"`
+	ExpiresAt   time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	SentAt      time.Time  `gorm:"column:sent_at" json:"sent_at"`
+	RespondedBy *uuid.UUID `gorm:"type:uuid;column:responded_by" json:"responded_by,omitempty"`
+	RespondedAt *time.Time `gorm:"column:responded_at" json:"responded_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (i *WorkspaceInvitation) BeforeCreate(tx *gorm.DB) error {
+	i.ID = uuid.New()
+	return nil
+}
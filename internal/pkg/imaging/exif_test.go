@@ -0,0 +1,73 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// exifJPEG builds the start of a JPEG whose APP1 segment holds a single
+// IFD entry with the given orientation.
+func exifJPEG(byteOrder string, orientation uint16) []byte {
+	var order binary.AppendByteOrder = binary.LittleEndian
+	if byteOrder == "MM" {
+		order = binary.BigEndian
+	}
+	tiff := []byte(byteOrder)
+	tiff = order.AppendUint16(tiff, 42)
+	tiff = order.AppendUint32(tiff, 8)
+	tiff = order.AppendUint16(tiff, 1)
+	tiff = order.AppendUint16(tiff, exifOrientationTag)
+	tiff = order.AppendUint16(tiff, 3)
+	tiff = order.AppendUint32(tiff, 1)
+	tiff = order.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0)
+	tiff = order.AppendUint32(tiff, 0)
+	return app1JPEG(append([]byte("Exif\x00\x00"), tiff...))
+}
+
+func app1JPEG(payload []byte) []byte {
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(payload)+2))
+	data = append(data, payload...)
+	return append(data, 0xFF, 0xDA, 0x00, 0x02)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	truncated := exifJPEG("II", 6)
+	// Cut the TIFF block off in the middle of its only IFD entry, keeping
+	// the segment length consistent.
+	truncated = app1JPEG(truncated[6 : 6+6+8+2+6])
+
+	badOffset := exifJPEG("MM", 6)
+	binary.BigEndian.PutUint32(badOffset[6+6+4:], 1<<20)
+
+	overlong := exifJPEG("II", 6)
+	binary.BigEndian.PutUint16(overlong[4:], 0xFFF0)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{name: "little endian", data: exifJPEG("II", 6), want: 6},
+		{name: "big endian", data: exifJPEG("MM", 6), want: 6},
+		{name: "big endian rotate 180", data: exifJPEG("MM", 3), want: 3},
+		{name: "little endian mirrored", data: exifJPEG("II", 8), want: 8},
+		{name: "orientation zero", data: exifJPEG("II", 0), want: 1},
+		{name: "orientation above eight", data: exifJPEG("MM", 9), want: 1},
+		{name: "unknown byte order", data: exifJPEG("XX", 6), want: 1},
+		{name: "truncated IFD", data: truncated, want: 1},
+		{name: "IFD offset past the end", data: badOffset, want: 1},
+		{name: "segment longer than the file", data: overlong, want: 1},
+		{name: "APP1 without Exif header", data: app1JPEG([]byte("http://ns.adobe.com/xap/1.0/\x00")), want: 1},
+		{name: "no APP1 segment", data: []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02}, want: 1},
+		{name: "empty", data: nil, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package imaging
+
+import (
+	"crypto/sha256"
+	"image"
+	"image/color"
+)
+
+const identiconGrid = 5
+
+var identiconBackground = color.NRGBA{R: 0xF0, G: 0xF0, B: 0xF0, A: 0xFF}
+
+// Identicon draws a horizontally symmetric 5×5 pattern derived from seed,
+// so the same seed always yields the same picture.
+func Identicon(seed []byte, size int) *image.NRGBA {
+	sum := sha256.Sum256(seed)
+	fg := hslToRGB(float64(uint16(sum[0])<<8|uint16(sum[1]))/65536, 0.55, 0.5)
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = identiconBackground.R, identiconBackground.G, identiconBackground.B, identiconBackground.A
+	}
+
+	cell := size / (identiconGrid + 1)
+	if cell == 0 {
+		return img
+	}
+	margin := (size - cell*identiconGrid) / 2
+	half := (identiconGrid + 1) / 2
+	for row := 0; row < identiconGrid; row++ {
+		for col := 0; col < half; col++ {
+			bit := row*half + col
+			if sum[2+bit/8]>>(bit%8)&1 == 0 {
+				continue
+			}
+			fillCell(img, margin, cell, col, row, fg)
+			fillCell(img, margin, cell, identiconGrid-1-col, row, fg)
+		}
+	}
+	return img
+}
+
+func fillCell(img *image.NRGBA, margin, cell, col, row int, c color.NRGBA) {
+	x0, y0 := margin+col*cell, margin+row*cell
+	for y := y0; y < y0+cell; y++ {
+		for x := x0; x < x0+cell; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+}
+
+func hslToRGB(h, s, l float64) color.NRGBA {
+	var q float64
+	if l < 0.5 {
+		q = l * (1 + s)
+	} else {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+	return color.NRGBA{
+		R: uint8(hueToChannel(p, q, h+1.0/3) * 255),
+		G: uint8(hueToChannel(p, q, h) * 255),
+		B: uint8(hueToChannel(p, q, h-1.0/3) * 255),
+		A: 0xFF,
+	}
+}
+
+func hueToChannel(p, q, t float64) float64 {
+	switch {
+	case t < 0:
+		t++
+	case t > 1:
+		t--
+	}
+	switch {
+	case t < 1.0/6:
+		return p + (q-p)*6*t
+	case t < 1.0/2:
+		return q
+	case t < 2.0/3:
+		return p + (q-p)*(2.0/3-t)*6
+	default:
+		return p
+	}
+}
@@ -0,0 +1,26 @@
+package imaging
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIdenticon(t *testing.T) {
+	a := Identicon([]byte("ana"), 64)
+	if !bytes.Equal(a.Pix, Identicon([]byte("ana"), 64).Pix) {
+		t.Errorf("same seed produced different pictures")
+	}
+	if bytes.Equal(a.Pix, Identicon([]byte("budi"), 64).Pix) {
+		t.Errorf("different seeds produced the same picture")
+	}
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if a.NRGBAAt(x, y) != a.NRGBAAt(63-x, y) {
+				t.Fatalf("pixel (%d,%d) does not mirror (%d,%d)", x, y, 63-x, y)
+			}
+		}
+	}
+	if tiny := Identicon([]byte("ana"), 3); tiny.Bounds().Dx() != 3 || tiny.NRGBAAt(1, 1) != identiconBackground {
+		t.Errorf("an identicon too small for the grid should be plain background")
+	}
+}
@@ -0,0 +1,206 @@
+// Package imaging turns uploaded pictures into small square thumbnails.
+// Decoding and re-encoding the pixels drops every metadata block, EXIF
+// included; only the orientation tag is honoured first.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatGIF  = "gif"
+
+	maxDimension = 8192
+	maxPixels    = 40_000_000
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("format gambar tidak didukung, gunakan JPEG, PNG, atau GIF")
+	ErrInvalidImage      = errors.New("file gambar rusak atau tidak valid")
+	ErrImageTooLarge     = errors.New("dimensi gambar terlalu besar")
+)
+
+// DetectFormat identifies the image type from its leading bytes, ignoring
+// whatever the file name or Content-Type claims.
+func DetectFormat(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return FormatJPEG, nil
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG, nil
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return FormatGIF, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// Image is a decoded upload together with the EXIF orientation to apply.
+type Image struct {
+	img         image.Image
+	orientation int
+}
+
+// Decode validates data by magic bytes and dimensions before decoding it.
+// Animated GIFs keep only their first frame.
+func Decode(data []byte) (*Image, error) {
+	format, err := DetectFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		cfg    image.Config
+		decode func(io.Reader) (image.Image, error)
+	)
+	switch format {
+	case FormatJPEG:
+		cfg, err = jpeg.DecodeConfig(bytes.NewReader(data))
+		decode = jpeg.Decode
+	case FormatPNG:
+		cfg, err = png.DecodeConfig(bytes.NewReader(data))
+		decode = png.Decode
+	case FormatGIF:
+		cfg, err = gif.DecodeConfig(bytes.NewReader(data))
+		decode = gif.Decode
+	}
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width > maxDimension || cfg.Height > maxDimension || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	orientation := 1
+	if format == FormatJPEG {
+		orientation = jpegOrientation(data)
+	}
+	return &Image{img: img, orientation: orientation}, nil
+}
+
+// Square center-crops the image to a square and scales it to size×size
+// pixels, upright according to its EXIF orientation.
+func (m *Image) Square(size int) *image.NRGBA {
+	b := m.img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2))
+	// Cropping is centred and square, so orientation can be fixed on the
+	// small result instead of the full image.
+	return orient(resample(m.img, crop, size), m.orientation)
+}
+
+// Resize scales an already square image to size×size. Producing small
+// variants from the largest one is much cheaper than from the original.
+func Resize(img *image.NRGBA, size int) *image.NRGBA {
+	return resample(img, img.Bounds(), size)
+}
+
+// Encode writes img as FormatJPEG or FormatPNG. Callers should only pick
+// JPEG for opaque images.
+func Encode(w io.Writer, img *image.NRGBA, format string) error {
+	switch format {
+	case FormatJPEG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	case FormatPNG:
+		return png.Encode(w, img)
+	default:
+		return ErrUnsupportedFormat
+	}
+}
+
+// resample scales the src region r down (or up) to size×size by averaging
+// every source pixel that falls into each destination pixel.
+func resample(src image.Image, r image.Rectangle, size int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	side := r.Dx()
+	for dy := 0; dy < size; dy++ {
+		y0, y1 := span(dy, side, size)
+		for dx := 0; dx < size; dx++ {
+			x0, x1 := span(dx, side, size)
+			var rs, gs, bs, as, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					cr, cg, cb, ca := src.At(r.Min.X+x, r.Min.Y+y).RGBA()
+					rs += uint64(cr)
+					gs += uint64(cg)
+					bs += uint64(cb)
+					as += uint64(ca)
+					n++
+				}
+			}
+			dst.SetNRGBA(dx, dy, unpremultiply(rs/n, gs/n, bs/n, as/n))
+		}
+	}
+	return dst
+}
+
+// span returns the source range covered by destination index i.
+func span(i, src, dst int) (int, int) {
+	from := i * src / dst
+	to := (i + 1) * src / dst
+	if to <= from {
+		to = from + 1
+	}
+	return from, to
+}
+
+func unpremultiply(r, g, b, a uint64) color.NRGBA {
+	if a == 0 {
+		return color.NRGBA{}
+	}
+	return color.NRGBA{
+		R: uint8((r * 0xffff / a) >> 8),
+		G: uint8((g * 0xffff / a) >> 8),
+		B: uint8((b * 0xffff / a) >> 8),
+		A: uint8(a >> 8),
+	}
+}
+
+// orient applies an EXIF orientation (1-8) to a square image.
+func orient(img *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	n := img.Bounds().Dx()
+	dst := image.NewNRGBA(img.Bounds())
+	last := n - 1
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = last-x, y
+			case 3:
+				sx, sy = last-x, last-y
+			case 4:
+				sx, sy = x, last-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, last-x
+			case 7:
+				sx, sy = last-y, last-x
+			case 8:
+				sx, sy = last-y, x
+			}
+			dst.SetNRGBA(x, y, img.NRGBAAt(sx, sy))
+		}
+	}
+	return dst
+}
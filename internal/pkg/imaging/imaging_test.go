@@ -0,0 +1,176 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+		err  error
+	}{
+		{name: "jpeg", data: []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00}, want: FormatJPEG},
+		{name: "png", data: []byte("\x89PNG\r\n\x1a\n\x00\x00"), want: FormatPNG},
+		{name: "gif87a", data: []byte("GIF87a\x01\x00"), want: FormatGIF},
+		{name: "gif89a", data: []byte("GIF89a\x01\x00"), want: FormatGIF},
+		{name: "short jpeg prefix", data: []byte{0xFF, 0xD8}, err: ErrUnsupportedFormat},
+		{name: "png without line ending check", data: []byte("\x89PNG\n\n\x1a\n"), err: ErrUnsupportedFormat},
+		{name: "unknown gif version", data: []byte("GIF88a"), err: ErrUnsupportedFormat},
+		{name: "svg named .png", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`), err: ErrUnsupportedFormat},
+		{name: "html", data: []byte("<!doctype html><script>"), err: ErrUnsupportedFormat},
+		{name: "webp", data: []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), err: ErrUnsupportedFormat},
+		{name: "png signature after padding", data: []byte(" \x89PNG\r\n\x1a\n"), err: ErrUnsupportedFormat},
+		{name: "empty", data: nil, err: ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormat(tt.data)
+			if got != tt.want || !errors.Is(err, tt.err) {
+				t.Errorf("DetectFormat = %q, %v; want %q, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// pngHeader is a PNG whose IHDR claims width×height; the pixel data is
+// never read because Decode checks the size first.
+func pngHeader(t *testing.T, width, height uint32) []byte {
+	data := encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 1, 1)))
+	// Signature (8) + IHDR length (4), then "IHDR" and its 13 data bytes.
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func gifHeader(width, height uint16) []byte {
+	data := []byte("GIF89a")
+	data = binary.LittleEndian.AppendUint16(data, width)
+	data = binary.LittleEndian.AppendUint16(data, height)
+	return append(data, 0, 0, 0)
+}
+
+func TestDecodeLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{name: "small png", data: encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 4, 3)))},
+		{name: "png at the dimension limit passes the size check", data: pngHeader(t, maxDimension, 1), err: ErrInvalidImage},
+		{name: "png wider than the limit", data: pngHeader(t, maxDimension+1, 1), err: ErrImageTooLarge},
+		{name: "png taller than the limit", data: pngHeader(t, 1, maxDimension+1), err: ErrImageTooLarge},
+		{name: "png over the pixel budget", data: pngHeader(t, 7000, 7000), err: ErrImageTooLarge},
+		{name: "gif wider than the limit", data: gifHeader(maxDimension+1, 1), err: ErrImageTooLarge},
+		{name: "gif over the pixel budget", data: gifHeader(8000, 8000), err: ErrImageTooLarge},
+		{name: "gif with zero width", data: gifHeader(0, 10), err: ErrInvalidImage},
+		{name: "truncated jpeg", data: []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10}, err: ErrInvalidImage},
+		{name: "spoofed text", data: []byte("not an image"), err: ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.data); !errors.Is(err, tt.err) {
+				t.Errorf("Decode error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// quadrants is a 2×2 image with pixels A B / C D, told apart by their red
+// channel.
+func quadrants() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	for i, p := range []image.Point{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		img.SetNRGBA(p.X, p.Y, color.NRGBA{R: uint8('A' + i), A: 0xFF})
+	}
+	return img
+}
+
+func TestOrient(t *testing.T) {
+	tests := []struct {
+		orientation int
+		want        string
+	}{
+		{orientation: 1, want: "ABCD"},
+		{orientation: 2, want: "BADC"},
+		{orientation: 3, want: "DCBA"},
+		{orientation: 4, want: "CDAB"},
+		{orientation: 5, want: "ACBD"},
+		{orientation: 6, want: "CADB"},
+		{orientation: 7, want: "DBCA"},
+		{orientation: 8, want: "BDAC"},
+		{orientation: 9, want: "ABCD"},
+	}
+	for _, tt := range tests {
+		out := orient(quadrants(), tt.orientation)
+		got := string([]byte{out.NRGBAAt(0, 0).R, out.NRGBAAt(1, 0).R, out.NRGBAAt(0, 1).R, out.NRGBAAt(1, 1).R})
+		if got != tt.want {
+			t.Errorf("orient(%d) = %s, want %s", tt.orientation, got, tt.want)
+		}
+	}
+}
+
+// markers lists the segment markers of a JPEG up to the start of scan.
+func markers(data []byte) []byte {
+	var found []byte
+	for pos := 2; pos+4 <= len(data) && data[pos] == 0xFF; {
+		found = append(found, data[pos+1])
+		if data[pos+1] == 0xDA {
+			break
+		}
+		pos += 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+	}
+	return found
+}
+
+func TestEncodeDropsMetadata(t *testing.T) {
+	var src bytes.Buffer
+	if err := jpeg.Encode(&src, image.NewNRGBA(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	// Splice an EXIF segment right after SOI, the way cameras write it.
+	exif := exifJPEG("II", 6)
+	withExif := append(append(append([]byte{}, src.Bytes()[:2]...), exif[2:len(exif)-4]...), src.Bytes()[2:]...)
+	if bytes.IndexByte(markers(withExif), 0xE1) < 0 {
+		t.Fatalf("test input has no APP1 segment")
+	}
+
+	img, err := Decode(withExif)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if img.orientation != 6 {
+		t.Errorf("orientation = %d, want 6", img.orientation)
+	}
+	for _, format := range []string{FormatJPEG, FormatPNG} {
+		var out bytes.Buffer
+		if err := Encode(&out, img.Square(4), format); err != nil {
+			t.Fatalf("Encode %s: %v", format, err)
+		}
+		if bytes.Contains(out.Bytes(), []byte("Exif")) {
+			t.Errorf("%s output still carries EXIF", format)
+		}
+		if format == FormatJPEG && bytes.IndexByte(markers(out.Bytes()), 0xE1) >= 0 {
+			t.Errorf("jpeg output has an APP1 segment")
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	Driver   string
+	From     string
+	Dir      string
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// New picks the implementation named by cfg.Driver: "smtp", "file" or
+// "log" (the default).
+func New(cfg Config, logger *slog.Logger) (Mailer, error) {
+	if cfg.From == "" {
+		cfg.From = "no-reply@kerjakuy.local"
+	}
+	switch cfg.Driver {
+	case "smtp":
+		if cfg.Host == "" {
+			return nil, fmt.Errorf("mailer smtp membutuhkan host")
+		}
+		return NewSMTPMailer(cfg), nil
+	case "file":
+		if cfg.Dir == "" {
+			return nil, fmt.Errorf("mailer file membutuhkan direktori")
+		}
+		return NewFileMailer(cfg.From, cfg.Dir), nil
+	case "", "log":
+		return NewLogMailer(cfg.From, logger), nil
+	default:
+		return nil, fmt.Errorf("driver mailer tidak dikenal: %s", cfg.Driver)
+	}
+}
+
+// LogMailer writes every message to the application log. Intended for local
+// development where no mail server is available. Link tokens are redacted,
+// since logs are kept longer and read by more people than mailboxes; use the
+// file driver to follow links locally.
+type LogMailer struct {
+	from   string
+	logger *slog.Logger
+}
+
+func NewLogMailer(from string, logger *slog.Logger) *LogMailer {
+	return &LogMailer{from: from, logger: logger}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.logger.Info("mail sent",
+		"from", m.from,
+		"to", strings.Join(msg.To, ","),
+		"subject", msg.Subject,
+		"body", redactTokens(msg.Body),
+	)
+	return nil
+}
+
+var tokenParam = regexp.MustCompile(`([?&]token=)[^\s&#]+`)
+
+// redactTokens hides the value of every token query parameter in body.
+func redactTokens(body string) string {
+	return tokenParam.ReplaceAllString(body, "${1}[redacted]")
+}
+
+// FileMailer stores each message as an .eml file in a directory.
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+func NewFileMailer(from, dir string) *FileMailer {
+	return &FileMailer{from: from, dir: dir}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.NewString())
+	return os.WriteFile(filepath.Join(m.dir, name), buildMessage(m.from, msg), 0o644)
+}
+
+func buildMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", sanitizeHeader(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
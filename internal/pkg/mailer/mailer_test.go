@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "single link",
+			body: "Buka http://app.test/reset-password?token=abc-123_XYZ dalam 60 menit",
+			want: "Buka http://app.test/reset-password?token=[redacted] dalam 60 menit",
+		},
+		{
+			name: "token among other parameters",
+			body: "http://app.test/invitations?workspace=1&token=secret&x=2",
+			want: "http://app.test/invitations?workspace=1&token=[redacted]&x=2",
+		},
+		{
+			name: "several links",
+			body: "a?token=one\nb?token=two",
+			want: "a?token=[redacted]\nb?token=[redacted]",
+		},
+		{
+			name: "no token",
+			body: "Halo, tidak ada tautan di sini.",
+			want: "Halo, tidak ada tautan di sini.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactTokens(tt.body); got != tt.want {
+				t.Errorf("redactTokens() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogMailerDoesNotLogTokens(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLogMailer("no-reply@kerjakuy.local", slog.New(slog.NewTextHandler(&buf, nil)))
+	err := m.Send(context.Background(), Message{
+		To:      []string{"ana@example.com"},
+		Subject: "Tautan login KerjaKuy",
+		Body:    "http://app.test/magic-link?token=very-secret-token",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if strings.Contains(buf.String(), "very-secret-token") {
+		t.Errorf("log contains the token: %s", buf.String())
+	}
+}
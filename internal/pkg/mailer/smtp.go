@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+)
+
+// SMTPMailer delivers through a plain SMTP server. STARTTLS is used
+// automatically when the server advertises it.
+type SMTPMailer struct {
+	from string
+	addr string
+	host string
+	auth smtp.Auth
+}
+
+func NewSMTPMailer(cfg Config) *SMTPMailer {
+	port := cfg.Port
+	if port == "" {
+		port = "587"
+	}
+	m := &SMTPMailer{
+		from: cfg.From,
+		addr: net.JoinHostPort(cfg.Host, port),
+		host: cfg.Host,
+	}
+	if cfg.Username != "" {
+		m.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(m.addr, m.auth, m.from, msg.To, buildMessage(m.from, msg))
+}
@@ -0,0 +1,159 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+)
+
+// smtpSession is what the fake server saw from one client.
+type smtpSession struct {
+	auth string
+	from string
+	rcpt []string
+	data string
+}
+
+// startFakeSMTP accepts a single SMTP conversation on a local port and sends
+// the transcript on the returned channel.
+func startFakeSMTP(t *testing.T, advertiseAuth bool) (string, string, <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	done := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var s smtpSession
+		reply("220 fake.test ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				if advertiseAuth {
+					reply("250-fake.test")
+					reply("250 AUTH PLAIN")
+				} else {
+					reply("250 fake.test")
+				}
+			case strings.HasPrefix(cmd, "AUTH PLAIN"):
+				s.auth = strings.TrimSpace(line[len("AUTH PLAIN"):])
+				reply("235 ok")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				s.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 ok")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				s.rcpt = append(s.rcpt, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				reply("250 ok")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var b strings.Builder
+				for {
+					dl, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dl == ".\r\n" {
+						break
+					}
+					b.WriteString(dl)
+				}
+				s.data = b.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				done <- s
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	if err != nil {
+		t.Fatalf("split addr: %v", err)
+	}
+	return host, port, done
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantAuth string
+	}{
+		{name: "without auth"},
+		{name: "with plain auth", username: "kerjakuy", password: "rahasia", wantAuth: "\x00kerjakuy\x00rahasia"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, done := startFakeSMTP(t, tt.username != "")
+			m := NewSMTPMailer(Config{
+				From:     "no-reply@kerjakuy.test",
+				Host:     host,
+				Port:     port,
+				Username: tt.username,
+				Password: tt.password,
+			})
+			err := m.Send(context.Background(), Message{
+				To:      []string{"ana@example.com", "budi@example.com"},
+				Subject: "Halo\r\nBcc: evil@example.com",
+				Body:    "Baris satu\nBaris dua",
+			})
+			if err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			s := <-done
+
+			if s.from != "no-reply@kerjakuy.test" {
+				t.Errorf("MAIL FROM = %q", s.from)
+			}
+			if strings.Join(s.rcpt, ",") != "ana@example.com,budi@example.com" {
+				t.Errorf("RCPT TO = %v", s.rcpt)
+			}
+			if tt.wantAuth != "" {
+				decoded, err := base64.StdEncoding.DecodeString(s.auth)
+				if err != nil || string(decoded) != tt.wantAuth {
+					t.Errorf("AUTH PLAIN = %q (%v), want %q", decoded, err, tt.wantAuth)
+				}
+			} else if s.auth != "" {
+				t.Errorf("unexpected AUTH %q", s.auth)
+			}
+			if !strings.Contains(s.data, "Subject: Halo  Bcc: evil@example.com\r\n") {
+				t.Errorf("subject header not sanitized:\n%s", s.data)
+			}
+			if !strings.Contains(s.data, "\r\n\r\nBaris satu\r\nBaris dua") {
+				t.Errorf("body not CRLF encoded:\n%s", s.data)
+			}
+		})
+	}
+}
+
+func TestSMTPMailerHonoursCancelledContext(t *testing.T) {
+	m := NewSMTPMailer(Config{From: "no-reply@kerjakuy.test", Host: "127.0.0.1", Port: "1"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.Send(ctx, Message{To: []string{"ana@example.com"}}); err == nil {
+		t.Fatal("Send() with cancelled context = nil, want error")
+	}
+}
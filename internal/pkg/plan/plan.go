@@ -0,0 +1,107 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type Plan string
+
+const (
+	Free     Plan = "free"
+	Standard Plan = "standard"
+	Pro      Plan = "pro"
+)
+
+// Limits caps what a workspace on a plan may use. Zero means unlimited.
+type Limits struct {
+	Members          int64
+	Projects         int64
+	BoardsPerProject int64
+	StorageBytes     int64
+	ChatRetention    time.Duration
+}
+
+const (
+	mb  = int64(1) << 20
+	gb  = int64(1) << 30
+	day = 24 * time.Hour
+)
+
+var Catalogue = map[Plan]Limits{
+	Free: {
+		Members:          5,
+		Projects:         3,
+		BoardsPerProject: 3,
+		StorageBytes:     100 * mb,
+		ChatRetention:    30 * day,
+	},
+	Standard: {
+		Members:          25,
+		Projects:         25,
+		BoardsPerProject: 10,
+		StorageBytes:     10 * gb,
+		ChatRetention:    365 * day,
+	},
+	Pro: {
+		StorageBytes: 100 * gb,
+	},
+}
+
+// IsValid reports whether p is in the catalogue.
+func IsValid(p Plan) bool {
+	_, ok := Catalogue[p]
+	return ok
+}
+
+// Names lists the plans in the catalogue, sorted.
+func Names() []string {
+	names := make([]string, 0, len(Catalogue))
+	for p := range Catalogue {
+		names = append(names, string(p))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LimitsFor returns the limits of p. Unknown plans get the free limits.
+func LimitsFor(p Plan) Limits {
+	if limits, ok := Catalogue[p]; ok {
+		return limits
+	}
+	return Catalogue[Free]
+}
+
+var ErrQuotaExceeded = errors.New("kuota paket workspace terlampaui")
+
+// Resources named in a QuotaError.
+const (
+	ResourceMembers  = "anggota"
+	ResourceProjects = "proyek"
+	ResourceBoards   = "board per proyek"
+	ResourceStorage  = "penyimpanan lampiran"
+)
+
+// QuotaError says which limit was hit. It matches ErrQuotaExceeded.
+type QuotaError struct {
+	Resource string
+	Limit    int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s: batas %s adalah %d", ErrQuotaExceeded.Error(), e.Resource, e.Limit)
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// Check returns a QuotaError when adding n to used would go over limit.
+func Check(resource string, limit, used, n int64) error {
+	if limit > 0 && used+n > limit {
+		return &QuotaError{Resource: resource, Limit: limit}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package plan
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int64
+		used    int64
+		n       int64
+		wantErr bool
+	}{
+		{name: "under the limit", limit: 3, used: 1, n: 1},
+		{name: "exactly at the limit", limit: 3, used: 2, n: 1},
+		{name: "one over the limit", limit: 3, used: 3, n: 1, wantErr: true},
+		{name: "large upload over storage", limit: 100, used: 10, n: 91, wantErr: true},
+		{name: "zero limit is unlimited", limit: 0, used: 1 << 40, n: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(ResourceProjects, tt.limit, tt.used, tt.n)
+			if got := errors.Is(err, ErrQuotaExceeded); got != tt.wantErr {
+				t.Fatalf("Check(%d, %d, %d) = %v, want quota error %v", tt.limit, tt.used, tt.n, err, tt.wantErr)
+			}
+			var quota *QuotaError
+			if tt.wantErr && (!errors.As(err, &quota) || quota.Limit != tt.limit || quota.Resource != ResourceProjects) {
+				t.Errorf("Check error = %#v, want QuotaError for %s", err, ResourceProjects)
+			}
+		})
+	}
+}
+
+func TestLimitsForUnknownPlanIsFree(t *testing.T) {
+	if got := LimitsFor(Plan("enterprise")); got != Catalogue[Free] {
+		t.Errorf("LimitsFor(enterprise) = %+v, want free limits", got)
+	}
+	for _, name := range Names() {
+		if !IsValid(Plan(name)) {
+			t.Errorf("Names() lists %q, which is not in the catalogue", name)
+		}
+	}
+}
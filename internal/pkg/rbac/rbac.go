@@ -11,11 +11,13 @@ const (
 
 const (
 	// Workspace permissions
-	PermissionUpdateWorkspace Permission = "workspace:update"
-	PermissionDeleteWorkspace Permission = "workspace:delete"
-	PermissionInviteMember    Permission = "workspace:invite_member"
-	PermissionRemoveMember    Permission = "workspace:remove_member"
-	PermissionUpdateMember    Permission = "workspace:update_member"
+	PermissionUpdateWorkspace   Permission = "workspace:update"
+	PermissionDeleteWorkspace   Permission = "workspace:delete"
+	PermissionInviteMember      Permission = "workspace:invite_member"
+	PermissionRemoveMember      Permission = "workspace:remove_member"
+	PermissionUpdateMember      Permission = "workspace:update_member"
+	PermissionViewSecurityLog   Permission = "workspace:view_security_log"
+	PermissionTransferOwnership Permission = "workspace:transfer_ownership"
 
 	// Project permissions
 	PermissionCreateProject Permission = "project:create"
@@ -38,6 +40,8 @@ var Policy = map[Role][]Permission{
 		PermissionInviteMember,
 		PermissionRemoveMember,
 		PermissionUpdateMember,
+		PermissionViewSecurityLog,
+		PermissionTransferOwnership,
 		PermissionCreateProject,
 		PermissionUpdateProject,
 		PermissionDeleteProject,
@@ -75,6 +79,25 @@ var Policy = map[Role][]Permission{
 	},
 }
 
+// roleRank orders the roles from least to most privileged.
+var roleRank = map[Role]int{
+	RoleMember: 1,
+	RoleAdmin:  2,
+	RoleOwner:  3,
+}
+
+// IsValidRole reports whether role is one of the defined roles.
+func IsValidRole(role Role) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// Outranks reports whether a sits strictly above b in the role hierarchy.
+// Unknown roles rank below every defined role.
+func Outranks(a, b Role) bool {
+	return roleRank[a] > roleRank[b]
+}
+
 func HasPermission(role Role, perm Permission) bool {
 	perms, ok := Policy[role]
 	if !ok {
@@ -87,3 +110,15 @@ func HasPermission(role Role, perm Permission) bool {
 	}
 	return false
 }
+
+// IsValidPermission reports whether perm is granted by at least one role.
+func IsValidPermission(perm Permission) bool {
+	for _, perms := range Policy {
+		for _, p := range perms {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("objek tidak ditemukan")
+	ErrInvalidKey = errors.New("kunci objek tidak valid")
+)
+
+// Store keeps binary objects under slash-separated keys such as
+// "avatars/<user>/<file>".
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type Config struct {
+	Driver string
+	Dir    string
+}
+
+// New picks the implementation named by cfg.Driver; "local" (the default)
+// is the only one so far.
+func New(cfg Config) (Store, error) {
+	switch cfg.Driver {
+	case "", "local":
+		if cfg.Dir == "" {
+			cfg.Dir = "./tmp/storage"
+		}
+		return NewLocalStore(cfg.Dir), nil
+	default:
+		return nil, fmt.Errorf("driver storage tidak dikenal: %s", cfg.Driver)
+	}
+}
+
+// LocalStore keeps objects as files below a directory.
+type LocalStore struct {
+	dir string
+}
+
+func NewLocalStore(dir string) *LocalStore {
+	return &LocalStore{dir: dir}
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	// Write then rename so readers never see a partial object.
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s *LocalStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := NewLocalStore(dir)
+
+	if err := s.Put(ctx, "avatars/u1/a-256.png", []byte("first")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := s.Put(ctx, "avatars/u1/a-256.png", []byte("second")); err != nil {
+		t.Fatalf("Put overwrite: %v", err)
+	}
+	body, err := s.Open(ctx, "avatars/u1/a-256.png")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	got, _ := io.ReadAll(body)
+	body.Close()
+	if string(got) != "second" {
+		t.Errorf("Open read %q, want %q", got, "second")
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "avatars", "u1")); len(entries) != 1 {
+		t.Errorf("directory holds %d files, want only the object", len(entries))
+	}
+
+	if err := s.Delete(ctx, "avatars/u1/a-256.png"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Open(ctx, "avatars/u1/a-256.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after Delete error = %v, want %v", err, ErrNotFound)
+	}
+	if err := s.Delete(ctx, "avatars/u1/a-256.png"); err != nil {
+		t.Errorf("Delete of a missing object = %v, want nil", err)
+	}
+}
+
+func TestLocalStoreRejectsInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocalStore(t.TempDir())
+	for _, key := range []string{"", "/etc/passwd", "..", "../secret", "avatars/../../secret", "avatars//a.png", "avatars/./a.png", "avatars/"} {
+		if err := s.Put(ctx, key, []byte("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) error = %v, want %v", key, err, ErrInvalidKey)
+		}
+		if _, err := s.Open(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Open(%q) error = %v, want %v", key, err, ErrInvalidKey)
+		}
+		if err := s.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Delete(%q) error = %v, want %v", key, err, ErrInvalidKey)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Config{Driver: "local", Dir: t.TempDir()}); err != nil {
+		t.Errorf("New(local) error = %v", err)
+	}
+	if _, err := New(Config{Driver: "s3"}); err == nil {
+		t.Errorf("New accepted an unknown driver")
+	}
+}
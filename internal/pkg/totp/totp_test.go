@@ -0,0 +1,64 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the RFC 6238 SHA-1 test key "12345678901234567890" in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeAtMatchesRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+	for _, tt := range tests {
+		got, err := CodeAt(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("CodeAt(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("CodeAt(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code := func(offset int64) string {
+		c, err := CodeAt(rfcSecret, Step(now)+offset)
+		if err != nil {
+			t.Fatalf("CodeAt: %v", err)
+		}
+		return c
+	}
+	tests := []struct {
+		name     string
+		code     string
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", code: code(0), skew: 1, wantStep: Step(now), wantOK: true},
+		{name: "surrounding whitespace", code: " " + code(0) + "\n", skew: 1, wantStep: Step(now), wantOK: true},
+		{name: "previous step within skew", code: code(-1), skew: 1, wantStep: Step(now) - 1, wantOK: true},
+		{name: "previous step without skew", code: code(-1), skew: 0},
+		{name: "two steps old", code: code(-2), skew: 1},
+		{name: "wrong length", code: "12345", skew: 1},
+		{name: "wrong code", code: "000000", skew: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, now, tt.skew)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate = %d, %v; want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package project
 
 import (
+	"errors"
 	"net/http"
 
 	"kerjakuy/internal/auth"
+	"kerjakuy/internal/pkg/plan"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -39,7 +41,7 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 
 	project, err := h.projectService.CreateProject(c.Request.Context(), req, createdBy)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -53,9 +55,15 @@ func (h *ProjectHandler) ListProjects(c *gin.Context) {
 		return
 	}
 
-	projects, err := h.projectService.ListWorkspaceProjects(c.Request.Context(), workspaceID)
+	viewerID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	projects, err := h.projectService.ListWorkspaceProjects(c.Request.Context(), viewerID, workspaceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -83,7 +91,7 @@ func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 
 	project, err := h.projectService.UpdateProject(c.Request.Context(), actorID, projectID, req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -104,7 +112,7 @@ func (h *ProjectHandler) DeleteProject(c *gin.Context) {
 	}
 
 	if err := h.projectService.DeleteProject(c.Request.Context(), actorID, projectID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -133,7 +141,7 @@ func (h *ProjectHandler) CreateBoard(c *gin.Context) {
 
 	board, err := h.projectService.CreateBoard(c.Request.Context(), actorID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -147,9 +155,15 @@ func (h *ProjectHandler) ListBoards(c *gin.Context) {
 		return
 	}
 
-	boards, err := h.projectService.ListBoards(c.Request.Context(), projectID)
+	viewerID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	boards, err := h.projectService.ListBoards(c.Request.Context(), viewerID, projectID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -177,7 +191,7 @@ func (h *ProjectHandler) UpdateBoard(c *gin.Context) {
 
 	board, err := h.projectService.UpdateBoard(c.Request.Context(), actorID, boardID, req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -198,7 +212,7 @@ func (h *ProjectHandler) DeleteBoard(c *gin.Context) {
 	}
 
 	if err := h.projectService.DeleteBoard(c.Request.Context(), actorID, boardID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -227,7 +241,7 @@ func (h *ProjectHandler) CreateColumn(c *gin.Context) {
 
 	column, err := h.projectService.CreateColumn(c.Request.Context(), actorID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -241,9 +255,15 @@ func (h *ProjectHandler) ListColumns(c *gin.Context) {
 		return
 	}
 
-	columns, err := h.projectService.ListColumns(c.Request.Context(), boardID)
+	viewerID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	columns, err := h.projectService.ListColumns(c.Request.Context(), viewerID, boardID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -271,7 +291,7 @@ func (h *ProjectHandler) UpdateColumn(c *gin.Context) {
 
 	column, err := h.projectService.UpdateColumn(c.Request.Context(), actorID, columnID, req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -292,9 +312,24 @@ func (h *ProjectHandler) DeleteColumn(c *gin.Context) {
 	}
 
 	if err := h.projectService.DeleteColumn(c.Request.Context(), actorID, columnID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+// respondError maps permission, workspace policy and quota errors to 403 and
+// everything else to fallback.
+func (h *ProjectHandler) respondError(c *gin.Context, err error, fallback int) {
+	switch {
+	case errors.Is(err, ErrPermissionDenied),
+		errors.Is(err, plan.ErrQuotaExceeded),
+		errors.Is(err, auth.ErrWorkspaceRequiresTwoFactor),
+		errors.Is(err, auth.ErrWorkspaceRequiresVerifiedEmail),
+		errors.Is(err, auth.ErrWorkspacePendingDeletion):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(fallback, gin.H{"error": err.Error()})
+	}
+}
@@ -7,12 +7,15 @@ import (
 	"kerjakuy/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProjectRepository interface {
 	Create(ctx context.Context, project *models.Project) error
 	FindByID(ctx context.Context, id uuid.UUID) (*models.Project, error)
 	ListByWorkspace(ctx context.Context, workspaceID uuid.UUID) ([]models.Project, error)
+	CountByWorkspace(ctx context.Context, workspaceID uuid.UUID) (int64, error)
+	LockWorkspace(ctx context.Context, workspaceID uuid.UUID) error
 	Update(ctx context.Context, project *models.Project) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -21,6 +24,7 @@ type BoardRepository interface {
 	Create(ctx context.Context, board *models.Board) error
 	FindByID(ctx context.Context, id uuid.UUID) (*models.Board, error)
 	ListByProject(ctx context.Context, projectID uuid.UUID) ([]models.Board, error)
+	CountByProject(ctx context.Context, projectID uuid.UUID) (int64, error)
 	Update(ctx context.Context, board *models.Board) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -77,6 +81,22 @@ func (r *projectRepository) ListByWorkspace(ctx context.Context, workspaceID uui
 	return projects, nil
 }
 
+func (r *projectRepository) CountByWorkspace(ctx context.Context, workspaceID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Project{}).Where("workspace_id = ?", workspaceID).Count(&count).Error
+	return count, err
+}
+
+// LockWorkspace holds the workspace row until the surrounding transaction
+// ends, so quota checks against the workspace run one at a time.
+func (r *projectRepository) LockWorkspace(ctx context.Context, workspaceID uuid.UUID) error {
+	var workspace models.Workspace
+	return r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		First(&workspace, "id = ?", workspaceID).Error
+}
+
 func (r *projectRepository) Update(ctx context.Context, project *models.Project) error {
 	return r.db.WithContext(ctx).Save(project).Error
 }
@@ -105,6 +125,12 @@ func (r *boardRepository) ListByProject(ctx context.Context, projectID uuid.UUID
 	return boards, nil
 }
 
+func (r *boardRepository) CountByProject(ctx context.Context, projectID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Board{}).Where("project_id = ?", projectID).Count(&count).Error
+	return count, err
+}
+
 func (r *boardRepository) Update(ctx context.Context, board *models.Board) error {
 	return r.db.WithContext(ctx).Save(board).Error
 }
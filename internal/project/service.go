@@ -6,39 +6,54 @@ import (
 
 	"kerjakuy/internal/auth"
 	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/plan"
 	"kerjakuy/internal/pkg/rbac"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type ProjectService interface {
 	CreateProject(ctx context.Context, req CreateProjectRequest, createdBy uuid.UUID) (*ProjectDTO, error)
 	UpdateProject(ctx context.Context, actorID uuid.UUID, projectID uuid.UUID, req UpdateProjectRequest) (*ProjectDTO, error)
 	DeleteProject(ctx context.Context, actorID uuid.UUID, projectID uuid.UUID) error
-	ListWorkspaceProjects(ctx context.Context, workspaceID uuid.UUID) ([]ProjectDTO, error)
+	ListWorkspaceProjects(ctx context.Context, viewerID uuid.UUID, workspaceID uuid.UUID) ([]ProjectDTO, error)
 	CreateBoard(ctx context.Context, actorID uuid.UUID, req CreateBoardRequest) (*BoardDTO, error)
-	ListBoards(ctx context.Context, projectID uuid.UUID) ([]BoardDTO, error)
+	ListBoards(ctx context.Context, viewerID uuid.UUID, projectID uuid.UUID) ([]BoardDTO, error)
 	UpdateBoard(ctx context.Context, actorID uuid.UUID, boardID uuid.UUID, req UpdateBoardRequest) (*BoardDTO, error)
 	DeleteBoard(ctx context.Context, actorID uuid.UUID, boardID uuid.UUID) error
 	CreateColumn(ctx context.Context, actorID uuid.UUID, req CreateColumnRequest) (*ColumnDTO, error)
-	ListColumns(ctx context.Context, boardID uuid.UUID) ([]ColumnDTO, error)
+	ListColumns(ctx context.Context, viewerID uuid.UUID, boardID uuid.UUID) ([]ColumnDTO, error)
 	UpdateColumn(ctx context.Context, actorID uuid.UUID, columnID uuid.UUID, req UpdateColumnRequest) (*ColumnDTO, error)
 	DeleteColumn(ctx context.Context, actorID uuid.UUID, columnID uuid.UUID) error
 }
 
+// ErrPermissionDenied is returned when the viewer cannot read a workspace.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// PlanLimiter returns the plan limits of a workspace. It is implemented by
+// workspace.WorkspaceService.
+type PlanLimiter interface {
+	PlanLimits(ctx context.Context, workspaceID uuid.UUID) (plan.Limits, error)
+}
+
 type projectService struct {
+	db                *gorm.DB
 	projectRepo       ProjectRepository
 	boardRepo         BoardRepository
 	columnRepo        ColumnRepository
 	permissionService auth.PermissionService
+	plans             PlanLimiter
 }
 
-func NewProjectService(projectRepo ProjectRepository, boardRepo BoardRepository, columnRepo ColumnRepository, permissionService auth.PermissionService) ProjectService {
+func NewProjectService(db *gorm.DB, projectRepo ProjectRepository, boardRepo BoardRepository, columnRepo ColumnRepository, permissionService auth.PermissionService, plans PlanLimiter) ProjectService {
 	return &projectService{
+		db:                db,
 		projectRepo:       projectRepo,
 		boardRepo:         boardRepo,
 		columnRepo:        columnRepo,
 		permissionService: permissionService,
+		plans:             plans,
 	}
 }
 
@@ -51,6 +66,11 @@ func (s *projectService) CreateProject(ctx context.Context, req CreateProjectReq
 		return nil, errors.New("permission denied")
 	}
 
+	limits, err := s.plans.PlanLimits(ctx, req.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
 	project := &models.Project{
 		WorkspaceID: req.WorkspaceID,
 		Name:        req.Name,
@@ -58,7 +78,22 @@ func (s *projectService) CreateProject(ctx context.Context, req CreateProjectReq
 		Color:       req.Color,
 		CreatedBy:   createdBy,
 	}
-	if err := s.projectRepo.Create(ctx, project); err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txProjectRepo := NewProjectRepository(tx)
+		// The lock keeps concurrent creates from sharing the last slot.
+		if err := txProjectRepo.LockWorkspace(ctx, req.WorkspaceID); err != nil {
+			return err
+		}
+		count, err := txProjectRepo.CountByWorkspace(ctx, req.WorkspaceID)
+		if err != nil {
+			return err
+		}
+		if err := plan.Check(plan.ResourceProjects, limits.Projects, count, 1); err != nil {
+			return err
+		}
+		return txProjectRepo.Create(ctx, project)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -115,7 +150,11 @@ func (s *projectService) DeleteProject(ctx context.Context, actorID uuid.UUID, p
 	return s.projectRepo.Delete(ctx, projectID)
 }
 
-func (s *projectService) ListWorkspaceProjects(ctx context.Context, workspaceID uuid.UUID) ([]ProjectDTO, error) {
+func (s *projectService) ListWorkspaceProjects(ctx context.Context, viewerID uuid.UUID, workspaceID uuid.UUID) ([]ProjectDTO, error) {
+	if err := s.authorizeView(ctx, viewerID, workspaceID); err != nil {
+		return nil, err
+	}
+
 	projects, err := s.projectRepo.ListByWorkspace(ctx, workspaceID)
 	if err != nil {
 		return nil, err
@@ -141,6 +180,11 @@ func (s *projectService) CreateBoard(ctx context.Context, actorID uuid.UUID, req
 		return nil, errors.New("permission denied")
 	}
 
+	limits, err := s.plans.PlanLimits(ctx, project.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
 	board := &models.Board{
 		ProjectID: req.ProjectID,
 		Name:      req.Name,
@@ -150,13 +194,36 @@ func (s *projectService) CreateBoard(ctx context.Context, actorID uuid.UUID, req
 		board.Position = *req.Position
 	}
 
-	if err := s.boardRepo.Create(ctx, board); err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txBoardRepo := NewBoardRepository(tx)
+		// The lock keeps concurrent creates from sharing the last slot.
+		if err := NewProjectRepository(tx).LockWorkspace(ctx, project.WorkspaceID); err != nil {
+			return err
+		}
+		count, err := txBoardRepo.CountByProject(ctx, project.ID)
+		if err != nil {
+			return err
+		}
+		if err := plan.Check(plan.ResourceBoards, limits.BoardsPerProject, count, 1); err != nil {
+			return err
+		}
+		return txBoardRepo.Create(ctx, board)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return mapBoardToDTO(board), nil
 }
 
-func (s *projectService) ListBoards(ctx context.Context, projectID uuid.UUID) ([]BoardDTO, error) {
+func (s *projectService) ListBoards(ctx context.Context, viewerID uuid.UUID, projectID uuid.UUID) ([]BoardDTO, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeView(ctx, viewerID, project.WorkspaceID); err != nil {
+		return nil, err
+	}
+
 	boards, err := s.boardRepo.ListByProject(ctx, projectID)
 	if err != nil {
 		return nil, err
@@ -258,10 +325,18 @@ func (s *projectService) CreateColumn(ctx context.Context, actorID uuid.UUID, re
 	return mapColumnToDTO(column), nil
 }
 
-func (s *projectService) ListColumns(ctx context.Context, boardID uuid.UUID) ([]ColumnDTO, error) {
-	if _, err := s.boardRepo.FindByID(ctx, boardID); err != nil {
+func (s *projectService) ListColumns(ctx context.Context, viewerID uuid.UUID, boardID uuid.UUID) ([]ColumnDTO, error) {
+	board, err := s.boardRepo.FindByID(ctx, boardID)
+	if err != nil {
 		return nil, errors.New("board not found")
 	}
+	project, err := s.projectRepo.FindByID(ctx, board.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeView(ctx, viewerID, project.WorkspaceID); err != nil {
+		return nil, err
+	}
 
 	columns, err := s.columnRepo.ListByBoard(ctx, boardID)
 	if err != nil {
@@ -371,3 +446,16 @@ func mapColumnToDTO(column *models.Column) *ColumnDTO {
 		UpdatedAt: column.UpdatedAt,
 	}
 }
+
+// authorizeView lets any member of workspaceID read its projects, boards and
+// columns.
+func (s *projectService) authorizeView(ctx context.Context, viewerID, workspaceID uuid.UUID) error {
+	allowed, err := s.permissionService.CanViewWorkspace(ctx, viewerID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrPermissionDenied
+	}
+	return nil
+}
@@ -10,8 +10,11 @@ import (
 
 type WorkspaceMemberRepository interface {
 	Add(ctx context.Context, member *models.WorkspaceMember) error
-	UpdateRole(ctx context.Context, memberID uuid.UUID, role string) error
+	UpdateRole(ctx context.Context, workspaceID, memberID uuid.UUID, role string) error
 	ListByWorkspace(ctx context.Context, workspaceID uuid.UUID) ([]models.WorkspaceMember, error)
 	Remove(ctx context.Context, workspaceID, userID uuid.UUID) error
 	FindByUserAndWorkspace(ctx context.Context, userID, workspaceID uuid.UUID) (*models.WorkspaceMember, error)
+	FindByID(ctx context.Context, workspaceID, memberID uuid.UUID) (*models.WorkspaceMember, error)
+	CountByRole(ctx context.Context, workspaceID uuid.UUID, role string) (int64, error)
+	ListWorkspaceIDsByUser(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
 }
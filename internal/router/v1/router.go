@@ -1,20 +1,24 @@
 package router
 
 import (
+	"kerjakuy/internal/account"
 	"kerjakuy/internal/auth"
 	"kerjakuy/internal/project"
 	"kerjakuy/internal/task"
+	"kerjakuy/internal/user"
 	"kerjakuy/internal/workspace"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(authHandler *auth.AuthHandler, workspaceHandler *workspace.WorkspaceHandler, projectHandler *project.ProjectHandler, taskHandler *task.TaskHandler, authMiddleware *auth.AuthMiddleware) *gin.Engine {
+func SetupRouter(authHandler *auth.AuthHandler, accountHandler *account.AccountHandler, userHandler *user.UserHandler, workspaceHandler *workspace.WorkspaceHandler, projectHandler *project.ProjectHandler, taskHandler *task.TaskHandler, authMiddleware *auth.AuthMiddleware) *gin.Engine {
 	if gin.Mode() == gin.DebugMode {
 		gin.SetMode(gin.DebugMode)
 	}
 	router := gin.Default()
 
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
+
 	api := router.Group("/api/v1")
 	{
 		api.GET("/ping", func(c *gin.Context) {
@@ -30,26 +34,112 @@ func SetupRouter(authHandler *auth.AuthHandler, workspaceHandler *workspace.Work
 			authGroup.GET("/oauth/:provider", authHandler.OAuthRedirect)
 			authGroup.GET("/oauth/:provider/callback", authHandler.OAuthCallback)
 			authGroup.GET("/me", authMiddleware.RequireAuth(), authHandler.Me)
+
+			authGroup.POST("/magic-link", authHandler.RequestMagicLink)
+			authGroup.POST("/magic-link/consume", authHandler.ConsumeMagicLink)
+			authGroup.POST("/password/forgot", authHandler.ForgotPassword)
+			authGroup.POST("/password/reset", authHandler.ResetPassword)
+			authGroup.POST("/password/change", authMiddleware.RequireSessionAuth(), authHandler.ChangePassword)
+
+			authGroup.POST("/email/verify", authHandler.VerifyEmail)
+			authGroup.POST("/email/resend", authMiddleware.RequireSessionAuth(), authHandler.ResendVerificationEmail)
+			authGroup.POST("/email/change", authMiddleware.RequireSessionAuth(), authHandler.RequestEmailChange)
+			authGroup.POST("/email/change/confirm", authHandler.ConfirmEmailChange)
+			authGroup.POST("/email/change/revert", authHandler.RevertEmailChange)
+
+			authGroup.POST("/2fa/verify", authHandler.VerifyTwoFactor)
+			twoFactor := authGroup.Group("/2fa")
+			twoFactor.Use(authMiddleware.RequireSessionAuth())
+			{
+				twoFactor.POST("/enroll", authHandler.EnrollTwoFactor)
+				twoFactor.POST("/confirm", authHandler.ConfirmTwoFactor)
+				twoFactor.POST("/disable", authHandler.DisableTwoFactor)
+				twoFactor.POST("/recovery-codes", authHandler.RegenerateRecoveryCodes)
+			}
+
+			sessions := authGroup.Group("/sessions")
+			sessions.Use(authMiddleware.RequireSessionAuth())
+			{
+				sessions.GET("", authHandler.ListSessions)
+				sessions.DELETE("", authHandler.RevokeOtherSessions)
+				sessions.PATCH("/:sessionID", authHandler.RenameSession)
+				sessions.DELETE("/:sessionID", authHandler.RevokeSession)
+			}
+
+			authGroup.GET("/security-events", authMiddleware.RequireSessionAuth(), authHandler.ListSecurityEvents)
+			authGroup.DELETE("/impersonation", authMiddleware.RequireAuth(), authHandler.StopImpersonation)
+
+			tokens := authGroup.Group("/tokens")
+			tokens.Use(authMiddleware.RequireSessionAuth())
+			{
+				tokens.GET("", authHandler.ListAccessTokens)
+				tokens.POST("", authHandler.CreateAccessToken)
+				tokens.DELETE("/:tokenID", authHandler.RevokeAccessToken)
+			}
+		}
+
+		accountGroup := api.Group("/account")
+		accountGroup.Use(authMiddleware.RequireSessionAuth())
+		{
+			accountGroup.POST("/deletion", accountHandler.ScheduleDeletion)
+			accountGroup.DELETE("/deletion", accountHandler.CancelDeletion)
+			accountGroup.GET("/export", accountHandler.Export)
 		}
 
+		users := api.Group("/users")
+		users.Use(authMiddleware.RequireAuth())
+		{
+			users.GET("/me", userHandler.GetProfile)
+			users.GET("/directory", userHandler.SearchDirectory)
+			users.PATCH("/me", userHandler.UpdateProfile)
+			users.PUT("/me/avatar", userHandler.UploadAvatar)
+			users.DELETE("/me/avatar", userHandler.RemoveAvatar)
+		}
+		api.GET("/avatars/:userID/:file", userHandler.ServeAvatar)
+
+		admin := api.Group("/admin")
+		admin.Use(authMiddleware.RequireSessionAuth())
+		{
+			admin.POST("/impersonations", authHandler.StartImpersonation)
+			admin.PUT("/workspaces/:workspaceID/plan", workspaceHandler.ChangePlan)
+		}
+
+		api.POST("/workspaces", authMiddleware.RequireAuth(), workspaceHandler.CreateWorkspace)
+		api.GET("/workspaces", authMiddleware.RequireAuth(), workspaceHandler.ListWorkspaces)
+
 		workspaces := api.Group("/workspaces")
-		workspaces.Use(authMiddleware.RequireAuth())
+		workspaces.Use(authMiddleware.RequireWorkspaceAuth())
 		{
-			workspaces.POST("", workspaceHandler.CreateWorkspace)
-			workspaces.GET("", workspaceHandler.ListWorkspaces)
 			workspaces.PUT("/:workspaceID", workspaceHandler.UpdateWorkspace)
+			workspaces.DELETE("/:workspaceID", workspaceHandler.DeleteWorkspace)
+			workspaces.POST("/:workspaceID/restore", workspaceHandler.RestoreWorkspace)
+			workspaces.POST("/:workspaceID/ownership-transfer", authMiddleware.RequireSessionAuth(), workspaceHandler.NominateOwner)
+			workspaces.POST("/:workspaceID/ownership-transfer/accept", authMiddleware.RequireSessionAuth(), workspaceHandler.AcceptOwnership)
+			workspaces.DELETE("/:workspaceID/ownership-transfer", authMiddleware.RequireSessionAuth(), workspaceHandler.CancelOwnershipTransfer)
 
 			workspaces.GET("/:workspaceID/members", workspaceHandler.ListMembers)
-			workspaces.POST("/:workspaceID/members", workspaceHandler.InviteMember)
+			workspaces.GET("/:workspaceID/invitees", workspaceHandler.LookupInvitee)
+			workspaces.GET("/:workspaceID/invitations", workspaceHandler.ListInvitations)
+			workspaces.POST("/:workspaceID/invitations", workspaceHandler.SendInvitation)
+			workspaces.POST("/:workspaceID/invitations/:invitationID/resend", workspaceHandler.ResendInvitation)
+			workspaces.DELETE("/:workspaceID/invitations/:invitationID", workspaceHandler.RevokeInvitation)
 			workspaces.PATCH("/:workspaceID/members/:memberID", workspaceHandler.UpdateMemberRole)
 			workspaces.DELETE("/:workspaceID/members/:userID", workspaceHandler.RemoveMember)
+			workspaces.GET("/:workspaceID/security-events", workspaceHandler.ListMemberSecurityEvents)
+			workspaces.GET("/:workspaceID/usage", workspaceHandler.GetUsage)
 
 			workspaces.POST("/:workspaceID/projects", projectHandler.CreateProject)
 			workspaces.GET("/:workspaceID/projects", projectHandler.ListProjects)
 		}
 
+		invitations := api.Group("/invitations")
+		{
+			invitations.POST("/accept", authMiddleware.RequireSessionAuth(), workspaceHandler.AcceptInvitation)
+			invitations.POST("/decline", workspaceHandler.DeclineInvitation)
+		}
+
 		projects := api.Group("/projects")
-		projects.Use(authMiddleware.RequireAuth())
+		projects.Use(authMiddleware.RequireWorkspaceAuth())
 		{
 			projects.PUT("/:projectID", projectHandler.UpdateProject)
 			projects.DELETE("/:projectID", projectHandler.DeleteProject)
@@ -58,7 +148,7 @@ func SetupRouter(authHandler *auth.AuthHandler, workspaceHandler *workspace.Work
 		}
 
 		boards := api.Group("/boards")
-		boards.Use(authMiddleware.RequireAuth())
+		boards.Use(authMiddleware.RequireWorkspaceAuth())
 		{
 			boards.PUT("/:boardID", projectHandler.UpdateBoard)
 			boards.DELETE("/:boardID", projectHandler.DeleteBoard)
@@ -67,7 +157,7 @@ func SetupRouter(authHandler *auth.AuthHandler, workspaceHandler *workspace.Work
 		}
 		
 		columns := api.Group("/columns")
-		columns.Use(authMiddleware.RequireAuth())
+		columns.Use(authMiddleware.RequireWorkspaceAuth())
 		{
 			columns.PUT("/:columnID", projectHandler.UpdateColumn)
 			columns.DELETE("/:columnID", projectHandler.DeleteColumn)
@@ -76,7 +166,7 @@ func SetupRouter(authHandler *auth.AuthHandler, workspaceHandler *workspace.Work
 		}
 
 		tasks := api.Group("/tasks")
-		tasks.Use(authMiddleware.RequireAuth())
+		tasks.Use(authMiddleware.RequireWorkspaceAuth())
 		{
 			tasks.PUT("/:taskID", taskHandler.UpdateTask)
 			tasks.DELETE("/:taskID", taskHandler.DeleteTask)
@@ -83,6 +83,6 @@ type CreateAttachmentRequest struct {
 	TaskID   uuid.UUID `json:"task_id" binding:"required"`
 	FileName string    `json:"file_name" binding:"required"`
 	FileURL  string    `json:"file_url" binding:"required,url"`
-	FileSize *int64    `json:"file_size,omitempty"`
+	FileSize int64     `json:"file_size" binding:"required,min=1"`
 	MimeType *string   `json:"mime_type,omitempty"`
 }
@@ -0,0 +1,112 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/project"
+	"kerjakuy/internal/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// The fakes below embed their interface, so a call a test does not expect
+// panics instead of silently passing.
+
+type fakeMembers struct {
+	repository.WorkspaceMemberRepository
+	roles map[uuid.UUID]string
+}
+
+func (f *fakeMembers) FindByUserAndWorkspace(ctx context.Context, userID, workspaceID uuid.UUID) (*models.WorkspaceMember, error) {
+	role, ok := f.roles[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.WorkspaceMember{UserID: userID, WorkspaceID: workspaceID, Role: role}, nil
+}
+
+type fakeWorkspaces struct {
+	workspace *models.Workspace
+}
+
+func (f *fakeWorkspaces) FindByID(ctx context.Context, id uuid.UUID) (*models.Workspace, error) {
+	if f.workspace.ID != id {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return f.workspace, nil
+}
+
+type noTwoFactor struct{}
+
+func (noTwoFactor) IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error) { return false, nil }
+
+func (noTwoFactor) IsEmailVerified(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return true, nil
+}
+
+type utcLocations struct{}
+
+func (utcLocations) Location(ctx context.Context, id uuid.UUID) (*time.Location, error) {
+	return time.UTC, nil
+}
+
+type fakeProjects struct {
+	project.ProjectRepository
+	proj *models.Project
+}
+
+func (f *fakeProjects) FindByID(ctx context.Context, id uuid.UUID) (*models.Project, error) {
+	if id != f.proj.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return f.proj, nil
+}
+
+func (f *fakeProjects) ListByWorkspace(ctx context.Context, workspaceID uuid.UUID) ([]models.Project, error) {
+	if workspaceID != f.proj.WorkspaceID {
+		return nil, nil
+	}
+	return []models.Project{*f.proj}, nil
+}
+
+type fakeBoards struct {
+	project.BoardRepository
+	board *models.Board
+}
+
+func (f *fakeBoards) FindByID(ctx context.Context, id uuid.UUID) (*models.Board, error) {
+	if id != f.board.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return f.board, nil
+}
+
+type fakeColumns struct {
+	project.ColumnRepository
+	column *models.Column
+}
+
+func (f *fakeColumns) FindByID(ctx context.Context, id uuid.UUID) (*models.Column, error) {
+	if id != f.column.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return f.column, nil
+}
+
+type fakeTasks struct {
+	TaskRepository
+	rows []models.Task
+}
+
+func (f *fakeTasks) ListByColumn(ctx context.Context, columnID uuid.UUID) ([]models.Task, error) {
+	var out []models.Task
+	for _, row := range f.rows {
+		if row.ColumnID != nil && *row.ColumnID == columnID {
+			out = append(out, row)
+		}
+	}
+	return out, nil
+}
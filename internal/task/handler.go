@@ -1,9 +1,12 @@
 package task
 
 import (
+	"errors"
 	"net/http"
 
 	"kerjakuy/internal/auth"
+	"kerjakuy/internal/pkg/plan"
+	"kerjakuy/internal/project"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -39,7 +42,7 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 
 	task, err := h.taskService.CreateTask(c.Request.Context(), req, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -53,9 +56,15 @@ func (h *TaskHandler) ListTasks(c *gin.Context) {
 		return
 	}
 
-	tasks, err := h.taskService.ListTasksByColumn(c.Request.Context(), columnID)
+	viewerID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	tasks, err := h.taskService.ListTasksByColumn(c.Request.Context(), viewerID, columnID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -83,7 +92,7 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 
 	task, err := h.taskService.UpdateTask(c.Request.Context(), actorID, taskID, req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -104,7 +113,7 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 	}
 
 	if err := h.taskService.DeleteTask(c.Request.Context(), actorID, taskID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -132,7 +141,7 @@ func (h *TaskHandler) UpdateAssignees(c *gin.Context) {
 
 	assignees, err := h.taskService.UpdateAssignees(c.Request.Context(), actorID, taskID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -161,7 +170,7 @@ func (h *TaskHandler) AddComment(c *gin.Context) {
 
 	comment, err := h.taskService.AddComment(c.Request.Context(), req, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -175,9 +184,15 @@ func (h *TaskHandler) ListComments(c *gin.Context) {
 		return
 	}
 
-	comments, err := h.taskService.ListComments(c.Request.Context(), taskID)
+	viewerID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	comments, err := h.taskService.ListComments(c.Request.Context(), viewerID, taskID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -206,7 +221,7 @@ func (h *TaskHandler) AddAttachment(c *gin.Context) {
 
 	attachment, err := h.taskService.AddAttachment(c.Request.Context(), req, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -220,11 +235,32 @@ func (h *TaskHandler) ListAttachments(c *gin.Context) {
 		return
 	}
 
-	attachments, err := h.taskService.ListAttachments(c.Request.Context(), taskID)
+	viewerID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	attachments, err := h.taskService.ListAttachments(c.Request.Context(), viewerID, taskID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
 	c.JSON(http.StatusOK, attachments)
 }
+
+// respondError maps permission, workspace policy and quota errors to 403 and
+// everything else to fallback.
+func (h *TaskHandler) respondError(c *gin.Context, err error, fallback int) {
+	switch {
+	case errors.Is(err, project.ErrPermissionDenied),
+		errors.Is(err, plan.ErrQuotaExceeded),
+		errors.Is(err, auth.ErrWorkspaceRequiresTwoFactor),
+		errors.Is(err, auth.ErrWorkspaceRequiresVerifiedEmail),
+		errors.Is(err, auth.ErrWorkspacePendingDeletion):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(fallback, gin.H{"error": err.Error()})
+	}
+}
@@ -31,6 +31,7 @@ type TaskCommentRepository interface {
 type AttachmentRepository interface {
 	Create(ctx context.Context, attachment *models.Attachment) error
 	ListByTask(ctx context.Context, taskID uuid.UUID) ([]models.Attachment, error)
+	SumSizeByWorkspace(ctx context.Context, workspaceID uuid.UUID) (int64, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
@@ -152,6 +153,18 @@ func (r *attachmentRepository) ListByTask(ctx context.Context, taskID uuid.UUID)
 	return attachments, nil
 }
 
+// SumSizeByWorkspace adds up the declared size of every attachment on the
+// workspace's tasks.
+func (r *attachmentRepository) SumSizeByWorkspace(ctx context.Context, workspaceID uuid.UUID) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Model(&models.Attachment{}).
+		Select("COALESCE(SUM(attachments.file_size), 0)").
+		Joins("JOIN tasks ON tasks.id = attachments.task_id").
+		Where("tasks.workspace_id = ?", workspaceID).
+		Scan(&total).Error
+	return total, err
+}
+
 func (r *attachmentRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&models.Attachment{}, "id = ?", id).Error
 }
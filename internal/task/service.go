@@ -3,46 +3,62 @@ package task
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"kerjakuy/internal/auth"
 	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/plan"
 	"kerjakuy/internal/pkg/rbac"
 	"kerjakuy/internal/project"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Service interface {
 	CreateTask(ctx context.Context, req CreateTaskRequest, createdBy uuid.UUID) (*TaskDTO, error)
 	UpdateTask(ctx context.Context, actorID uuid.UUID, taskID uuid.UUID, req UpdateTaskRequest) (*TaskDTO, error)
 	DeleteTask(ctx context.Context, actorID uuid.UUID, taskID uuid.UUID) error
-	ListTasksByColumn(ctx context.Context, columnID uuid.UUID) ([]TaskDTO, error)
+	ListTasksByColumn(ctx context.Context, viewerID uuid.UUID, columnID uuid.UUID) ([]TaskDTO, error)
 	UpdateAssignees(ctx context.Context, actorID uuid.UUID, taskID uuid.UUID, req UpdateTaskAssigneesRequest) ([]TaskAssigneeDTO, error)
 	AddComment(ctx context.Context, req CreateTaskCommentRequest, userID uuid.UUID) (*TaskCommentDTO, error)
-	ListComments(ctx context.Context, taskID uuid.UUID) ([]TaskCommentDTO, error)
+	ListComments(ctx context.Context, viewerID uuid.UUID, taskID uuid.UUID) ([]TaskCommentDTO, error)
 	AddAttachment(ctx context.Context, req CreateAttachmentRequest, uploadedBy uuid.UUID) (*AttachmentDTO, error)
-	ListAttachments(ctx context.Context, taskID uuid.UUID) ([]AttachmentDTO, error)
+	ListAttachments(ctx context.Context, viewerID uuid.UUID, taskID uuid.UUID) ([]AttachmentDTO, error)
+}
+
+// locationResolver returns the timezone a user's dates are rendered in.
+type locationResolver interface {
+	Location(ctx context.Context, id uuid.UUID) (*time.Location, error)
 }
 
 type taskService struct {
+	db                *gorm.DB
 	taskRepo          TaskRepository
 	assigneeRepo      TaskAssigneeRepository
 	commentRepo       TaskCommentRepository
 	attachmentRepo    AttachmentRepository
+	projectRepo       project.ProjectRepository
 	boardRepo         project.BoardRepository
 	columnRepo        project.ColumnRepository
 	permissionService auth.PermissionService
+	locations         locationResolver
+	plans             project.PlanLimiter
 }
 
-func NewService(taskRepo TaskRepository, assigneeRepo TaskAssigneeRepository, commentRepo TaskCommentRepository, attachmentRepo AttachmentRepository, boardRepo project.BoardRepository, columnRepo project.ColumnRepository, permissionService auth.PermissionService) Service {
+func NewService(db *gorm.DB, taskRepo TaskRepository, assigneeRepo TaskAssigneeRepository, commentRepo TaskCommentRepository, attachmentRepo AttachmentRepository, projectRepo project.ProjectRepository, boardRepo project.BoardRepository, columnRepo project.ColumnRepository, permissionService auth.PermissionService, locations locationResolver, plans project.PlanLimiter) Service {
 	return &taskService{
+		db:                db,
 		taskRepo:          taskRepo,
 		assigneeRepo:      assigneeRepo,
 		commentRepo:       commentRepo,
 		attachmentRepo:    attachmentRepo,
+		projectRepo:       projectRepo,
 		boardRepo:         boardRepo,
 		columnRepo:        columnRepo,
 		permissionService: permissionService,
+		locations:         locations,
+		plans:             plans,
 	}
 }
 
@@ -71,6 +87,13 @@ func (s *taskService) CreateTask(ctx context.Context, req CreateTaskRequest, cre
 	if board.ProjectID != req.ProjectID {
 		return nil, fmt.Errorf("column does not belong to project")
 	}
+	proj, err := s.projectRepo.FindByID(ctx, board.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if proj.WorkspaceID != req.WorkspaceID {
+		return nil, fmt.Errorf("project does not belong to workspace")
+	}
 
 	position := 0
 	if req.Position != nil {
@@ -111,7 +134,7 @@ func (s *taskService) CreateTask(ctx context.Context, req CreateTaskRequest, cre
 	if err := s.taskRepo.Create(ctx, task); err != nil {
 		return nil, err
 	}
-	return mapTaskToDTO(task), nil
+	return mapTaskToDTO(task, s.viewerLocation(ctx, createdBy)), nil
 }
 
 func (s *taskService) UpdateTask(ctx context.Context, actorID uuid.UUID, taskID uuid.UUID, req UpdateTaskRequest) (*TaskDTO, error) {
@@ -171,7 +194,7 @@ func (s *taskService) UpdateTask(ctx context.Context, actorID uuid.UUID, taskID
 	if err := s.taskRepo.Update(ctx, task); err != nil {
 		return nil, err
 	}
-	return mapTaskToDTO(task), nil
+	return mapTaskToDTO(task, s.viewerLocation(ctx, actorID)), nil
 }
 
 func (s *taskService) DeleteTask(ctx context.Context, actorID uuid.UUID, taskID uuid.UUID) error {
@@ -191,18 +214,45 @@ func (s *taskService) DeleteTask(ctx context.Context, actorID uuid.UUID, taskID
 	return s.taskRepo.Delete(ctx, taskID)
 }
 
-func (s *taskService) ListTasksByColumn(ctx context.Context, columnID uuid.UUID) ([]TaskDTO, error) {
+func (s *taskService) ListTasksByColumn(ctx context.Context, viewerID uuid.UUID, columnID uuid.UUID) ([]TaskDTO, error) {
+	column, err := s.columnRepo.FindByID(ctx, columnID)
+	if err != nil {
+		return nil, fmt.Errorf("column not found")
+	}
+	board, err := s.boardRepo.FindByID(ctx, column.BoardID)
+	if err != nil {
+		return nil, fmt.Errorf("board not found for column")
+	}
+	proj, err := s.projectRepo.FindByID(ctx, board.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeView(ctx, viewerID, proj.WorkspaceID); err != nil {
+		return nil, err
+	}
+
 	tasks, err := s.taskRepo.ListByColumn(ctx, columnID)
 	if err != nil {
 		return nil, err
 	}
+	loc := s.viewerLocation(ctx, viewerID)
 	result := make([]TaskDTO, 0, len(tasks))
 	for i := range tasks {
-		result = append(result, *mapTaskToDTO(&tasks[i]))
+		result = append(result, *mapTaskToDTO(&tasks[i], loc))
 	}
 	return result, nil
 }
 
+// viewerLocation is the timezone task dates are rendered in for the caller.
+// Rendering falls back to UTC rather than failing the request.
+func (s *taskService) viewerLocation(ctx context.Context, viewerID uuid.UUID) *time.Location {
+	loc, err := s.locations.Location(ctx, viewerID)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
 func (s *taskService) UpdateAssignees(ctx context.Context, actorID uuid.UUID, taskID uuid.UUID, req UpdateTaskAssigneesRequest) ([]TaskAssigneeDTO, error) {
 	task, err := s.taskRepo.FindByID(ctx, taskID)
 	if err != nil {
@@ -278,7 +328,11 @@ func (s *taskService) AddComment(ctx context.Context, req CreateTaskCommentReque
 	}, nil
 }
 
-func (s *taskService) ListComments(ctx context.Context, taskID uuid.UUID) ([]TaskCommentDTO, error) {
+func (s *taskService) ListComments(ctx context.Context, viewerID uuid.UUID, taskID uuid.UUID) ([]TaskCommentDTO, error) {
+	if err := s.authorizeTaskView(ctx, viewerID, taskID); err != nil {
+		return nil, err
+	}
+
 	comments, err := s.commentRepo.ListByTask(ctx, taskID)
 	if err != nil {
 		return nil, err
@@ -310,21 +364,45 @@ func (s *taskService) AddAttachment(ctx context.Context, req CreateAttachmentReq
 		return nil, fmt.Errorf("permission denied")
 	}
 
+	limits, err := s.plans.PlanLimits(ctx, task.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
 	attachment := &models.Attachment{
 		TaskID:     req.TaskID,
 		UploadedBy: uploadedBy,
 		FileName:   req.FileName,
 		FileURL:    req.FileURL,
-		FileSize:   req.FileSize,
+		FileSize:   &req.FileSize,
 		MimeType:   req.MimeType,
 	}
-	if err := s.attachmentRepo.Create(ctx, attachment); err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txAttachmentRepo := NewAttachmentRepository(tx)
+		// The lock keeps concurrent uploads from sharing the last bytes.
+		if err := project.NewProjectRepository(tx).LockWorkspace(ctx, task.WorkspaceID); err != nil {
+			return err
+		}
+		used, err := txAttachmentRepo.SumSizeByWorkspace(ctx, task.WorkspaceID)
+		if err != nil {
+			return err
+		}
+		if err := plan.Check(plan.ResourceStorage, limits.StorageBytes, used, req.FileSize); err != nil {
+			return err
+		}
+		return txAttachmentRepo.Create(ctx, attachment)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return mapAttachmentToDTO(attachment), nil
 }
 
-func (s *taskService) ListAttachments(ctx context.Context, taskID uuid.UUID) ([]AttachmentDTO, error) {
+func (s *taskService) ListAttachments(ctx context.Context, viewerID uuid.UUID, taskID uuid.UUID) ([]AttachmentDTO, error) {
+	if err := s.authorizeTaskView(ctx, viewerID, taskID); err != nil {
+		return nil, err
+	}
+
 	attachments, err := s.attachmentRepo.ListByTask(ctx, taskID)
 	if err != nil {
 		return nil, err
@@ -336,7 +414,27 @@ func (s *taskService) ListAttachments(ctx context.Context, taskID uuid.UUID) ([]
 	return result, nil
 }
 
-func mapTaskToDTO(task *models.Task) *TaskDTO {
+// authorizeTaskView lets any member of the task's workspace read it.
+func (s *taskService) authorizeTaskView(ctx context.Context, viewerID, taskID uuid.UUID) error {
+	task, err := s.taskRepo.FindByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	return s.authorizeView(ctx, viewerID, task.WorkspaceID)
+}
+
+func (s *taskService) authorizeView(ctx context.Context, viewerID, workspaceID uuid.UUID) error {
+	allowed, err := s.permissionService.CanViewWorkspace(ctx, viewerID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return project.ErrPermissionDenied
+	}
+	return nil
+}
+
+func mapTaskToDTO(task *models.Task, loc *time.Location) *TaskDTO {
 	return &TaskDTO{
 		ID:          task.ID,
 		WorkspaceID: task.WorkspaceID,
@@ -346,13 +444,23 @@ func mapTaskToDTO(task *models.Task) *TaskDTO {
 		Description: task.Description,
 		Position:    task.Position,
 		Priority:    task.Priority,
+		// A due date is a calendar day, not an instant; shifting it into
+		// the viewer's zone could move it to the previous day.
 		DueDate:     task.DueDate,
 		Status:      task.Status,
 		CreatedBy:   task.CreatedBy,
-		CompletedAt: task.CompletedAt,
-		CreatedAt:   task.CreatedAt,
-		UpdatedAt:   task.UpdatedAt,
+		CompletedAt: inLocation(task.CompletedAt, loc),
+		CreatedAt:   task.CreatedAt.In(loc),
+		UpdatedAt:   task.UpdatedAt.In(loc),
+	}
+}
+
+func inLocation(t *time.Time, loc *time.Location) *time.Time {
+	if t == nil {
+		return nil
 	}
+	local := t.In(loc)
+	return &local
 }
 
 func mapAttachmentToDTO(a *models.Attachment) *AttachmentDTO {
@@ -0,0 +1,64 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"kerjakuy/internal/auth"
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/rbac"
+	"kerjakuy/internal/project"
+
+	"github.com/google/uuid"
+)
+
+func TestMapTaskToDTOKeepsDueDate(t *testing.T) {
+	due := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	completed := time.Date(2026, 3, 1, 1, 0, 0, 0, time.UTC)
+	task := &models.Task{DueDate: &due, CompletedAt: &completed}
+
+	// UTC-5: the completion time moves to the previous day, the due date
+	// must not.
+	loc := time.FixedZone("EST", -5*60*60)
+	dto := mapTaskToDTO(task, loc)
+
+	if !dto.DueDate.Equal(due) || dto.DueDate.Day() != 1 || dto.DueDate.Location() != time.UTC {
+		t.Errorf("DueDate = %v, want %v unchanged", dto.DueDate, due)
+	}
+	if dto.CompletedAt.Location() != loc || dto.CompletedAt.Day() != 28 {
+		t.Errorf("CompletedAt = %v, want it in the viewer's zone", dto.CompletedAt)
+	}
+}
+
+func TestWorkspacePendingDeletionStaysReadable(t *testing.T) {
+	ctx := context.Background()
+	memberID := uuid.New()
+	scheduled := time.Now().Add(7 * 24 * time.Hour)
+	ws := &models.Workspace{ID: uuid.New(), DeletionScheduledAt: &scheduled}
+	permissions := auth.NewPermissionService(&fakeMembers{roles: map[uuid.UUID]string{memberID: string(rbac.RoleMember)}}, &fakeWorkspaces{workspace: ws}, noTwoFactor{}, noTwoFactor{})
+
+	proj := &models.Project{ID: uuid.New(), WorkspaceID: ws.ID, Name: "Peluncuran"}
+	board := &models.Board{ID: uuid.New(), ProjectID: proj.ID, Name: "Sprint 1"}
+	column := &models.Column{ID: uuid.New(), BoardID: board.ID, Name: "To Do"}
+	tasks := &fakeTasks{rows: []models.Task{{ID: uuid.New(), WorkspaceID: ws.ID, ProjectID: proj.ID, ColumnID: &column.ID, Title: "Siapkan rilis"}}}
+	projects, boards, columns := &fakeProjects{proj: proj}, &fakeBoards{board: board}, &fakeColumns{column: column}
+
+	projectSvc := project.NewProjectService(nil, projects, boards, columns, permissions, nil)
+	listed, err := projectSvc.ListWorkspaceProjects(ctx, memberID, ws.ID)
+	if err != nil || len(listed) != 1 {
+		t.Errorf("ListWorkspaceProjects = %d projects, %v; want the project", len(listed), err)
+	}
+
+	taskSvc := NewService(nil, tasks, nil, nil, nil, projects, boards, columns, permissions, utcLocations{}, nil)
+	columnTasks, err := taskSvc.ListTasksByColumn(ctx, memberID, column.ID)
+	if err != nil || len(columnTasks) != 1 {
+		t.Errorf("ListTasksByColumn = %d tasks, %v; want the task", len(columnTasks), err)
+	}
+
+	_, err = taskSvc.CreateTask(ctx, CreateTaskRequest{WorkspaceID: ws.ID, ColumnID: &column.ID, Title: "Tugas baru"}, memberID)
+	if !errors.Is(err, auth.ErrWorkspacePendingDeletion) {
+		t.Errorf("CreateTask error = %v, want %v", err, auth.ErrWorkspacePendingDeletion)
+	}
+}
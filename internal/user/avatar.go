@@ -0,0 +1,171 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/imaging"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxAvatarBytes caps the size of an uploaded avatar file.
+	MaxAvatarBytes = 5 << 20
+	// DefaultAvatarSize is the variant AvatarURL points at.
+	DefaultAvatarSize = 256
+
+	identiconFile = "identicon.png"
+)
+
+// AvatarSizes are the square variants stored for every upload.
+var AvatarSizes = []int{256, 64, 32}
+
+var (
+	ErrAvatarTooLarge  = errors.New("ukuran avatar maksimal 5 MB")
+	ErrAvatarNotFound  = errors.New("avatar tidak ditemukan")
+	ErrAvatarSizeValue = errors.New("ukuran avatar harus 32, 64, atau 256")
+)
+
+var avatarFilePattern = regexp.MustCompile(`^[0-9a-f]{16}\.(jpg|png)$`)
+
+// Avatar is a rendered avatar ready to be served.
+type Avatar struct {
+	Body        io.ReadCloser
+	ContentType string
+	// Immutable is true for uploaded versions, whose URL changes with
+	// every upload.
+	Immutable bool
+}
+
+// UploadAvatar re-encodes the image into every AvatarSizes variant, which
+// drops EXIF and any other embedded metadata, and points AvatarURL at it.
+func (s *userService) UploadAvatar(ctx context.Context, id uuid.UUID, data []byte) (*UserDTO, error) {
+	if len(data) > MaxAvatarBytes {
+		return nil, ErrAvatarTooLarge
+	}
+	user, err := s.userRepo.FindByID(ctx, id.String())
+	if err != nil {
+		return nil, err
+	}
+	img, err := imaging.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	version := make([]byte, 8)
+	if _, err := rand.Read(version); err != nil {
+		return nil, err
+	}
+	largest := img.Square(AvatarSizes[0])
+	format, ext := imaging.FormatPNG, "png"
+	if largest.Opaque() {
+		format, ext = imaging.FormatJPEG, "jpg"
+	}
+	key := hex.EncodeToString(version) + "." + ext
+
+	for _, size := range AvatarSizes {
+		variant := largest
+		if size != AvatarSizes[0] {
+			variant = imaging.Resize(largest, size)
+		}
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, variant, format); err != nil {
+			return nil, err
+		}
+		if err := s.avatars.Put(ctx, avatarObjectKey(id, key, size), buf.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	previous := user.AvatarKey
+	url := s.avatarURL(id, key)
+	user.AvatarURL, user.AvatarKey = &url, &key
+	if err := s.userRepo.UpdateAvatar(ctx, id.String(), user.AvatarURL, user.AvatarKey); err != nil {
+		s.deleteAvatarObjects(ctx, id, &key)
+		return nil, err
+	}
+	s.deleteAvatarObjects(ctx, id, previous)
+	return s.ToDTO(user), nil
+}
+
+// RemoveAvatar clears the avatar, uploaded or external, so the identicon is
+// shown instead.
+func (s *userService) RemoveAvatar(ctx context.Context, id uuid.UUID) (*UserDTO, error) {
+	user, err := s.userRepo.FindByID(ctx, id.String())
+	if err != nil {
+		return nil, err
+	}
+	previous := user.AvatarKey
+	user.AvatarURL, user.AvatarKey = nil, nil
+	if err := s.userRepo.UpdateAvatar(ctx, id.String(), nil, nil); err != nil {
+		return nil, err
+	}
+	s.deleteAvatarObjects(ctx, id, previous)
+	return s.ToDTO(user), nil
+}
+
+// OpenAvatar returns an uploaded avatar variant, or the user's identicon
+// when file is "identicon.png".
+func (s *userService) OpenAvatar(ctx context.Context, id uuid.UUID, file string, size int) (*Avatar, error) {
+	if !slices.Contains(AvatarSizes, size) {
+		return nil, ErrAvatarSizeValue
+	}
+	if file == identiconFile {
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, imaging.Identicon(id[:], size), imaging.FormatPNG); err != nil {
+			return nil, err
+		}
+		return &Avatar{Body: io.NopCloser(&buf), ContentType: "image/png"}, nil
+	}
+	if !avatarFilePattern.MatchString(file) {
+		return nil, ErrAvatarNotFound
+	}
+	body, err := s.avatars.Open(ctx, avatarObjectKey(id, file, size))
+	if err != nil {
+		return nil, ErrAvatarNotFound
+	}
+	contentType := "image/jpeg"
+	if file[len(file)-3:] == "png" {
+		contentType = "image/png"
+	}
+	return &Avatar{Body: body, ContentType: contentType, Immutable: true}, nil
+}
+
+// deleteAvatarObjects removes a replaced upload. Failures only leave
+// unreferenced files behind, so they do not fail the request.
+func (s *userService) deleteAvatarObjects(ctx context.Context, id uuid.UUID, key *string) {
+	if key == nil {
+		return
+	}
+	for _, size := range AvatarSizes {
+		_ = s.avatars.Delete(ctx, avatarObjectKey(id, *key, size))
+	}
+}
+
+func (s *userService) avatarURL(id uuid.UUID, file string) string {
+	return fmt.Sprintf("%s/%s/%s", s.avatarBaseURL, id, file)
+}
+
+// displayAvatarURL falls back to the identicon for users without an avatar.
+func (s *userService) displayAvatarURL(user *models.User) *string {
+	if user.AvatarURL != nil {
+		return user.AvatarURL
+	}
+	url := s.avatarURL(user.ID, identiconFile)
+	return &url
+}
+
+// avatarObjectKey maps "<version>.<ext>" to the stored object of one size.
+func avatarObjectKey(id uuid.UUID, file string, size int) string {
+	version, ext := file[:len(file)-4], file[len(file)-3:]
+	return fmt.Sprintf("avatars/%s/%s-%d.%s", id, version, size, ext)
+}
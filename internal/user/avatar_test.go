@@ -0,0 +1,175 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/imaging"
+	"kerjakuy/internal/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+type fakeAvatarUsers struct {
+	UserRepository
+	user *models.User
+}
+
+func (f *fakeAvatarUsers) FindByID(ctx context.Context, id string) (*models.User, error) {
+	u := *f.user
+	return &u, nil
+}
+
+func (f *fakeAvatarUsers) UpdateAvatar(ctx context.Context, id string, url *string, key *string) error {
+	f.user.AvatarURL, f.user.AvatarKey = url, key
+	return nil
+}
+
+func newAvatarService(t *testing.T) (*userService, *fakeAvatarUsers, string) {
+	dir := t.TempDir()
+	users := &fakeAvatarUsers{user: &models.User{ID: uuid.New(), Name: "Ana", Email: "ana@example.com"}}
+	svc := NewUserService(users, storage.NewLocalStore(dir), "https://app.example.com/api/v1/avatars").(*userService)
+	return svc, users, dir
+}
+
+// jpegWithExif is a camera-style JPEG: an APP1 segment carrying EXIF data
+// (orientation 6 and a fake GPS note) right after SOI.
+func jpegWithExif(t *testing.T) []byte {
+	t.Helper()
+	var src bytes.Buffer
+	if err := jpeg.Encode(&src, image.NewNRGBA(image.Rect(0, 0, 300, 200)), nil); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	payload := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x06\x00\x00\x00\x00\x00\x00GPS -6.2,106.8")
+	segment := binary.BigEndian.AppendUint16([]byte{0xFF, 0xE1}, uint16(len(payload)+2))
+	data := append([]byte{0xFF, 0xD8}, append(segment, payload...)...)
+	return append(data, src.Bytes()[2:]...)
+}
+
+func transparentPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 40))
+	img.SetNRGBA(20, 20, color.NRGBA{R: 0xFF, A: 0xFF})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadAvatar(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		err         error
+		ext         string
+		contentType string
+	}{
+		{name: "opaque jpeg is stored as jpeg without exif", data: jpegWithExif(t), ext: ".jpg", contentType: "image/jpeg"},
+		{name: "transparent png stays png", data: transparentPNG(t), ext: ".png", contentType: "image/png"},
+		{name: "file over the size limit", data: append([]byte{0xFF, 0xD8, 0xFF}, make([]byte, MaxAvatarBytes)...), err: ErrAvatarTooLarge},
+		{name: "svg pretending to be an image", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script/></svg>`), err: imaging.ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, users, dir := newAvatarService(t)
+			ctx := context.Background()
+			id := users.user.ID
+
+			dto, err := svc.UploadAvatar(ctx, id, tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("UploadAvatar error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				if users.user.AvatarKey != nil {
+					t.Errorf("rejected upload changed the avatar")
+				}
+				return
+			}
+			key := *users.user.AvatarKey
+			if !strings.HasSuffix(key, tt.ext) || *dto.AvatarURL != "https://app.example.com/api/v1/avatars/"+id.String()+"/"+key {
+				t.Fatalf("avatar key %q, url %q", key, *dto.AvatarURL)
+			}
+
+			for _, size := range AvatarSizes {
+				avatar, err := svc.OpenAvatar(ctx, id, key, size)
+				if err != nil {
+					t.Fatalf("OpenAvatar(%d): %v", size, err)
+				}
+				body, _ := io.ReadAll(avatar.Body)
+				avatar.Body.Close()
+				if avatar.ContentType != tt.contentType || !avatar.Immutable {
+					t.Errorf("size %d served as %q, immutable=%v", size, avatar.ContentType, avatar.Immutable)
+				}
+				cfg, _, err := image.DecodeConfig(bytes.NewReader(body))
+				if err != nil || cfg.Width != size || cfg.Height != size {
+					t.Errorf("size %d variant is %dx%d (%v)", size, cfg.Width, cfg.Height, err)
+				}
+				if bytes.Contains(body, []byte("Exif")) || bytes.Contains(body, []byte("GPS")) {
+					t.Errorf("size %d variant still carries metadata", size)
+				}
+			}
+
+			// A new upload replaces the files of the previous one.
+			if _, err := svc.UploadAvatar(ctx, id, tt.data); err != nil {
+				t.Fatalf("second UploadAvatar: %v", err)
+			}
+			files, _ := os.ReadDir(filepath.Join(dir, "avatars", id.String()))
+			if len(files) != len(AvatarSizes) {
+				t.Errorf("%d files stored after replacing the avatar, want %d", len(files), len(AvatarSizes))
+			}
+			if _, err := svc.OpenAvatar(ctx, id, key, DefaultAvatarSize); !errors.Is(err, ErrAvatarNotFound) {
+				t.Errorf("replaced avatar error = %v, want %v", err, ErrAvatarNotFound)
+			}
+		})
+	}
+}
+
+func TestOpenAvatar(t *testing.T) {
+	svc, users, _ := newAvatarService(t)
+	id := users.user.ID
+	tests := []struct {
+		name        string
+		file        string
+		size        int
+		err         error
+		contentType string
+	}{
+		{name: "identicon", file: "identicon.png", size: 64, contentType: "image/png"},
+		{name: "unsupported size", file: "identicon.png", size: 128, err: ErrAvatarSizeValue},
+		{name: "path traversal", file: "../../etc/passwd", size: 64, err: ErrAvatarNotFound},
+		{name: "unknown extension", file: "0123456789abcdef.gif", size: 64, err: ErrAvatarNotFound},
+		{name: "missing upload", file: "0123456789abcdef.jpg", size: 64, err: ErrAvatarNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			avatar, err := svc.OpenAvatar(context.Background(), id, tt.file, tt.size)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("OpenAvatar error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			defer avatar.Body.Close()
+			if avatar.ContentType != tt.contentType || avatar.Immutable {
+				t.Errorf("served as %q, immutable=%v", avatar.ContentType, avatar.Immutable)
+			}
+			cfg, err := png.DecodeConfig(avatar.Body)
+			if err != nil || cfg.Width != tt.size {
+				t.Errorf("identicon is %d wide (%v), want %d", cfg.Width, err, tt.size)
+			}
+		})
+	}
+}
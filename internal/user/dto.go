@@ -7,12 +7,18 @@ import (
 )
 
 type UserDTO struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	AvatarURL *string   `json:"avatar_url,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                  uuid.UUID              `json:"id"`
+	Name                string                 `json:"name"`
+	Email               string                 `json:"email"`
+	AvatarURL           *string                `json:"avatar_url,omitempty"`
+	Timezone            string                 `json:"timezone"`
+	Locale              string                 `json:"locale"`
+	WorkingHours        *WorkingHours          `json:"working_hours,omitempty"`
+	Preferences         map[string]interface{} `json:"preferences"`
+	EmailVerifiedAt     *time.Time             `json:"email_verified_at,omitempty"`
+	DeletionScheduledAt *time.Time             `json:"deletion_scheduled_at,omitempty"`
+	CreatedAt           time.Time              `json:"created_at"`
+	UpdatedAt           time.Time              `json:"updated_at"`
 }
 
 type CreateUserRequest struct {
@@ -21,7 +27,48 @@ type CreateUserRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 }
 
+// DirectoryQuery searches the people who share at least one workspace
+// with the caller. Q matches the start of the email or of any word in the
+// name; WorkspaceID narrows the search to one shared workspace.
+type DirectoryQuery struct {
+	Q           string `form:"q" binding:"max=100"`
+	WorkspaceID string `form:"workspace_id" binding:"omitempty,uuid"`
+	Limit       int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset      int    `form:"offset" binding:"omitempty,min=0"`
+}
+
+// DirectoryEntryDTO is the part of a profile other users may see.
+type DirectoryEntryDTO struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	AvatarURL *string   `json:"avatar_url,omitempty"`
+}
+
+type DirectoryPageDTO struct {
+	Users   []DirectoryEntryDTO `json:"users"`
+	Limit   int                 `json:"limit"`
+	Offset  int                 `json:"offset"`
+	HasMore bool                `json:"has_more"`
+}
+
+// WorkingHours is a daily window in the user's timezone, as "HH:MM". An end
+// before the start wraps past midnight.
+type WorkingHours struct {
+	Start string `json:"start" binding:"required"`
+	End   string `json:"end" binding:"required"`
+}
+
 type UpdateUserProfileRequest struct {
-	Name      *string `json:"name,omitempty" binding:"omitempty,min=2,max=100"`
-	AvatarURL *string `json:"avatar_url,omitempty"`
+	Name *string `json:"name,omitempty" binding:"omitempty,min=2,max=100"`
+	// AvatarURL points at an externally hosted https image; "" removes it.
+	AvatarURL *string `json:"avatar_url,omitempty" binding:"omitempty,url,max=2048"`
+	Timezone  *string `json:"timezone,omitempty"`
+	Locale    *string `json:"locale,omitempty"`
+	// WorkingHours replaces the current window; ClearWorkingHours removes it.
+	WorkingHours      *WorkingHours `json:"working_hours,omitempty"`
+	ClearWorkingHours bool          `json:"clear_working_hours,omitempty"`
+	// Preferences are merged into the stored ones; a null value removes
+	// the key.
+	Preferences map[string]interface{} `json:"preferences,omitempty"`
 }
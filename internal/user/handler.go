@@ -0,0 +1,192 @@
+package user
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"kerjakuy/internal/pkg/imaging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CurrentUserFunc resolves the authenticated user of a request. The auth
+// package provides it; user cannot import auth directly.
+type CurrentUserFunc func(c *gin.Context) (uuid.UUID, bool)
+
+type UserHandler struct {
+	userService UserService
+	currentUser CurrentUserFunc
+}
+
+func NewUserHandler(userService UserService, currentUser CurrentUserFunc) *UserHandler {
+	return &UserHandler{userService: userService, currentUser: currentUser}
+}
+
+func (h *UserHandler) GetProfile(c *gin.Context) {
+	userID, ok := h.currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	profile, err := h.userService.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	var req UpdateUserProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := h.currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	profile, err := h.userService.UpdateProfile(c.Request.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidTimezone), errors.Is(err, ErrInvalidLocale), errors.Is(err, ErrInvalidWorkingHours), errors.Is(err, ErrPreferencesTooLarge), errors.Is(err, ErrInvalidAvatarURL):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *UserHandler) SearchDirectory(c *gin.Context) {
+	userID, ok := h.currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var query DirectoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.userService.SearchDirectory(c.Request.Context(), userID, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// UploadAvatar accepts a multipart form with the image in the "avatar"
+// field.
+func (h *UserHandler) UploadAvatar(c *gin.Context) {
+	userID, ok := h.currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Leave room for the multipart envelope around the file itself.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxAvatarBytes+64<<10)
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": ErrAvatarTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file avatar wajib diisi"})
+		return
+	}
+	if file.Size > MaxAvatarBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": ErrAvatarTooLarge.Error()})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, MaxAvatarBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.userService.UploadAvatar(c.Request.Context(), userID, data)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrAvatarTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, imaging.ErrUnsupportedFormat):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		case errors.Is(err, imaging.ErrInvalidImage), errors.Is(err, imaging.ErrImageTooLarge):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *UserHandler) RemoveAvatar(c *gin.Context) {
+	userID, ok := h.currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	profile, err := h.userService.RemoveAvatar(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// ServeAvatar is public so avatar URLs work in plain <img> tags. The size
+// query parameter selects the variant, 256 by default.
+func (h *UserHandler) ServeAvatar(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("userID"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrAvatarNotFound.Error()})
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(DefaultAvatarSize)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrAvatarSizeValue.Error()})
+		return
+	}
+
+	avatar, err := h.userService.OpenAvatar(c.Request.Context(), userID, c.Param("file"), size)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrAvatarSizeValue):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrAvatarNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	defer avatar.Body.Close()
+
+	if avatar.Immutable {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "public, max-age=86400")
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, -1, avatar.ContentType, avatar.Body, nil)
+}
@@ -0,0 +1,123 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	// Embedded zone data keeps timezone validation working on images
+	// without /usr/share/zoneinfo.
+	_ "time/tzdata"
+)
+
+const (
+	// DefaultTimezone is what the app used for every user before profiles
+	// carried a timezone.
+	DefaultTimezone = "Asia/Jakarta"
+	DefaultLocale   = "id-ID"
+
+	maxPreferencesBytes = 16 << 10
+	clockLayout         = "15:04"
+)
+
+var (
+	ErrInvalidTimezone     = errors.New("zona waktu tidak valid, gunakan nama IANA seperti Asia/Jakarta")
+	ErrInvalidLocale       = errors.New("locale tidak valid, gunakan format seperti id-ID")
+	ErrInvalidWorkingHours = errors.New("jam kerja tidak valid, gunakan format HH:MM dengan jam mulai dan selesai berbeda")
+	ErrPreferencesTooLarge = errors.New("preferensi terlalu besar")
+	ErrInvalidAvatarURL    = errors.New("avatar_url harus berupa URL https")
+)
+
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z]{4})?(-([A-Za-z]{2}|[0-9]{3}))?$`)
+
+func loadLocation(name string) *time.Location {
+	if loc, err := parseTimezone(name); err == nil {
+		return loc
+	}
+	if loc, err := time.LoadLocation(DefaultTimezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// validateAvatarURL only allows https links, so an avatar can neither be a
+// javascript: or data: URL nor downgrade the page to mixed content.
+func validateAvatarURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return ErrInvalidAvatarURL
+	}
+	return nil
+}
+
+// parseTimezone accepts IANA names only; "Local" would silently mean the
+// server's zone.
+func parseTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+// normalizeLocale validates a language[-Script][-REGION] tag and returns it
+// in canonical case, e.g. "en-us" becomes "en-US".
+func normalizeLocale(locale string) (string, error) {
+	locale = strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+	if !localePattern.MatchString(locale) {
+		return "", ErrInvalidLocale
+	}
+	parts := strings.Split(locale, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 4 {
+			parts[i] = strings.ToUpper(parts[i][:1]) + strings.ToLower(parts[i][1:])
+		} else {
+			parts[i] = strings.ToUpper(parts[i])
+		}
+	}
+	return strings.Join(parts, "-"), nil
+}
+
+func validateWorkingHours(wh WorkingHours) error {
+	start, err := time.Parse(clockLayout, wh.Start)
+	if err != nil {
+		return ErrInvalidWorkingHours
+	}
+	end, err := time.Parse(clockLayout, wh.End)
+	if err != nil {
+		return ErrInvalidWorkingHours
+	}
+	if start.Equal(end) {
+		return ErrInvalidWorkingHours
+	}
+	return nil
+}
+
+// mergePreferences applies patch onto current; null values delete keys.
+func mergePreferences(current, patch map[string]interface{}) (map[string]interface{}, error) {
+	merged := make(map[string]interface{}, len(current)+len(patch))
+	for k, v := range current {
+		merged[k] = v
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+	raw, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > maxPreferencesBytes {
+		return nil, ErrPreferencesTooLarge
+	}
+	return merged, nil
+}
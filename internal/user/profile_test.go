@@ -0,0 +1,23 @@
+package user
+
+import "testing"
+
+func TestValidateAvatarURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{url: "https://cdn.example.com/a.png", valid: true},
+		{url: "http://cdn.example.com/a.png"},
+		{url: "javascript:alert(1)"},
+		{url: "data:image/png;base64,AAAA"},
+		{url: "https:///a.png"},
+		{url: "//cdn.example.com/a.png"},
+	}
+	for _, tt := range tests {
+		err := validateAvatarURL(tt.url)
+		if (err == nil) != tt.valid {
+			t.Errorf("validateAvatarURL(%q) = %v, want valid=%v", tt.url, err, tt.valid)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package user
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"time"
 
 	"kerjakuy/internal/models"
 
@@ -13,7 +16,14 @@ type UserRepository interface {
 	FindByID(ctx context.Context, id string) (*models.User, error)
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
 	Update(ctx context.Context, user *models.User) error
-	List(ctx context.Context) ([]models.User, error)
+	UpdatePasswordHash(ctx context.Context, id string, hash string) error
+	UpdateAvatar(ctx context.Context, id string, url *string, key *string) error
+	MarkEmailVerified(ctx context.Context, id string, email string, at time.Time) error
+	UpdateEmail(ctx context.Context, id string, from string, to string, verifiedAt *time.Time) error
+	MarkVerificationSent(ctx context.Context, id string, at time.Time, notAfter time.Time) (bool, error)
+	FindByEmailFold(ctx context.Context, email string) (*models.User, error)
+	SearchDirectory(ctx context.Context, viewerID string, workspaceID string, prefix string, limit, offset int) ([]models.User, error)
+	FindSharedByEmail(ctx context.Context, viewerID string, email string) (*models.User, error)
 }
 
 type userRepository struct {
@@ -48,10 +58,128 @@ func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 	return r.db.WithContext(ctx).Save(user).Error
 }
 
-func (r *userRepository) List(ctx context.Context) ([]models.User, error) {
+func (r *userRepository) UpdateAvatar(ctx context.Context, id string, url *string, key *string) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"avatar_url": url,
+		"avatar_key": key,
+	}).Error
+}
+
+func (r *userRepository) UpdatePasswordHash(ctx context.Context, id string, hash string) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("password_hash", hash)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id string, email string, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND email = ?", id, email).
+		Update("email_verified_at", gorm.Expr("COALESCE(email_verified_at, ?)", at))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateEmail switches the login email only while the account still uses
+// from. The users.email unique index is the final arbiter when two accounts
+// race for the same address; that surfaces as ErrEmailTaken.
+func (r *userRepository) UpdateEmail(ctx context.Context, id string, from string, to string, verifiedAt *time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND email = ?", id, from).
+		Updates(map[string]interface{}{
+			"email":                      to,
+			"email_verified_at":          verifiedAt,
+			"email_verification_sent_at": nil,
+		})
+	if result.Error != nil {
+		if translator, ok := r.db.Dialector.(gorm.ErrorTranslator); ok && errors.Is(translator.Translate(result.Error), gorm.ErrDuplicatedKey) {
+			return ErrEmailTaken
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) MarkVerificationSent(ctx context.Context, id string, at time.Time, notAfter time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND (email_verification_sent_at IS NULL OR email_verification_sent_at <= ?)", id, notAfter).
+		Update("email_verification_sent_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// FindByEmailFold matches the email case-insensitively and skips
+// anonymized accounts.
+func (r *userRepository) FindByEmailFold(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).
+		Where("lower(email) = lower(?) AND anonymized_at IS NULL", email).
+		First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SearchDirectory lists users sharing a workspace with viewerID (only
+// workspaceID when set), ordered by name. prefix must already be lower
+// case.
+func (r *userRepository) SearchDirectory(ctx context.Context, viewerID string, workspaceID string, prefix string, limit, offset int) ([]models.User, error) {
+	shared := r.sharesWorkspace(viewerID)
+	if workspaceID != "" {
+		shared = shared.Where("mine.workspace_id = ?", workspaceID)
+	}
+
+	query := r.db.WithContext(ctx).
+		Where("anonymized_at IS NULL").
+		Where("EXISTS (?)", shared)
+	if prefix != "" {
+		like := escapeLike(prefix) + "%"
+		query = query.Where("lower(email) LIKE ? OR lower(name) LIKE ? OR lower(name) LIKE ?", like, like, "% "+like)
+	}
+
 	var users []models.User
-	if err := r.db.WithContext(ctx).Find(&users).Error; err != nil {
+	if err := query.Order("lower(name), id").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
 		return nil, err
 	}
 	return users, nil
 }
+
+// FindSharedByEmail finds the account with email, ignoring case, only if
+// it shares a workspace with viewerID.
+func (r *userRepository) FindSharedByEmail(ctx context.Context, viewerID string, email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).
+		Where("lower(email) = lower(?) AND anonymized_at IS NULL", email).
+		Where("EXISTS (?)", r.sharesWorkspace(viewerID)).
+		First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// sharesWorkspace is an EXISTS subquery matching users rows that are in a
+// workspace together with viewerID.
+func (r *userRepository) sharesWorkspace(viewerID string) *gorm.DB {
+	return r.db.Table("workspace_members AS mine").
+		Select("1").
+		Joins("JOIN workspace_members AS theirs ON theirs.workspace_id = mine.workspace_id").
+		Where("mine.user_id = ? AND theirs.user_id = users.id", viewerID)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -3,11 +3,15 @@ package user
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 
 	"github.com/google/uuid"
 	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/storage"
 )
 
 type UserService interface {
@@ -16,15 +20,39 @@ type UserService interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*UserDTO, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	UpdateProfile(ctx context.Context, id uuid.UUID, req UpdateUserProfileRequest) (*UserDTO, error)
-	List(ctx context.Context) ([]UserDTO, error)
+	UpdatePassword(ctx context.Context, id uuid.UUID, password string) error
+	ClearPassword(ctx context.Context, id uuid.UUID) error
+	MarkEmailVerified(ctx context.Context, id uuid.UUID, email string, at time.Time) error
+	EmailInUse(ctx context.Context, email string) (bool, error)
+	ReserveVerificationEmail(ctx context.Context, id uuid.UUID, now time.Time, interval time.Duration) (bool, error)
+	IsEmailVerified(ctx context.Context, id uuid.UUID) (bool, error)
+	IsPlatformAdmin(ctx context.Context, id uuid.UUID) (bool, error)
+	Location(ctx context.Context, id uuid.UUID) (*time.Location, error)
+	UploadAvatar(ctx context.Context, id uuid.UUID, data []byte) (*UserDTO, error)
+	RemoveAvatar(ctx context.Context, id uuid.UUID) (*UserDTO, error)
+	OpenAvatar(ctx context.Context, id uuid.UUID, file string, size int) (*Avatar, error)
+	ToDTO(user *models.User) *UserDTO
+	SearchDirectory(ctx context.Context, viewerID uuid.UUID, query DirectoryQuery) (*DirectoryPageDTO, error)
+	LookupByEmail(ctx context.Context, viewerID uuid.UUID, email string) (*DirectoryEntryDTO, error)
 }
 
+const directoryDefaultLimit = 20
+
+var (
+	ErrUserNotFound = errors.New("pengguna tidak ditemukan")
+	ErrEmailTaken   = errors.New("email sudah digunakan akun lain")
+)
+
 type userService struct {
-	userRepo UserRepository
+	userRepo      UserRepository
+	avatars       storage.Store
+	avatarBaseURL string
 }
 
-func NewUserService(userRepo UserRepository) UserService {
-	return &userService{userRepo: userRepo}
+// NewUserService stores uploaded avatars in avatars; avatarBaseURL is the
+// public address of the avatar route, without a trailing slash.
+func NewUserService(userRepo UserRepository, avatars storage.Store, avatarBaseURL string) UserService {
+	return &userService{userRepo: userRepo, avatars: avatars, avatarBaseURL: avatarBaseURL}
 }
 
 func (s *userService) Register(ctx context.Context, req CreateUserRequest, hashedPassword string) (*UserDTO, error) {
@@ -32,11 +60,13 @@ func (s *userService) Register(ctx context.Context, req CreateUserRequest, hashe
 		Name:         req.Name,
 		Email:        req.Email,
 		PasswordHash: hashedPassword,
+		Timezone:     DefaultTimezone,
+		Locale:       DefaultLocale,
 	}
 	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, err
 	}
-	return mapUserToDTO(user), nil
+	return s.ToDTO(user), nil
 }
 
 func (s *userService) CreateWithPassword(ctx context.Context, req CreateUserRequest) (*UserDTO, error) {
@@ -52,11 +82,13 @@ func (s *userService) GetByID(ctx context.Context, id uuid.UUID) (*UserDTO, erro
 	if err != nil {
 		return nil, err
 	}
-	return mapUserToDTO(user), nil
+	return s.ToDTO(user), nil
 }
 
+// GetByEmail ignores case and surrounding spaces, like the unique email
+// check, so a login matches however the address was typed.
 func (s *userService) GetByEmail(ctx context.Context, email string) (*models.User, error) {
-	return s.userRepo.FindByEmail(ctx, email)
+	return s.userRepo.FindByEmailFold(ctx, strings.TrimSpace(email))
 }
 
 func (s *userService) UpdateProfile(ctx context.Context, id uuid.UUID, req UpdateUserProfileRequest) (*UserDTO, error) {
@@ -71,35 +103,197 @@ func (s *userService) UpdateProfile(ctx context.Context, id uuid.UUID, req Updat
 		}
 		user.Name = *req.Name
 	}
+	var replacedAvatar *string
 	if req.AvatarURL != nil {
+		if *req.AvatarURL != "" {
+			if err := validateAvatarURL(*req.AvatarURL); err != nil {
+				return nil, err
+			}
+		}
+		replacedAvatar, user.AvatarKey = user.AvatarKey, nil
 		user.AvatarURL = req.AvatarURL
+		if *req.AvatarURL == "" {
+			user.AvatarURL = nil
+		}
+	}
+	if req.Timezone != nil {
+		if _, err := parseTimezone(*req.Timezone); err != nil {
+			return nil, err
+		}
+		user.Timezone = *req.Timezone
+	}
+	if req.Locale != nil {
+		locale, err := normalizeLocale(*req.Locale)
+		if err != nil {
+			return nil, err
+		}
+		user.Locale = locale
+	}
+	if req.ClearWorkingHours {
+		user.WorkingHoursStart, user.WorkingHoursEnd = nil, nil
+	} else if req.WorkingHours != nil {
+		if err := validateWorkingHours(*req.WorkingHours); err != nil {
+			return nil, err
+		}
+		start, end := req.WorkingHours.Start, req.WorkingHours.End
+		user.WorkingHoursStart, user.WorkingHoursEnd = &start, &end
+	}
+	if req.Preferences != nil {
+		merged, err := mergePreferences(user.Preferences, req.Preferences)
+		if err != nil {
+			return nil, err
+		}
+		user.Preferences = merged
 	}
 
 	if err := s.userRepo.Update(ctx, user); err != nil {
 		return nil, err
 	}
-	return mapUserToDTO(user), nil
+	s.deleteAvatarObjects(ctx, id, replacedAvatar)
+	return s.ToDTO(user), nil
+}
+
+func (s *userService) UpdatePassword(ctx context.Context, id uuid.UUID, password string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return s.userRepo.UpdatePasswordHash(ctx, id.String(), string(hashed))
+}
+
+// ClearPassword removes the local password so the account can only sign in
+// through a linked identity or a password reset.
+func (s *userService) ClearPassword(ctx context.Context, id uuid.UUID) error {
+	return s.userRepo.UpdatePasswordHash(ctx, id.String(), "")
+}
+
+// MarkEmailVerified only succeeds while the account still uses email, so a
+// link issued before an address change cannot verify the new address.
+func (s *userService) MarkEmailVerified(ctx context.Context, id uuid.UUID, email string, at time.Time) error {
+	return s.userRepo.MarkEmailVerified(ctx, id.String(), email, at)
+}
+
+// EmailInUse reports whether any account already uses email, ignoring
+// case.
+func (s *userService) EmailInUse(ctx context.Context, email string) (bool, error) {
+	_, err := s.userRepo.FindByEmailFold(ctx, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ReserveVerificationEmail records that a verification email is about to be
+// sent. It reports false when the previous one went out less than interval
+// ago.
+func (s *userService) ReserveVerificationEmail(ctx context.Context, id uuid.UUID, now time.Time, interval time.Duration) (bool, error) {
+	return s.userRepo.MarkVerificationSent(ctx, id.String(), now, now.Add(-interval))
+}
+
+func (s *userService) IsEmailVerified(ctx context.Context, id uuid.UUID) (bool, error) {
+	user, err := s.userRepo.FindByID(ctx, id.String())
+	if err != nil {
+		return false, err
+	}
+	return user.EmailVerifiedAt != nil, nil
+}
+
+func (s *userService) IsPlatformAdmin(ctx context.Context, id uuid.UUID) (bool, error) {
+	user, err := s.userRepo.FindByID(ctx, id.String())
+	if err != nil {
+		return false, err
+	}
+	return user.IsPlatformAdmin, nil
+}
+
+// Location returns the timezone dates should be rendered in for the user.
+func (s *userService) Location(ctx context.Context, id uuid.UUID) (*time.Location, error) {
+	user, err := s.userRepo.FindByID(ctx, id.String())
+	if err != nil {
+		return nil, err
+	}
+	return loadLocation(user.Timezone), nil
 }
 
-func (s *userService) List(ctx context.Context) ([]UserDTO, error) {
-	users, err := s.userRepo.List(ctx)
+// SearchDirectory only ever returns people the viewer already shares a
+// workspace with, so it cannot be used to enumerate other tenants.
+func (s *userService) SearchDirectory(ctx context.Context, viewerID uuid.UUID, query DirectoryQuery) (*DirectoryPageDTO, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = directoryDefaultLimit
+	}
+	prefix := strings.ToLower(strings.TrimSpace(query.Q))
+
+	// One extra row tells whether another page exists.
+	users, err := s.userRepo.SearchDirectory(ctx, viewerID.String(), query.WorkspaceID, prefix, limit+1, query.Offset)
 	if err != nil {
 		return nil, err
 	}
-	result := make([]UserDTO, 0, len(users))
-	for i := range users {
-		result = append(result, *mapUserToDTO(&users[i]))
+	page := &DirectoryPageDTO{
+		Users:   make([]DirectoryEntryDTO, 0, min(len(users), limit)),
+		Limit:   limit,
+		Offset:  query.Offset,
+		HasMore: len(users) > limit,
+	}
+	for i := range users[:min(len(users), limit)] {
+		page.Users = append(page.Users, *s.toDirectoryEntry(&users[i]))
 	}
-	return result, nil
+	return page, nil
 }
 
-func mapUserToDTO(user *models.User) *UserDTO {
-	return &UserDTO{
+// LookupByEmail finds exactly one account by email, for resolving an
+// invitee. Like the directory it only sees people who already share a
+// workspace with viewerID; anyone else is ErrUserNotFound, same as an
+// address with no account, so it cannot tell whether an email is
+// registered.
+func (s *userService) LookupByEmail(ctx context.Context, viewerID uuid.UUID, email string) (*DirectoryEntryDTO, error) {
+	user, err := s.userRepo.FindSharedByEmail(ctx, viewerID.String(), strings.TrimSpace(email))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return s.toDirectoryEntry(user), nil
+}
+
+func (s *userService) toDirectoryEntry(user *models.User) *DirectoryEntryDTO {
+	return &DirectoryEntryDTO{
 		ID:        user.ID,
 		Name:      user.Name,
 		Email:     user.Email,
-		AvatarURL: user.AvatarURL,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		AvatarURL: s.displayAvatarURL(user),
+	}
+}
+
+// ToDTO converts a user row to its public representation.
+func (s *userService) ToDTO(user *models.User) *UserDTO {
+	return &UserDTO{
+		ID:                  user.ID,
+		Name:                user.Name,
+		Email:               user.Email,
+		AvatarURL:           s.displayAvatarURL(user),
+		Timezone:            user.Timezone,
+		Locale:              user.Locale,
+		WorkingHours:        mapWorkingHours(user),
+		Preferences:         mapPreferences(user.Preferences),
+		EmailVerifiedAt:     user.EmailVerifiedAt,
+		DeletionScheduledAt: user.DeletionScheduledAt,
+		CreatedAt:           user.CreatedAt,
+		UpdatedAt:           user.UpdatedAt,
+	}
+}
+
+func mapWorkingHours(user *models.User) *WorkingHours {
+	if user.WorkingHoursStart == nil || user.WorkingHoursEnd == nil {
+		return nil
+	}
+	return &WorkingHours{Start: *user.WorkingHoursStart, End: *user.WorkingHoursEnd}
+}
+
+func mapPreferences(prefs map[string]interface{}) map[string]interface{} {
+	if prefs == nil {
+		return map[string]interface{}{}
 	}
+	return prefs
 }
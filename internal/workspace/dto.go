@@ -7,24 +7,75 @@ import (
 )
 
 type WorkspaceDTO struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Slug      string    `json:"slug"`
-	Plan      string    `json:"plan"`
-	OwnerID   uuid.UUID `json:"owner_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                   uuid.UUID `json:"id"`
+	Name                 string    `json:"name"`
+	Slug                 string    `json:"slug"`
+	Plan                 string    `json:"plan"`
+	OwnerID              uuid.UUID `json:"owner_id"`
+	RequireTwoFactor     bool      `json:"require_two_factor"`
+	RequireVerifiedEmail bool      `json:"require_verified_email"`
+	// PendingOwnerID is only set while the nomination can be accepted,
+	// that is until PendingOwnerExpiresAt.
+	PendingOwnerID        *uuid.UUID `json:"pending_owner_id,omitempty"`
+	PendingOwnerExpiresAt *time.Time `json:"pending_owner_expires_at,omitempty"`
+	DeletionScheduledAt   *time.Time `json:"deletion_scheduled_at,omitempty"`
+	// MyRole, the counts and LastActivityAt are filled in by listings and
+	// left out of single-workspace responses.
+	MyRole         string     `json:"my_role,omitempty"`
+	MemberCount    *int64     `json:"member_count,omitempty"`
+	ProjectCount   *int64     `json:"project_count,omitempty"`
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
+// ListWorkspacesQuery picks the order of the caller's workspaces; by
+// default they are sorted by name.
+type ListWorkspacesQuery struct {
+	Sort string `form:"sort" binding:"omitempty,oneof=name created_at last_activity"`
+}
+
+// CreateWorkspaceRequest has no plan: new workspaces start on the free
+// plan and only a platform admin can change it.
 type CreateWorkspaceRequest struct {
 	Name string `json:"name" binding:"required,min=3,max=100"`
 	Slug string `json:"slug" binding:"required,min=3,max=100"`
-	Plan string `json:"plan" binding:"omitempty,oneof=free standard pro"`
 }
 
 type UpdateWorkspaceRequest struct {
-	Name *string `json:"name,omitempty" binding:"omitempty,min=3,max=100"`
-	Plan *string `json:"plan,omitempty" binding:"omitempty,oneof=free standard pro"`
+	Name                 *string `json:"name,omitempty" binding:"omitempty,min=3,max=100"`
+	RequireTwoFactor     *bool   `json:"require_two_factor,omitempty"`
+	RequireVerifiedEmail *bool   `json:"require_verified_email,omitempty"`
+}
+
+type ChangePlanRequest struct {
+	Plan string `json:"plan" binding:"required,oneof=free standard pro"`
+}
+
+// WorkspaceUsageDTO compares what a workspace uses with the limits of its
+// plan. A limit of 0 means unlimited.
+type WorkspaceUsageDTO struct {
+	Plan   string        `json:"plan"`
+	Usage  UsageDTO      `json:"usage"`
+	Limits PlanLimitsDTO `json:"limits"`
+}
+
+type UsageDTO struct {
+	Members      int64 `json:"members"`
+	Projects     int64 `json:"projects"`
+	StorageBytes int64 `json:"storage_bytes"`
+}
+
+type PlanLimitsDTO struct {
+	Members           int64 `json:"members"`
+	Projects          int64 `json:"projects"`
+	BoardsPerProject  int64 `json:"boards_per_project"`
+	StorageBytes      int64 `json:"storage_bytes"`
+	ChatRetentionDays int   `json:"chat_retention_days"`
+}
+
+type NominateOwnerRequest struct {
+	UserID uuid.UUID `json:"user_id" binding:"required"`
 }
 
 type WorkspaceMemberDTO struct {
@@ -35,9 +86,29 @@ type WorkspaceMemberDTO struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
-type InviteWorkspaceMemberRequest struct {
-	Email string `json:"email" binding:"required,email"`
-	Role  string `json:"role" binding:"required,oneof=owner admin member"`
+type InvitationDTO struct {
+	ID          uuid.UUID `json:"id"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	InvitedBy   uuid.UUID `json:"invited_by"`
+	Status      string    `json:"status"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	SentAt      time.Time `json:"sent_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type SendInvitationRequest struct {
+	Email string `json:"email" binding:"required,email,max=150"`
+	Role  string `json:"role" binding:"omitempty,oneof=admin member"`
+}
+
+type InvitationTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type LookupInviteeQuery struct {
+	Email string `form:"email" binding:"required,email"`
 }
 
 type UpdateWorkspaceMemberRoleRequest struct {
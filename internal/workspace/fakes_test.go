@@ -0,0 +1,89 @@
+package workspace
+
+import (
+	"context"
+	"time"
+
+	"kerjakuy/internal/auth"
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/repository"
+	"kerjakuy/internal/user"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// The fakes below keep just enough state in memory to drive the workspace
+// services. Each embeds its interface, so calling a method a test does not
+// expect panics instead of silently passing.
+
+type fakeMembers struct {
+	repository.WorkspaceMemberRepository
+	roles map[uuid.UUID]string
+}
+
+func (f *fakeMembers) FindByUserAndWorkspace(ctx context.Context, userID, workspaceID uuid.UUID) (*models.WorkspaceMember, error) {
+	role, ok := f.roles[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.WorkspaceMember{UserID: userID, WorkspaceID: workspaceID, Role: role}, nil
+}
+
+func (f *fakeMembers) CountByRole(ctx context.Context, workspaceID uuid.UUID, role string) (int64, error) {
+	var n int64
+	for _, r := range f.roles {
+		if r == role {
+			n++
+		}
+	}
+	return n, nil
+}
+
+type fakeInvitations struct {
+	InvitationRepository
+	rows []models.WorkspaceInvitation
+}
+
+func (f *fakeInvitations) FindPendingByToken(ctx context.Context, tokenHash string, now time.Time) (*models.WorkspaceInvitation, error) {
+	for i := range f.rows {
+		if f.rows[i].TokenHash == tokenHash {
+			return &f.rows[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeInvitations) ListClaimable(ctx context.Context, email string, now time.Time) ([]models.WorkspaceInvitation, error) {
+	var out []models.WorkspaceInvitation
+	for _, row := range f.rows {
+		if row.Email == email {
+			out = append(out, row)
+		}
+	}
+	return out, nil
+}
+
+type fakeInvitationUsers struct {
+	invitationUsers
+	byID map[uuid.UUID]*user.UserDTO
+}
+
+func (f *fakeInvitationUsers) GetByID(ctx context.Context, id uuid.UUID) (*user.UserDTO, error) {
+	u, ok := f.byID[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return u, nil
+}
+
+type fakeJoinPermissions struct {
+	auth.PermissionService
+	err   error
+	calls int
+}
+
+func (f *fakeJoinPermissions) CanJoinWorkspace(ctx context.Context, userID uuid.UUID, workspaceID uuid.UUID) error {
+	f.calls++
+	return f.err
+}
@@ -1,22 +1,25 @@
 package workspace
 
 import (
+	"errors"
 	"net/http"
 
 	"kerjakuy/internal/auth"
+	"kerjakuy/internal/pkg/plan"
 	"kerjakuy/internal/user"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type WorkspaceHandler struct {
-	workspaceService WorkspaceService
-	userService      user.UserService
+	workspaceService  WorkspaceService
+	invitationService InvitationService
 }
 
-func NewWorkspaceHandler(workspaceService WorkspaceService, userService user.UserService) *WorkspaceHandler {
-	return &WorkspaceHandler{workspaceService: workspaceService, userService: userService}
+func NewWorkspaceHandler(workspaceService WorkspaceService, invitationService InvitationService) *WorkspaceHandler {
+	return &WorkspaceHandler{workspaceService: workspaceService, invitationService: invitationService}
 }
 
 func (h *WorkspaceHandler) CreateWorkspace(c *gin.Context) {
@@ -41,13 +44,18 @@ func (h *WorkspaceHandler) CreateWorkspace(c *gin.Context) {
 }
 
 func (h *WorkspaceHandler) ListWorkspaces(c *gin.Context) {
-	ownerID, ok := auth.GetUserID(c)
+	userID, ok := auth.GetUserID(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
+	var query ListWorkspacesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	workspaces, err := h.workspaceService.ListOwnerWorkspaces(c.Request.Context(), ownerID)
+	workspaces, err := h.workspaceService.ListWorkspaces(c.Request.Context(), userID, query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -69,47 +77,359 @@ func (h *WorkspaceHandler) UpdateWorkspace(c *gin.Context) {
 		return
 	}
 
-	workspace, err := h.workspaceService.UpdateWorkspace(c.Request.Context(), workspaceID, req)
+	actorID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	workspace, err := h.workspaceService.UpdateWorkspace(c.Request.Context(), actorID, workspaceID, req)
 	if err != nil {
+		h.respondWorkspaceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+}
+
+func (h *WorkspaceHandler) DeleteWorkspace(c *gin.Context) {
+	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+		return
+	}
+
+	actorID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	workspace, err := h.workspaceService.DeleteWorkspace(c.Request.Context(), actorID, workspaceID)
+	if err != nil {
+		h.respondDeletionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, workspace)
+}
+
+func (h *WorkspaceHandler) RestoreWorkspace(c *gin.Context) {
+	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+		return
+	}
+
+	actorID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	workspace, err := h.workspaceService.RestoreWorkspace(c.Request.Context(), actorID, workspaceID)
+	if err != nil {
+		h.respondDeletionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+}
+
+func (h *WorkspaceHandler) NominateOwner(c *gin.Context) {
+	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+		return
+	}
+
+	var req NominateOwnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	actorID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	workspace, err := h.workspaceService.NominateOwner(c.Request.Context(), actorID, workspaceID, req.UserID)
+	if err != nil {
+		h.respondOwnershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, workspace)
+}
+
+func (h *WorkspaceHandler) CancelOwnershipTransfer(c *gin.Context) {
+	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+		return
+	}
+
+	actorID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.workspaceService.CancelOwnershipTransfer(c.Request.Context(), actorID, workspaceID); err != nil {
+		h.respondOwnershipError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *WorkspaceHandler) AcceptOwnership(c *gin.Context) {
+	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+		return
+	}
+
+	actorID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	workspace, err := h.workspaceService.AcceptOwnership(c.Request.Context(), actorID, workspaceID)
+	if err != nil {
+		h.respondOwnershipError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, workspace)
 }
 
-func (h *WorkspaceHandler) ListMembers(c *gin.Context) {
+func (h *WorkspaceHandler) respondOwnershipError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrPermissionDenied),
+		errors.Is(err, auth.ErrWorkspaceRequiresTwoFactor),
+		errors.Is(err, auth.ErrWorkspaceRequiresVerifiedEmail),
+		errors.Is(err, auth.ErrWorkspacePendingDeletion):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNomineeNotAdmin):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNoOwnershipTransfer):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "workspace tidak ditemukan"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *WorkspaceHandler) GetUsage(c *gin.Context) {
+	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+		return
+	}
+
+	actorID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	usage, err := h.workspaceService.GetUsage(c.Request.Context(), actorID, workspaceID)
+	if err != nil {
+		h.respondWorkspaceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// ChangePlan is mounted under /admin; the service checks that the caller
+// is a platform admin.
+func (h *WorkspaceHandler) ChangePlan(c *gin.Context) {
 	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
 		return
 	}
 
-	members, err := h.workspaceService.ListMembers(c.Request.Context(), workspaceID)
+	var req ChangePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actorID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	workspace, err := h.workspaceService.ChangePlan(c.Request.Context(), actorID, workspaceID, req)
 	if err != nil {
+		h.respondWorkspaceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+}
+
+// respondWorkspaceError maps errors from workspace settings and member
+// management.
+func (h *WorkspaceHandler) respondWorkspaceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrPermissionDenied),
+		errors.Is(err, auth.ErrWorkspaceRequiresTwoFactor),
+		errors.Is(err, auth.ErrWorkspaceRequiresVerifiedEmail),
+		errors.Is(err, auth.ErrWorkspacePendingDeletion),
+		errors.Is(err, ErrRoleAboveActor),
+		errors.Is(err, ErrMemberOutranksActor),
+		errors.Is(err, ErrOwnerProtected),
+		errors.Is(err, auth.ErrPlatformAdminRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "workspace tidak ditemukan"})
+	case errors.Is(err, ErrLastOwner):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidRole), errors.Is(err, ErrInvalidPlan):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *WorkspaceHandler) respondDeletionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrPermissionDenied),
+		errors.Is(err, auth.ErrWorkspaceRequiresTwoFactor),
+		errors.Is(err, auth.ErrWorkspaceRequiresVerifiedEmail):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrWorkspaceDeletionScheduled), errors.Is(err, ErrWorkspaceDeletionNotScheduled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *WorkspaceHandler) ListMembers(c *gin.Context) {
+	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+		return
+	}
+
+	actorID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	members, err := h.workspaceService.ListMembers(c.Request.Context(), actorID, workspaceID)
+	if err != nil {
+		h.respondWorkspaceError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, members)
 }
 
-func (h *WorkspaceHandler) InviteMember(c *gin.Context) {
+func (h *WorkspaceHandler) ListMemberSecurityEvents(c *gin.Context) {
+	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+		return
+	}
+
+	var query auth.SecurityEventQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actorID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	events, err := h.workspaceService.ListMemberSecurityEvents(c.Request.Context(), actorID, workspaceID, query)
+	if err != nil {
+		if errors.Is(err, ErrPermissionDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+func (h *WorkspaceHandler) SendInvitation(c *gin.Context) {
 	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
 		return
 	}
 
-	var req InviteWorkspaceMemberRequest
+	var req SendInvitationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	user, err := h.userService.GetByEmail(c.Request.Context(), req.Email)
+	actorID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	invitation, err := h.invitationService.SendInvitation(c.Request.Context(), actorID, workspaceID, req)
+	if err != nil {
+		h.respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+func (h *WorkspaceHandler) ListInvitations(c *gin.Context) {
+	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+		return
+	}
+
+	actorID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	invitations, err := h.invitationService.ListInvitations(c.Request.Context(), actorID, workspaceID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		h.respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+func (h *WorkspaceHandler) ResendInvitation(c *gin.Context) {
+	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+		return
+	}
+
+	invitationID, err := uuid.Parse(c.Param("invitationID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invitation id"})
 		return
 	}
 
@@ -119,15 +439,137 @@ func (h *WorkspaceHandler) InviteMember(c *gin.Context) {
 		return
 	}
 
-	member, err := h.workspaceService.InviteMember(c.Request.Context(), actorID, workspaceID, user.ID, req.Role)
+	invitation, err := h.invitationService.ResendInvitation(c.Request.Context(), actorID, workspaceID, invitationID)
 	if err != nil {
+		h.respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitation)
+}
+
+func (h *WorkspaceHandler) RevokeInvitation(c *gin.Context) {
+	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+		return
+	}
+
+	invitationID, err := uuid.Parse(c.Param("invitationID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invitation id"})
+		return
+	}
+
+	actorID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.invitationService.RevokeInvitation(c.Request.Context(), actorID, workspaceID, invitationID); err != nil {
+		h.respondInvitationError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *WorkspaceHandler) AcceptInvitation(c *gin.Context) {
+	var req InvitationTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	member, err := h.invitationService.AcceptInvitation(c.Request.Context(), userID, req.Token)
+	if err != nil {
+		h.respondInvitationError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusCreated, member)
 }
 
+// DeclineInvitation is public; the token alone identifies the invitation.
+func (h *WorkspaceHandler) DeclineInvitation(c *gin.Context) {
+	var req InvitationTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.invitationService.DeclineInvitation(c.Request.Context(), req.Token); err != nil {
+		h.respondInvitationError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *WorkspaceHandler) respondInvitationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrPermissionDenied),
+		errors.Is(err, ErrInvitationEmailMismatch),
+		errors.Is(err, auth.ErrWorkspaceRequiresTwoFactor),
+		errors.Is(err, auth.ErrWorkspaceRequiresVerifiedEmail),
+		errors.Is(err, auth.ErrWorkspacePendingDeletion):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, plan.ErrQuotaExceeded):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvitationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvitationPending), errors.Is(err, ErrAlreadyMember):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvitationInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *WorkspaceHandler) LookupInvitee(c *gin.Context) {
+	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace id"})
+		return
+	}
+	var query LookupInviteeQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	actorID, ok := auth.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	invitee, err := h.workspaceService.LookupInvitee(c.Request.Context(), actorID, workspaceID, query.Email)
+	if err != nil {
+		h.respondInviteeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, invitee)
+}
+
+func (h *WorkspaceHandler) respondInviteeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, user.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 func (h *WorkspaceHandler) UpdateMemberRole(c *gin.Context) {
 	workspaceID, err := uuid.Parse(c.Param("workspaceID"))
 	if err != nil {
@@ -154,7 +596,7 @@ func (h *WorkspaceHandler) UpdateMemberRole(c *gin.Context) {
 	}
 
 	if err := h.workspaceService.UpdateMemberRole(c.Request.Context(), actorID, workspaceID, memberID, req.Role); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		h.respondWorkspaceError(c, err)
 		return
 	}
 
@@ -181,7 +623,7 @@ func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
 	}
 
 	if err := h.workspaceService.RemoveMember(c.Request.Context(), actorID, workspaceID, userID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		h.respondWorkspaceError(c, err)
 		return
 	}
 
@@ -0,0 +1,135 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"kerjakuy/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvitationRepository interface {
+	Create(ctx context.Context, invitation *models.WorkspaceInvitation) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	ExpireStale(ctx context.Context, workspaceID uuid.UUID, email string, now time.Time) error
+	FindPending(ctx context.Context, workspaceID, id uuid.UUID) (*models.WorkspaceInvitation, error)
+	FindPendingByToken(ctx context.Context, tokenHash string, now time.Time) (*models.WorkspaceInvitation, error)
+	ListPending(ctx context.Context, workspaceID uuid.UUID) ([]models.WorkspaceInvitation, error)
+	ListClaimable(ctx context.Context, email string, now time.Time) ([]models.WorkspaceInvitation, error)
+	Rotate(ctx context.Context, id uuid.UUID, tokenHash string, expiresAt, sentAt time.Time) error
+	Respond(ctx context.Context, id uuid.UUID, status string, userID *uuid.UUID, now time.Time) (*models.WorkspaceInvitation, error)
+}
+
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+// Create maps a clash on the pending (workspace, email) index to
+// ErrInvitationPending.
+func (r *invitationRepository) Create(ctx context.Context, invitation *models.WorkspaceInvitation) error {
+	err := r.db.WithContext(ctx).Create(invitation).Error
+	if translator, ok := r.db.Dialector.(gorm.ErrorTranslator); ok && err != nil && errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey) {
+		return ErrInvitationPending
+	}
+	return err
+}
+
+func (r *invitationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.WorkspaceInvitation{}).Error
+}
+
+// ExpireStale closes a pending invitation for the address whose link has
+// run out, so a fresh one can be sent.
+func (r *invitationRepository) ExpireStale(ctx context.Context, workspaceID uuid.UUID, email string, now time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.WorkspaceInvitation{}).
+		Where("workspace_id = ? AND email = ? AND status = ? AND expires_at <= ?", workspaceID, email, InvitationStatusPending, now).
+		Update("status", InvitationStatusExpired).Error
+}
+
+func (r *invitationRepository) FindPending(ctx context.Context, workspaceID, id uuid.UUID) (*models.WorkspaceInvitation, error) {
+	var invitation models.WorkspaceInvitation
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND workspace_id = ? AND status = ?", id, workspaceID, InvitationStatusPending).
+		First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *invitationRepository) FindPendingByToken(ctx context.Context, tokenHash string, now time.Time) (*models.WorkspaceInvitation, error) {
+	var invitation models.WorkspaceInvitation
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ? AND status = ? AND expires_at > ?", tokenHash, InvitationStatusPending, now).
+		First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *invitationRepository) ListPending(ctx context.Context, workspaceID uuid.UUID) ([]models.WorkspaceInvitation, error) {
+	var invitations []models.WorkspaceInvitation
+	err := r.db.WithContext(ctx).
+		Where("workspace_id = ? AND status = ?", workspaceID, InvitationStatusPending).
+		Order("created_at DESC").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+func (r *invitationRepository) ListClaimable(ctx context.Context, email string, now time.Time) ([]models.WorkspaceInvitation, error) {
+	var invitations []models.WorkspaceInvitation
+	err := r.db.WithContext(ctx).
+		Where("email = ? AND status = ? AND expires_at > ?", email, InvitationStatusPending, now).
+		Order("created_at").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+// Rotate replaces the token of a pending invitation, which kills the link
+// sent earlier, and pushes the expiry out.
+func (r *invitationRepository) Rotate(ctx context.Context, id uuid.UUID, tokenHash string, expiresAt, sentAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.WorkspaceInvitation{}).
+		Where("id = ? AND status = ?", id, InvitationStatusPending).
+		Updates(map[string]interface{}{
+			"token_hash": tokenHash,
+			"expires_at": expiresAt,
+			"sent_at":    sentAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Respond moves a pending invitation to its final status and returns it.
+// The conditional update makes sure only one response wins.
+func (r *invitationRepository) Respond(ctx context.Context, id uuid.UUID, status string, userID *uuid.UUID, now time.Time) (*models.WorkspaceInvitation, error) {
+	var invitations []models.WorkspaceInvitation
+	result := r.db.WithContext(ctx).Model(&invitations).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status = ?", id, InvitationStatusPending).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"responded_by": userID,
+			"responded_at": now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(invitations) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &invitations[0], nil
+}
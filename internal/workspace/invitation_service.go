@@ -0,0 +1,426 @@
+package workspace
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"kerjakuy/internal/auth"
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/mailer"
+	"kerjakuy/internal/pkg/plan"
+	"kerjakuy/internal/pkg/rbac"
+	"kerjakuy/internal/repository"
+	"kerjakuy/internal/user"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	InvitationStatusPending  = "pending"
+	InvitationStatusAccepted = "accepted"
+	InvitationStatusDeclined = "declined"
+	InvitationStatusRevoked  = "revoked"
+	InvitationStatusExpired  = "expired"
+)
+
+var (
+	ErrInvitationInvalid       = errors.New("undangan tidak valid atau kedaluwarsa")
+	ErrInvitationNotFound      = errors.New("undangan tidak ditemukan")
+	ErrInvitationPending       = errors.New("undangan untuk email ini masih menunggu jawaban")
+	ErrAlreadyMember           = errors.New("pengguna sudah menjadi anggota workspace")
+	ErrInvitationEmailMismatch = errors.New("undangan hanya bisa diterima akun dengan email terverifikasi yang sama dengan alamat undangan")
+)
+
+type InvitationService interface {
+	SendInvitation(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, req SendInvitationRequest) (*InvitationDTO, error)
+	ResendInvitation(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, invitationID uuid.UUID) (*InvitationDTO, error)
+	RevokeInvitation(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, invitationID uuid.UUID) error
+	ListInvitations(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID) ([]InvitationDTO, error)
+	AcceptInvitation(ctx context.Context, userID uuid.UUID, token string) (*WorkspaceMemberDTO, error)
+	DeclineInvitation(ctx context.Context, token string) error
+	ClaimInvitations(ctx context.Context, userID uuid.UUID, email string) error
+}
+
+// invitationUsers is implemented by user.UserService.
+type invitationUsers interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*user.UserDTO, error)
+	LookupByEmail(ctx context.Context, viewerID uuid.UUID, email string) (*user.DirectoryEntryDTO, error)
+}
+
+type invitationService struct {
+	db                *gorm.DB
+	invitationRepo    InvitationRepository
+	workspaceRepo     WorkspaceRepository
+	memberRepo        repository.WorkspaceMemberRepository
+	permissionService auth.PermissionService
+	users             invitationUsers
+	mailer            mailer.Mailer
+	appURL            string
+	ttl               time.Duration
+	logger            *slog.Logger
+}
+
+func NewInvitationService(db *gorm.DB, invitationRepo InvitationRepository, workspaceRepo WorkspaceRepository, memberRepo repository.WorkspaceMemberRepository, permissionService auth.PermissionService, users invitationUsers, mail mailer.Mailer, appURL string, ttl time.Duration, logger *slog.Logger) InvitationService {
+	if ttl <= 0 {
+		ttl = 7 * 24 * time.Hour
+	}
+	return &invitationService{
+		db:                db,
+		invitationRepo:    invitationRepo,
+		workspaceRepo:     workspaceRepo,
+		memberRepo:        memberRepo,
+		permissionService: permissionService,
+		users:             users,
+		mailer:            mail,
+		appURL:            strings.TrimRight(appURL, "/"),
+		ttl:               ttl,
+		logger:            logger,
+	}
+}
+
+// SendInvitation mails an invitation to any address, registered or not.
+// Nothing is added to the workspace until the invitee accepts.
+func (s *invitationService) SendInvitation(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, req SendInvitationRequest) (*InvitationDTO, error) {
+	if err := s.authorize(ctx, actorID, workspaceID); err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	// Anyone already in this workspace shares it with the actor, so the
+	// scoped lookup is enough to spot existing members.
+	existing, err := s.users.LookupByEmail(ctx, actorID, email)
+	switch {
+	case err == nil:
+		if _, err := s.memberRepo.FindByUserAndWorkspace(ctx, existing.ID, workspaceID); err == nil {
+			return nil, ErrAlreadyMember
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	case !errors.Is(err, user.ErrUserNotFound):
+		return nil, err
+	}
+
+	workspace, err := s.workspaceRepo.FindByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMemberQuota(ctx, s.workspaceRepo, workspace); err != nil {
+		return nil, err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = string(rbac.RoleMember)
+	}
+	now := time.Now()
+	if err := s.invitationRepo.ExpireStale(ctx, workspaceID, email, now); err != nil {
+		return nil, err
+	}
+	token, tokenHash, err := newInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+	invitation := &models.WorkspaceInvitation{
+		WorkspaceID: workspaceID,
+		Email:       email,
+		Role:        role,
+		InvitedBy:   actorID,
+		TokenHash:   tokenHash,
+		Status:      InvitationStatusPending,
+		ExpiresAt:   now.Add(s.ttl),
+		SentAt:      now,
+	}
+	if err := s.invitationRepo.Create(ctx, invitation); err != nil {
+		return nil, err
+	}
+	if err := s.sendInvitationEmail(ctx, invitation, token); err != nil {
+		// Without the email nobody holds the token; drop the row so the
+		// admin can simply try again.
+		if delErr := s.invitationRepo.Delete(ctx, invitation.ID); delErr != nil {
+			s.logger.Error("failed to delete unsent invitation", "error", delErr, "invitation_id", invitation.ID)
+		}
+		return nil, err
+	}
+	s.logger.Info("invitation sent", "workspace_id", workspaceID, "invitation_id", invitation.ID, "role", role)
+	return mapInvitationToDTO(invitation, now), nil
+}
+
+// ResendInvitation mails a new link and restarts the expiry. The previous
+// link stops working.
+func (s *invitationService) ResendInvitation(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, invitationID uuid.UUID) (*InvitationDTO, error) {
+	if err := s.authorize(ctx, actorID, workspaceID); err != nil {
+		return nil, err
+	}
+	invitation, err := s.invitationRepo.FindPending(ctx, workspaceID, invitationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+
+	token, tokenHash, err := newInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := s.invitationRepo.Rotate(ctx, invitation.ID, tokenHash, now.Add(s.ttl), now); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	invitation.TokenHash, invitation.ExpiresAt, invitation.SentAt = tokenHash, now.Add(s.ttl), now
+	if err := s.sendInvitationEmail(ctx, invitation, token); err != nil {
+		return nil, err
+	}
+	s.logger.Info("invitation resent", "workspace_id", workspaceID, "invitation_id", invitation.ID)
+	return mapInvitationToDTO(invitation, now), nil
+}
+
+func (s *invitationService) RevokeInvitation(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, invitationID uuid.UUID) error {
+	if err := s.authorize(ctx, actorID, workspaceID); err != nil {
+		return err
+	}
+	if _, err := s.invitationRepo.FindPending(ctx, workspaceID, invitationID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvitationNotFound
+		}
+		return err
+	}
+	if _, err := s.invitationRepo.Respond(ctx, invitationID, InvitationStatusRevoked, &actorID, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvitationNotFound
+		}
+		return err
+	}
+	s.logger.Info("invitation revoked", "workspace_id", workspaceID, "invitation_id", invitationID)
+	return nil
+}
+
+// ListInvitations returns the invitations still waiting for an answer,
+// including ones whose link has expired and may be resent.
+func (s *invitationService) ListInvitations(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID) ([]InvitationDTO, error) {
+	if err := s.authorize(ctx, actorID, workspaceID); err != nil {
+		return nil, err
+	}
+	invitations, err := s.invitationRepo.ListPending(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	result := make([]InvitationDTO, 0, len(invitations))
+	for i := range invitations {
+		result = append(result, *mapInvitationToDTO(&invitations[i], now))
+	}
+	return result, nil
+}
+
+// AcceptInvitation adds userID to the workspace with the invited role. The
+// account must have verified the invited address.
+func (s *invitationService) AcceptInvitation(ctx context.Context, userID uuid.UUID, token string) (*WorkspaceMemberDTO, error) {
+	invitation, err := s.invitationRepo.FindPendingByToken(ctx, hashInvitationToken(token), time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationInvalid
+		}
+		return nil, err
+	}
+	// The token alone is not enough: a forwarded or leaked link must not
+	// let another account in, least of all as admin.
+	account, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if account.EmailVerifiedAt == nil || !strings.EqualFold(account.Email, invitation.Email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+	if err := s.permissionService.CanJoinWorkspace(ctx, userID, invitation.WorkspaceID); err != nil {
+		return nil, err
+	}
+	if _, err := s.memberRepo.FindByUserAndWorkspace(ctx, userID, invitation.WorkspaceID); err == nil {
+		return nil, ErrAlreadyMember
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	member, err := s.join(ctx, invitation, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationInvalid
+		}
+		return nil, err
+	}
+	s.logger.Info("invitation accepted", "workspace_id", invitation.WorkspaceID, "invitation_id", invitation.ID, "user_id", userID)
+	return mapWorkspaceMemberToDTO(member), nil
+}
+
+// DeclineInvitation needs only the token, so people without an account can
+// refuse too.
+func (s *invitationService) DeclineInvitation(ctx context.Context, token string) error {
+	invitation, err := s.invitationRepo.FindPendingByToken(ctx, hashInvitationToken(token), time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvitationInvalid
+		}
+		return err
+	}
+	if _, err := s.invitationRepo.Respond(ctx, invitation.ID, InvitationStatusDeclined, nil, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvitationInvalid
+		}
+		return err
+	}
+	s.logger.Info("invitation declined", "workspace_id", invitation.WorkspaceID, "invitation_id", invitation.ID)
+	return nil
+}
+
+// ClaimInvitations accepts every open invitation for email on behalf of
+// userID. Callers must only pass an address the account has proven to own.
+// Invitations the user may not take yet, e.g. because the workspace
+// requires something the account lacks, stay pending.
+func (s *invitationService) ClaimInvitations(ctx context.Context, userID uuid.UUID, email string) error {
+	invitations, err := s.invitationRepo.ListClaimable(ctx, strings.ToLower(email), time.Now())
+	if err != nil {
+		return err
+	}
+	for i := range invitations {
+		invitation := &invitations[i]
+		if err := s.permissionService.CanJoinWorkspace(ctx, userID, invitation.WorkspaceID); err != nil {
+			s.logger.Info("invitation left pending", "invitation_id", invitation.ID, "user_id", userID, "reason", err)
+			continue
+		}
+		if _, err := s.join(ctx, invitation, userID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// Answered or revoked in the meantime.
+				continue
+			}
+			if errors.Is(err, plan.ErrQuotaExceeded) {
+				s.logger.Info("invitation left pending", "invitation_id", invitation.ID, "user_id", userID, "reason", err)
+				continue
+			}
+			return err
+		}
+		s.logger.Info("invitation claimed", "workspace_id", invitation.WorkspaceID, "invitation_id", invitation.ID, "user_id", userID)
+	}
+	return nil
+}
+
+// join marks the invitation accepted and adds the membership in one
+// transaction. An existing membership is kept as it is; a new one needs a
+// free seat on the workspace plan.
+func (s *invitationService) join(ctx context.Context, invitation *models.WorkspaceInvitation, userID uuid.UUID) (*models.WorkspaceMember, error) {
+	var member *models.WorkspaceMember
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		txInvitationRepo := NewInvitationRepository(tx)
+		txMemberRepo := NewWorkspaceMemberRepository(tx)
+		txWorkspaceRepo := NewWorkspaceRepository(tx)
+
+		if _, err := txInvitationRepo.Respond(ctx, invitation.ID, InvitationStatusAccepted, &userID, time.Now()); err != nil {
+			return err
+		}
+		existing, err := txMemberRepo.FindByUserAndWorkspace(ctx, userID, invitation.WorkspaceID)
+		if err == nil {
+			member = existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		// The lock keeps concurrent joins from sharing the last seat.
+		workspace, err := txWorkspaceRepo.FindForUpdate(ctx, invitation.WorkspaceID)
+		if err != nil {
+			return err
+		}
+		if err := checkMemberQuota(ctx, txWorkspaceRepo, workspace); err != nil {
+			return err
+		}
+		member = &models.WorkspaceMember{
+			WorkspaceID: invitation.WorkspaceID,
+			UserID:      userID,
+			Role:        invitation.Role,
+		}
+		return txMemberRepo.Add(ctx, member)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (s *invitationService) authorize(ctx context.Context, actorID, workspaceID uuid.UUID) error {
+	allowed, err := s.permissionService.HasPermission(ctx, actorID, workspaceID, rbac.PermissionInviteMember)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+func (s *invitationService) sendInvitationEmail(ctx context.Context, invitation *models.WorkspaceInvitation, token string) error {
+	workspace, err := s.workspaceRepo.FindByID(ctx, invitation.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	inviter := "Seseorang"
+	if inviterDTO, err := s.users.GetByID(ctx, invitation.InvitedBy); err == nil {
+		inviter = inviterDTO.Name
+	}
+
+	query := "?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      []string{invitation.Email},
+		Subject: fmt.Sprintf("Undangan bergabung ke %s di KerjaKuy", workspace.Name),
+		Body: fmt.Sprintf(
+			"Halo,\n\n%s mengundang Anda bergabung ke workspace %s di KerjaKuy sebagai %s.\n\nTerima undangan (berlaku sampai %s):\n\n%s\n\nTolak undangan:\n\n%s\n\nBelum punya akun? Daftar dengan alamat email ini; undangan akan diterima otomatis setelah email terverifikasi.\n",
+			inviter, workspace.Name, invitation.Role,
+			invitation.ExpiresAt.UTC().Format("2006-01-02 15:04 MST"),
+			s.appURL+"/invitations/accept"+query,
+			s.appURL+"/invitations/decline"+query,
+		),
+	})
+}
+
+func newInvitationToken() (token, tokenHash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashInvitationToken(token), nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func mapInvitationToDTO(invitation *models.WorkspaceInvitation, now time.Time) *InvitationDTO {
+	status := invitation.Status
+	if status == InvitationStatusPending && !invitation.ExpiresAt.After(now) {
+		status = InvitationStatusExpired
+	}
+	return &InvitationDTO{
+		ID:          invitation.ID,
+		WorkspaceID: invitation.WorkspaceID,
+		Email:       invitation.Email,
+		Role:        invitation.Role,
+		InvitedBy:   invitation.InvitedBy,
+		Status:      status,
+		ExpiresAt:   invitation.ExpiresAt,
+		SentAt:      invitation.SentAt,
+		CreatedAt:   invitation.CreatedAt,
+	}
+}
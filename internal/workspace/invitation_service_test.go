@@ -0,0 +1,80 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/rbac"
+	"kerjakuy/internal/user"
+
+	"github.com/google/uuid"
+)
+
+func TestAcceptInvitationRefusals(t *testing.T) {
+	errBlocked := errors.New("workspace mewajibkan 2FA")
+	verified := time.Now()
+	tests := []struct {
+		name      string
+		token     string
+		email     string
+		verified  *time.Time
+		member    bool
+		canJoin   error
+		wantError error
+	}{
+		{name: "unknown token", token: "lain", email: "budi@example.com", verified: &verified, wantError: ErrInvitationInvalid},
+		{name: "unverified account", token: "rahasia", email: "budi@example.com", wantError: ErrInvitationEmailMismatch},
+		{name: "different address", token: "rahasia", email: "ana@example.com", verified: &verified, wantError: ErrInvitationEmailMismatch},
+		{name: "workspace refuses the account", token: "rahasia", email: "Budi@Example.com", verified: &verified, canJoin: errBlocked, wantError: errBlocked},
+		{name: "already a member", token: "rahasia", email: "budi@example.com", verified: &verified, member: true, wantError: ErrAlreadyMember},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, workspaceID := uuid.New(), uuid.New()
+			invitations := &fakeInvitations{rows: []models.WorkspaceInvitation{{
+				ID:          uuid.New(),
+				WorkspaceID: workspaceID,
+				Email:       "budi@example.com",
+				Role:        string(rbac.RoleAdmin),
+				TokenHash:   hashInvitationToken("rahasia"),
+			}}}
+			users := &fakeInvitationUsers{byID: map[uuid.UUID]*user.UserDTO{
+				userID: {ID: userID, Email: tt.email, EmailVerifiedAt: tt.verified},
+			}}
+			members := &fakeMembers{roles: map[uuid.UUID]string{}}
+			if tt.member {
+				members.roles[userID] = string(rbac.RoleMember)
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			svc := NewInvitationService(nil, invitations, nil, members, &fakeJoinPermissions{err: tt.canJoin}, users, nil, "http://app.test", time.Hour, logger)
+
+			_, err := svc.AcceptInvitation(context.Background(), userID, tt.token)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("AcceptInvitation = %v, want %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestClaimInvitationsLeavesRefusedInvitationsPending(t *testing.T) {
+	invitations := &fakeInvitations{rows: []models.WorkspaceInvitation{
+		{ID: uuid.New(), WorkspaceID: uuid.New(), Email: "budi@example.com", Role: string(rbac.RoleMember)},
+		{ID: uuid.New(), WorkspaceID: uuid.New(), Email: "budi@example.com", Role: string(rbac.RoleAdmin)},
+	}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// CanJoinWorkspace refuses, so join and its transaction are never reached.
+	permissions := &fakeJoinPermissions{err: errors.New("ditolak")}
+	svc := NewInvitationService(nil, invitations, nil, nil, permissions, nil, nil, "http://app.test", time.Hour, logger)
+
+	if err := svc.ClaimInvitations(context.Background(), uuid.New(), "Budi@Example.com"); err != nil {
+		t.Fatalf("ClaimInvitations: %v", err)
+	}
+	if permissions.calls != 2 {
+		t.Errorf("CanJoinWorkspace called %d times, want 2", permissions.calls)
+	}
+}
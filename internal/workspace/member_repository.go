@@ -23,8 +23,8 @@ func (r *workspaceMemberRepository) Add(ctx context.Context, member *models.Work
 	return r.db.WithContext(ctx).Create(member).Error
 }
 
-func (r *workspaceMemberRepository) UpdateRole(ctx context.Context, memberID uuid.UUID, role string) error {
-	return r.db.WithContext(ctx).Model(&models.WorkspaceMember{}).WithContext(ctx).Where("id = ?", memberID).Update("role", role).Error
+func (r *workspaceMemberRepository) UpdateRole(ctx context.Context, workspaceID, memberID uuid.UUID, role string) error {
+	return r.db.WithContext(ctx).Model(&models.WorkspaceMember{}).Where("id = ? AND workspace_id = ?", memberID, workspaceID).Update("role", role).Error
 }
 
 func (r *workspaceMemberRepository) ListByWorkspace(ctx context.Context, workspaceID uuid.UUID) ([]models.WorkspaceMember, error) {
@@ -46,3 +46,28 @@ func (r *workspaceMemberRepository) FindByUserAndWorkspace(ctx context.Context,
 	}
 	return &member, nil
 }
+
+// FindByID only finds the member if it belongs to workspaceID.
+func (r *workspaceMemberRepository) FindByID(ctx context.Context, workspaceID, memberID uuid.UUID) (*models.WorkspaceMember, error) {
+	var member models.WorkspaceMember
+	if err := r.db.WithContext(ctx).Where("id = ? AND workspace_id = ?", memberID, workspaceID).First(&member).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *workspaceMemberRepository) CountByRole(ctx context.Context, workspaceID uuid.UUID, role string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ? AND role = ?", workspaceID, role).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *workspaceMemberRepository) ListWorkspaceIDsByUser(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).Model(&models.WorkspaceMember{}).
+		Where("user_id = ?", userID).
+		Pluck("workspace_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,167 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"kerjakuy/internal/auth"
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/rbac"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ownershipNominationTTL is how long a nominee has to accept before the
+// owner has to nominate again.
+const ownershipNominationTTL = 7 * 24 * time.Hour
+
+var (
+	ErrNomineeNotAdmin     = errors.New("pemilik baru harus admin di workspace ini")
+	ErrNoOwnershipTransfer = errors.New("tidak ada transfer kepemilikan yang menunggu konfirmasi")
+)
+
+// NominateOwner starts an ownership transfer to an admin of the workspace.
+// Nothing changes until the nominee accepts, which they must do within
+// ownershipNominationTTL; nominating someone else replaces the previous
+// nomination.
+func (s *workspaceService) NominateOwner(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, nomineeID uuid.UUID) (*WorkspaceDTO, error) {
+	allowed, err := s.permissionService.HasPermission(ctx, actorID, workspaceID, rbac.PermissionTransferOwnership)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrPermissionDenied
+	}
+	if nomineeID == actorID {
+		return nil, ErrNomineeNotAdmin
+	}
+	nominee, err := s.memberRepo.FindByUserAndWorkspace(ctx, nomineeID, workspaceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNomineeNotAdmin
+		}
+		return nil, err
+	}
+	if rbac.Role(nominee.Role) != rbac.RoleAdmin {
+		return nil, ErrNomineeNotAdmin
+	}
+
+	now := time.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txWorkspaceRepo := NewWorkspaceRepository(tx)
+		nominated, err := txWorkspaceRepo.NominateOwner(ctx, workspaceID, actorID, &nomineeID, &now)
+		if err != nil {
+			return err
+		}
+		if !nominated {
+			return ErrPermissionDenied
+		}
+		return txWorkspaceRepo.LogActivity(ctx, &models.ActivityLog{
+			WorkspaceID: workspaceID,
+			UserID:      &actorID,
+			Action:      "workspace.ownership_transfer_requested",
+			TargetType:  "user",
+			TargetID:    &nomineeID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("ownership transfer requested", "workspace_id", workspaceID, "owner_id", actorID, "nominee_id", nomineeID)
+
+	workspace, err := s.workspaceRepo.FindByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return mapWorkspaceToDTO(workspace), nil
+}
+
+// CancelOwnershipTransfer withdraws a nomination. The owner can cancel it
+// and the nominee can decline it.
+func (s *workspaceService) CancelOwnershipTransfer(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID) error {
+	workspace, err := s.workspaceRepo.FindByID(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if workspace.PendingOwnerID == nil {
+		return ErrNoOwnershipTransfer
+	}
+	nomineeID := *workspace.PendingOwnerID
+	if actorID != workspace.OwnerID && actorID != nomineeID {
+		return ErrPermissionDenied
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txWorkspaceRepo := NewWorkspaceRepository(tx)
+		cancelled, err := txWorkspaceRepo.NominateOwner(ctx, workspaceID, workspace.OwnerID, nil, nil)
+		if err != nil {
+			return err
+		}
+		if !cancelled {
+			return ErrNoOwnershipTransfer
+		}
+		return txWorkspaceRepo.LogActivity(ctx, &models.ActivityLog{
+			WorkspaceID: workspaceID,
+			UserID:      &actorID,
+			Action:      "workspace.ownership_transfer_cancelled",
+			TargetType:  "user",
+			TargetID:    &nomineeID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Info("ownership transfer cancelled", "workspace_id", workspaceID, "actor_id", actorID)
+	return nil
+}
+
+// AcceptOwnership completes a transfer nominated for actorID. OwnerID and
+// both member roles change in one transaction; the previous owner becomes
+// an admin.
+func (s *workspaceService) AcceptOwnership(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID) (*WorkspaceDTO, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		txWorkspaceRepo := NewWorkspaceRepository(tx)
+		workspace, err := txWorkspaceRepo.FindForUpdate(ctx, workspaceID)
+		if err != nil {
+			return err
+		}
+		if !nominationOpen(workspace, time.Now()) || *workspace.PendingOwnerID != actorID {
+			return ErrNoOwnershipTransfer
+		}
+		if workspace.DeletionScheduledAt != nil {
+			return auth.ErrWorkspacePendingDeletion
+		}
+		// The nominee may have been demoted since the nomination.
+		member, err := NewWorkspaceMemberRepository(tx).FindByUserAndWorkspace(ctx, actorID, workspaceID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNomineeNotAdmin
+			}
+			return err
+		}
+		if rbac.Role(member.Role) != rbac.RoleAdmin {
+			return ErrNomineeNotAdmin
+		}
+		return txWorkspaceRepo.TransferOwnership(ctx, workspaceID, workspace.OwnerID, actorID, "nominated")
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("ownership transferred", "workspace_id", workspaceID, "owner_id", actorID)
+
+	workspace, err := s.workspaceRepo.FindByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return mapWorkspaceToDTO(workspace), nil
+}
+
+// nominationOpen reports whether the workspace has a nomination that can
+// still be accepted.
+func nominationOpen(workspace *models.Workspace, now time.Time) bool {
+	return workspace.PendingOwnerID != nil &&
+		workspace.OwnershipNominatedAt != nil &&
+		now.Before(workspace.OwnershipNominatedAt.Add(ownershipNominationTTL))
+}
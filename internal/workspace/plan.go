@@ -0,0 +1,146 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"kerjakuy/internal/auth"
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/plan"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// platformAdmins is implemented by user.UserService.
+type platformAdmins interface {
+	IsPlatformAdmin(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// PlanLimits returns the limits of the plan the workspace is on. Project
+// and task services use it to enforce their own quotas.
+func (s *workspaceService) PlanLimits(ctx context.Context, workspaceID uuid.UUID) (plan.Limits, error) {
+	workspace, err := s.workspaceRepo.FindByID(ctx, workspaceID)
+	if err != nil {
+		return plan.Limits{}, err
+	}
+	return plan.LimitsFor(plan.Plan(workspace.Plan)), nil
+}
+
+// GetUsage shows any member how much of the plan the workspace uses.
+func (s *workspaceService) GetUsage(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID) (*WorkspaceUsageDTO, error) {
+	if _, err := s.memberRepo.FindByUserAndWorkspace(ctx, actorID, workspaceID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPermissionDenied
+		}
+		return nil, err
+	}
+	if scope := auth.TokenScopeFromContext(ctx); scope != nil && !scope.AllowsWorkspace(workspaceID) {
+		return nil, ErrPermissionDenied
+	}
+	workspace, err := s.workspaceRepo.FindByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := s.workspaceRepo.Usage(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := plan.LimitsFor(plan.Plan(workspace.Plan))
+	return &WorkspaceUsageDTO{
+		Plan: workspace.Plan,
+		Usage: UsageDTO{
+			Members:      usage.Members,
+			Projects:     usage.Projects,
+			StorageBytes: usage.StorageBytes,
+		},
+		Limits: PlanLimitsDTO{
+			Members:           limits.Members,
+			Projects:          limits.Projects,
+			BoardsPerProject:  limits.BoardsPerProject,
+			StorageBytes:      limits.StorageBytes,
+			ChatRetentionDays: int(limits.ChatRetention / (24 * time.Hour)),
+		},
+	}, nil
+}
+
+// ChangePlan is reserved for platform admins; workspace owners cannot pick
+// their own plan. A downgrade keeps existing data but blocks new items
+// over the lower limits.
+func (s *workspaceService) ChangePlan(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, req ChangePlanRequest) (*WorkspaceDTO, error) {
+	isAdmin, err := s.admins.IsPlatformAdmin(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin {
+		return nil, auth.ErrPlatformAdminRequired
+	}
+	if !plan.IsValid(plan.Plan(req.Plan)) {
+		return nil, ErrInvalidPlan
+	}
+
+	var workspace *models.Workspace
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txWorkspaceRepo := NewWorkspaceRepository(tx)
+		workspace, err = txWorkspaceRepo.FindForUpdate(ctx, workspaceID)
+		if err != nil {
+			return err
+		}
+		previous := workspace.Plan
+		if previous == req.Plan {
+			return nil
+		}
+		now := time.Now()
+		workspace.Plan = req.Plan
+		workspace.PlanChangedAt = &now
+		if err := txWorkspaceRepo.Update(ctx, workspace); err != nil {
+			return err
+		}
+		return txWorkspaceRepo.LogActivity(ctx, &models.ActivityLog{
+			WorkspaceID: workspaceID,
+			UserID:      &actorID,
+			Action:      "workspace.plan_changed",
+			TargetType:  "workspace",
+			TargetID:    &workspaceID,
+			Metadata:    map[string]interface{}{"from": previous, "to": req.Plan},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("workspace plan changed", "workspace_id", workspaceID, "plan", req.Plan, "actor_id", actorID)
+	return mapWorkspaceToDTO(workspace), nil
+}
+
+// checkMemberQuota fails when the workspace has no seat left for one more
+// member.
+func checkMemberQuota(ctx context.Context, workspaces WorkspaceRepository, workspace *models.Workspace) error {
+	usage, err := workspaces.Usage(ctx, workspace.ID)
+	if err != nil {
+		return err
+	}
+	limits := plan.LimitsFor(plan.Plan(workspace.Plan))
+	return plan.Check(plan.ResourceMembers, limits.Members, usage.Members, 1)
+}
+
+// expireChatHistory deletes chat messages older than the retention of
+// each plan. Workspaces on an unknown plan get the free retention, matching
+// the limits they are held to.
+func (s *workspaceService) expireChatHistory(ctx context.Context) {
+	now := time.Now()
+	for name, limits := range plan.Catalogue {
+		if limits.ChatRetention <= 0 {
+			continue
+		}
+		deleted, err := s.workspaceRepo.PurgeChatHistory(ctx, string(name), now.Add(-limits.ChatRetention))
+		if err != nil {
+			s.logger.Error("failed to expire chat history", "error", err, "plan", name)
+			continue
+		}
+		if deleted > 0 {
+			s.logger.Info("chat history expired", "plan", name, "messages", deleted)
+		}
+	}
+}
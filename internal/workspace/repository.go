@@ -2,19 +2,33 @@ package workspace
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/plan"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type WorkspaceRepository interface {
 	Create(ctx context.Context, workspace *models.Workspace) error
 	FindByID(ctx context.Context, id uuid.UUID) (*models.Workspace, error)
 	FindBySlug(ctx context.Context, slug string) (*models.Workspace, error)
-	ListByOwner(ctx context.Context, ownerID uuid.UUID) ([]models.Workspace, error)
+	FindForUpdate(ctx context.Context, id uuid.UUID) (*models.Workspace, error)
+	ListByMember(ctx context.Context, userID uuid.UUID, sort string) ([]WorkspaceMembership, error)
 	Update(ctx context.Context, workspace *models.Workspace) error
+	ScheduleDeletion(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	CancelDeletion(ctx context.Context, id uuid.UUID) (bool, error)
+	ListDueForDeletion(ctx context.Context, now time.Time, limit int) ([]models.Workspace, error)
+	LockDueForDeletion(ctx context.Context, id uuid.UUID, now time.Time) (bool, error)
+	NominateOwner(ctx context.Context, id, ownerID uuid.UUID, nomineeID *uuid.UUID, at *time.Time) (bool, error)
+	TransferOwnership(ctx context.Context, id, fromID, toID uuid.UUID, reason string) error
+	LogActivity(ctx context.Context, entry *models.ActivityLog) error
+	Usage(ctx context.Context, id uuid.UUID) (*WorkspaceUsage, error)
+	PurgeChatHistory(ctx context.Context, planName string, before time.Time) (int64, error)
+	Purge(ctx context.Context, id uuid.UUID) error
 }
 
 type workspaceRepository struct {
@@ -45,9 +59,61 @@ func (r *workspaceRepository) FindBySlug(ctx context.Context, slug string) (*mod
 	return &workspace, nil
 }
 
-func (r *workspaceRepository) ListByOwner(ctx context.Context, ownerID uuid.UUID) ([]models.Workspace, error) {
-	var workspaces []models.Workspace
-	if err := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Find(&workspaces).Error; err != nil {
+// FindForUpdate locks the row until the surrounding transaction ends.
+func (r *workspaceRepository) FindForUpdate(ctx context.Context, id uuid.UUID) (*models.Workspace, error) {
+	var workspace models.Workspace
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&workspace, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// WorkspaceMembership is a workspace as seen by one of its members.
+type WorkspaceMembership struct {
+	models.Workspace
+	MyRole         string
+	MemberCount    int64
+	ProjectCount   int64
+	LastActivityAt time.Time
+}
+
+// Sort orders accepted by ListByMember.
+const (
+	WorkspaceSortName         = "name"
+	WorkspaceSortCreatedAt    = "created_at"
+	WorkspaceSortLastActivity = "last_activity"
+)
+
+// lastActivitySQL is the most recent change anywhere in the workspace:
+// the workspace itself, its projects and tasks, chat messages and the
+// activity log. GREATEST skips the NULLs of empty workspaces.
+const lastActivitySQL = `GREATEST(w.updated_at,
+	(SELECT MAX(p.updated_at) FROM projects p WHERE p.workspace_id = w.id),
+	(SELECT MAX(t.updated_at) FROM tasks t WHERE t.workspace_id = w.id),
+	(SELECT MAX(m.created_at) FROM chat_messages m JOIN chat_channels c ON c.id = m.channel_id WHERE c.workspace_id = w.id),
+	(SELECT MAX(a.created_at) FROM activity_logs a WHERE a.workspace_id = w.id))`
+
+// ListByMember returns every workspace userID belongs to along with their
+// role and the member and project counts.
+func (r *workspaceRepository) ListByMember(ctx context.Context, userID uuid.UUID, sort string) ([]WorkspaceMembership, error) {
+	order := "LOWER(w.name), w.id"
+	switch sort {
+	case WorkspaceSortCreatedAt:
+		order = "w.created_at DESC, w.id"
+	case WorkspaceSortLastActivity:
+		order = "last_activity_at DESC, w.id"
+	}
+
+	var workspaces []WorkspaceMembership
+	err := r.db.WithContext(ctx).Table("workspaces AS w").
+		Select("w.*, wm.role AS my_role, "+
+			"(SELECT COUNT(*) FROM workspace_members m WHERE m.workspace_id = w.id) AS member_count, "+
+			"(SELECT COUNT(*) FROM projects p WHERE p.workspace_id = w.id) AS project_count, "+
+			lastActivitySQL+" AS last_activity_at").
+		Joins("JOIN workspace_members wm ON wm.workspace_id = w.id AND wm.user_id = ?", userID).
+		Order(order).
+		Scan(&workspaces).Error
+	if err != nil {
 		return nil, err
 	}
 	return workspaces, nil
@@ -56,3 +122,181 @@ func (r *workspaceRepository) ListByOwner(ctx context.Context, ownerID uuid.UUID
 func (r *workspaceRepository) Update(ctx context.Context, workspace *models.Workspace) error {
 	return r.db.WithContext(ctx).Save(workspace).Error
 }
+
+func (r *workspaceRepository) ScheduleDeletion(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Workspace{}).
+		Where("id = ? AND deletion_scheduled_at IS NULL", id).
+		Update("deletion_scheduled_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *workspaceRepository) CancelDeletion(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Workspace{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", id).
+		Update("deletion_scheduled_at", nil)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *workspaceRepository) ListDueForDeletion(ctx context.Context, now time.Time, limit int) ([]models.Workspace, error) {
+	var workspaces []models.Workspace
+	err := r.db.WithContext(ctx).
+		Where("deletion_scheduled_at <= ?", now).
+		Order("deletion_scheduled_at").
+		Limit(limit).
+		Find(&workspaces).Error
+	return workspaces, err
+}
+
+// LockDueForDeletion reports whether the workspace is still due and, inside
+// a transaction, keeps a restore from racing the purge that follows.
+func (r *workspaceRepository) LockDueForDeletion(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	var workspaces []models.Workspace
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND deletion_scheduled_at <= ?", id, now).
+		Find(&workspaces).Error
+	return len(workspaces) == 1, err
+}
+
+// NominateOwner sets or, with a nil nominee, clears the pending ownership
+// transfer while ownerID still owns the workspace.
+func (r *workspaceRepository) NominateOwner(ctx context.Context, id, ownerID uuid.UUID, nomineeID *uuid.UUID, at *time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Workspace{}).
+		Where("id = ? AND owner_id = ?", id, ownerID).
+		Updates(map[string]interface{}{
+			"pending_owner_id":       nomineeID,
+			"ownership_nominated_at": at,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// TransferOwnership moves owner_id and both member roles, clears any
+// pending nomination and records it in the activity log; the previous
+// owner stays on as admin. Like Purge, it expects a repository built on a
+// transaction.
+func (r *workspaceRepository) TransferOwnership(ctx context.Context, id, fromID, toID uuid.UUID, reason string) error {
+	db := r.db.WithContext(ctx)
+	result := db.Model(&models.Workspace{}).
+		Where("id = ? AND owner_id = ?", id, fromID).
+		Updates(map[string]interface{}{
+			"owner_id":               toID,
+			"pending_owner_id":       nil,
+			"ownership_nominated_at": nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	result = db.Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ? AND user_id = ?", id, toID).
+		Update("role", "owner")
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if err := db.Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ? AND user_id = ?", id, fromID).
+		Update("role", "admin").Error; err != nil {
+		return err
+	}
+	return r.LogActivity(ctx, &models.ActivityLog{
+		WorkspaceID: id,
+		UserID:      &fromID,
+		Action:      "workspace.ownership_transferred",
+		TargetType:  "user",
+		TargetID:    &toID,
+		Metadata:    map[string]interface{}{"reason": reason},
+	})
+}
+
+func (r *workspaceRepository) LogActivity(ctx context.Context, entry *models.ActivityLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// WorkspaceUsage is what a workspace currently uses of its plan. Storage
+// is the declared size of all task attachments.
+type WorkspaceUsage struct {
+	Members      int64
+	Projects     int64
+	StorageBytes int64
+}
+
+func (r *workspaceRepository) Usage(ctx context.Context, id uuid.UUID) (*WorkspaceUsage, error) {
+	var usage WorkspaceUsage
+	err := r.db.WithContext(ctx).Table("workspaces AS w").
+		Select("(SELECT COUNT(*) FROM workspace_members m WHERE m.workspace_id = w.id) AS members, "+
+			"(SELECT COUNT(*) FROM projects p WHERE p.workspace_id = w.id) AS projects, "+
+			"(SELECT COALESCE(SUM(a.file_size), 0) FROM attachments a JOIN tasks t ON t.id = a.task_id WHERE t.workspace_id = w.id) AS storage_bytes").
+		Where("w.id = ?", id).
+		Scan(&usage).Error
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// PurgeChatHistory deletes messages sent before the cutoff in every
+// workspace on planName, along with their read receipts. Like
+// plan.LimitsFor, a plan outside the catalogue counts as free.
+func (r *workspaceRepository) PurgeChatHistory(ctx context.Context, planName string, before time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		workspaces := tx.Model(&models.Workspace{}).Select("id").Where("plan = ?", planName)
+		if plan.Plan(planName) == plan.Free {
+			workspaces = workspaces.Or("plan NOT IN ?", plan.Names())
+		}
+		channels := tx.Model(&models.ChatChannel{}).Select("id").Where("workspace_id IN (?)", workspaces)
+		messages := tx.Model(&models.ChatMessage{}).Select("id").Where("channel_id IN (?) AND created_at < ?", channels, before)
+		if err := tx.Where("message_id IN (?)", messages).Delete(&models.ChatMessageRead{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("channel_id IN (?) AND created_at < ?", channels, before).Delete(&models.ChatMessage{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
+
+// Purge hard-deletes the workspace and everything in it. The statements do
+// not run in a transaction of their own; callers pass a repository built on
+// a transaction.
+func (r *workspaceRepository) Purge(ctx context.Context, id uuid.UUID) error {
+	db := r.db.WithContext(ctx)
+	tasks := db.Model(&models.Task{}).Select("id").Where("workspace_id = ?", id)
+	projects := db.Model(&models.Project{}).Select("id").Where("workspace_id = ?", id)
+	boards := db.Model(&models.Board{}).Select("id").Where("project_id IN (?)", projects)
+	channels := db.Model(&models.ChatChannel{}).Select("id").Where("workspace_id = ?", id)
+	messages := db.Model(&models.ChatMessage{}).Select("id").Where("channel_id IN (?)", channels)
+
+	steps := []struct {
+		model interface{}
+		query string
+		arg   interface{}
+	}{
+		{&models.TaskComment{}, "task_id IN (?)", tasks},
+		{&models.TaskAssignee{}, "task_id IN (?)", tasks},
+		{&models.Attachment{}, "task_id IN (?)", tasks},
+		{&models.Task{}, "workspace_id = ?", id},
+		{&models.Column{}, "board_id IN (?)", boards},
+		{&models.Board{}, "project_id IN (?)", projects},
+		{&models.Project{}, "workspace_id = ?", id},
+		{&models.ChatMessageRead{}, "message_id IN (?)", messages},
+		{&models.ChatMessage{}, "channel_id IN (?)", channels},
+		{&models.ChatChannelMember{}, "channel_id IN (?)", channels},
+		{&models.ChatChannel{}, "workspace_id = ?", id},
+		{&models.ActivityLog{}, "workspace_id = ?", id},
+		{&models.WorkspaceInvitation{}, "workspace_id = ?", id},
+		{&models.WorkspaceMember{}, "workspace_id = ?", id},
+		{&models.Workspace{}, "id = ?", id},
+	}
+	for _, step := range steps {
+		if err := db.Where(step.query, step.arg).Delete(step.model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,11 +5,14 @@ import (
 	"errors"
 	"log/slog"
 	"strings"
+	"time"
 
 	"kerjakuy/internal/auth"
 	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/plan"
 	"kerjakuy/internal/pkg/rbac"
 	"kerjakuy/internal/repository"
+	"kerjakuy/internal/user"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -17,12 +20,46 @@ import (
 
 type WorkspaceService interface {
 	CreateWorkspace(ctx context.Context, ownerID uuid.UUID, req CreateWorkspaceRequest) (*WorkspaceDTO, error)
-	UpdateWorkspace(ctx context.Context, workspaceID uuid.UUID, req UpdateWorkspaceRequest) (*WorkspaceDTO, error)
-	ListOwnerWorkspaces(ctx context.Context, ownerID uuid.UUID) ([]WorkspaceDTO, error)
-	InviteMember(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, userID uuid.UUID, role string) (*WorkspaceMemberDTO, error)
-	ListMembers(ctx context.Context, workspaceID uuid.UUID) ([]WorkspaceMemberDTO, error)
+	UpdateWorkspace(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, req UpdateWorkspaceRequest) (*WorkspaceDTO, error)
+	DeleteWorkspace(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID) (*WorkspaceDTO, error)
+	RestoreWorkspace(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID) (*WorkspaceDTO, error)
+	NominateOwner(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, nomineeID uuid.UUID) (*WorkspaceDTO, error)
+	CancelOwnershipTransfer(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID) error
+	AcceptOwnership(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID) (*WorkspaceDTO, error)
+	ListWorkspaces(ctx context.Context, userID uuid.UUID, query ListWorkspacesQuery) ([]WorkspaceDTO, error)
+	LookupInvitee(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, email string) (*user.DirectoryEntryDTO, error)
+	ListMembers(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID) ([]WorkspaceMemberDTO, error)
 	UpdateMemberRole(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, memberID uuid.UUID, role string) error
 	RemoveMember(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, userID uuid.UUID) error
+	ListMemberSecurityEvents(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, query auth.SecurityEventQuery) ([]auth.SecurityEventDTO, error)
+	PlanLimits(ctx context.Context, workspaceID uuid.UUID) (plan.Limits, error)
+	GetUsage(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID) (*WorkspaceUsageDTO, error)
+	ChangePlan(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, req ChangePlanRequest) (*WorkspaceDTO, error)
+	Start(ctx context.Context, interval time.Duration)
+}
+
+const purgeBatchSize = 20
+
+var (
+	ErrPermissionDenied              = errors.New("permission denied")
+	ErrWorkspaceDeletionScheduled    = errors.New("workspace sudah dijadwalkan untuk dihapus")
+	ErrWorkspaceDeletionNotScheduled = errors.New("workspace tidak sedang dijadwalkan untuk dihapus")
+	ErrInvalidRole                   = errors.New("peran tidak valid")
+	ErrMemberNotFound                = errors.New("anggota tidak ditemukan di workspace ini")
+	ErrRoleAboveActor                = errors.New("tidak dapat memberikan peran yang lebih tinggi dari peran Anda")
+	ErrMemberOutranksActor           = errors.New("tidak dapat mengubah anggota dengan peran lebih tinggi dari peran Anda")
+	ErrOwnerProtected                = errors.New("pemilik workspace hanya dapat diganti melalui transfer kepemilikan")
+	ErrLastOwner                     = errors.New("workspace harus memiliki setidaknya satu pemilik")
+	ErrInvalidPlan                   = errors.New("paket tidak dikenal")
+)
+
+type securityEventLister interface {
+	ListSecurityEventsForMembers(ctx context.Context, joinedAt map[uuid.UUID]time.Time, query auth.SecurityEventQuery) ([]auth.SecurityEventDTO, error)
+}
+
+// inviteeDirectory is implemented by user.UserService.
+type inviteeDirectory interface {
+	LookupByEmail(ctx context.Context, viewerID uuid.UUID, email string) (*user.DirectoryEntryDTO, error)
 }
 
 type workspaceService struct {
@@ -30,16 +67,27 @@ type workspaceService struct {
 	workspaceRepo     WorkspaceRepository
 	memberRepo        repository.WorkspaceMemberRepository
 	permissionService auth.PermissionService
+	securityEvents    securityEventLister
+	directory         inviteeDirectory
+	admins            platformAdmins
 	logger            *slog.Logger
+	deletionGrace     time.Duration
 }
 
-func NewWorkspaceService(db *gorm.DB, workspaceRepo WorkspaceRepository, memberRepo repository.WorkspaceMemberRepository, permissionService auth.PermissionService, logger *slog.Logger) WorkspaceService {
+func NewWorkspaceService(db *gorm.DB, workspaceRepo WorkspaceRepository, memberRepo repository.WorkspaceMemberRepository, permissionService auth.PermissionService, securityEvents securityEventLister, directory inviteeDirectory, admins platformAdmins, logger *slog.Logger, deletionGrace time.Duration) WorkspaceService {
+	if deletionGrace <= 0 {
+		deletionGrace = 30 * 24 * time.Hour
+	}
 	return &workspaceService{
 		db:                db,
 		workspaceRepo:     workspaceRepo,
 		memberRepo:        memberRepo,
 		permissionService: permissionService,
+		securityEvents:    securityEvents,
+		directory:         directory,
+		admins:            admins,
 		logger:            logger,
+		deletionGrace:     deletionGrace,
 	}
 }
 
@@ -49,9 +97,7 @@ func (s *workspaceService) CreateWorkspace(ctx context.Context, ownerID uuid.UUI
 		Name:    req.Name,
 		Slug:    slug,
 		OwnerID: ownerID,
-	}
-	if req.Plan != "" {
-		workspace.Plan = req.Plan
+		Plan:    string(plan.Free),
 	}
 
 	// Start Transaction
@@ -90,7 +136,15 @@ func (s *workspaceService) CreateWorkspace(ctx context.Context, ownerID uuid.UUI
 	return mapWorkspaceToDTO(workspace), nil
 }
 
-func (s *workspaceService) UpdateWorkspace(ctx context.Context, workspaceID uuid.UUID, req UpdateWorkspaceRequest) (*WorkspaceDTO, error) {
+func (s *workspaceService) UpdateWorkspace(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, req UpdateWorkspaceRequest) (*WorkspaceDTO, error) {
+	allowed, err := s.permissionService.HasPermission(ctx, actorID, workspaceID, rbac.PermissionUpdateWorkspace)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrPermissionDenied
+	}
+
 	workspace, err := s.workspaceRepo.FindByID(ctx, workspaceID)
 	if err != nil {
 		return nil, err
@@ -99,8 +153,11 @@ func (s *workspaceService) UpdateWorkspace(ctx context.Context, workspaceID uuid
 	if req.Name != nil {
 		workspace.Name = *req.Name
 	}
-	if req.Plan != nil {
-		workspace.Plan = *req.Plan
+	if req.RequireTwoFactor != nil {
+		workspace.RequireTwoFactor = *req.RequireTwoFactor
+	}
+	if req.RequireVerifiedEmail != nil {
+		workspace.RequireVerifiedEmail = *req.RequireVerifiedEmail
 	}
 
 	if err := s.workspaceRepo.Update(ctx, workspace); err != nil {
@@ -111,46 +168,139 @@ func (s *workspaceService) UpdateWorkspace(ctx context.Context, workspaceID uuid
 	return mapWorkspaceToDTO(workspace), nil
 }
 
-func (s *workspaceService) ListOwnerWorkspaces(ctx context.Context, ownerID uuid.UUID) ([]WorkspaceDTO, error) {
-	workspaces, err := s.workspaceRepo.ListByOwner(ctx, ownerID)
+// DeleteWorkspace schedules the workspace to be purged once the grace
+// period ends. Until then it is read-only and the owner can restore it.
+func (s *workspaceService) DeleteWorkspace(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID) (*WorkspaceDTO, error) {
+	allowed, err := s.permissionService.HasPermission(ctx, actorID, workspaceID, rbac.PermissionDeleteWorkspace)
 	if err != nil {
+		if errors.Is(err, auth.ErrWorkspacePendingDeletion) {
+			return nil, ErrWorkspaceDeletionScheduled
+		}
 		return nil, err
 	}
-	result := make([]WorkspaceDTO, 0, len(workspaces))
-	for i := range workspaces {
-		result = append(result, *mapWorkspaceToDTO(&workspaces[i]))
+	if !allowed {
+		return nil, ErrPermissionDenied
 	}
-	return result, nil
+
+	scheduledAt := time.Now().Add(s.deletionGrace)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txWorkspaceRepo := NewWorkspaceRepository(tx)
+		scheduled, err := txWorkspaceRepo.ScheduleDeletion(ctx, workspaceID, scheduledAt)
+		if err != nil {
+			return err
+		}
+		if !scheduled {
+			return ErrWorkspaceDeletionScheduled
+		}
+		return txWorkspaceRepo.LogActivity(ctx, &models.ActivityLog{
+			WorkspaceID: workspaceID,
+			UserID:      &actorID,
+			Action:      "workspace.deletion_scheduled",
+			TargetType:  "workspace",
+			TargetID:    &workspaceID,
+			Metadata:    map[string]interface{}{"scheduled_at": scheduledAt},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("workspace deletion scheduled", "workspace_id", workspaceID, "actor_id", actorID, "scheduled_at", scheduledAt)
+
+	workspace, err := s.workspaceRepo.FindByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return mapWorkspaceToDTO(workspace), nil
 }
 
-func (s *workspaceService) InviteMember(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, userID uuid.UUID, role string) (*WorkspaceMemberDTO, error) {
-	allowed, err := s.permissionService.HasPermission(ctx, actorID, workspaceID, rbac.PermissionInviteMember)
+// RestoreWorkspace cancels a scheduled deletion. It needs the same
+// permission as deleting, checked directly because HasPermission refuses
+// everything while the workspace is pending deletion.
+func (s *workspaceService) RestoreWorkspace(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID) (*WorkspaceDTO, error) {
+	member, err := s.memberRepo.FindByUserAndWorkspace(ctx, actorID, workspaceID)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPermissionDenied
+		}
 		return nil, err
 	}
-	if !allowed {
-		return nil, errors.New("permission denied")
+	if !rbac.HasPermission(rbac.Role(member.Role), rbac.PermissionDeleteWorkspace) {
+		return nil, ErrPermissionDenied
+	}
+	if scope := auth.TokenScopeFromContext(ctx); scope != nil && !scope.Allows(workspaceID, rbac.PermissionDeleteWorkspace) {
+		return nil, ErrPermissionDenied
 	}
 
-	if role == "" {
-		role = "member"
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txWorkspaceRepo := NewWorkspaceRepository(tx)
+		restored, err := txWorkspaceRepo.CancelDeletion(ctx, workspaceID)
+		if err != nil {
+			return err
+		}
+		if !restored {
+			return ErrWorkspaceDeletionNotScheduled
+		}
+		return txWorkspaceRepo.LogActivity(ctx, &models.ActivityLog{
+			WorkspaceID: workspaceID,
+			UserID:      &actorID,
+			Action:      "workspace.restored",
+			TargetType:  "workspace",
+			TargetID:    &workspaceID,
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
+	s.logger.Info("workspace restored", "workspace_id", workspaceID, "actor_id", actorID)
 
-	member := &models.WorkspaceMember{
-		WorkspaceID: workspaceID,
-		UserID:      userID,
-		Role:        role,
+	workspace, err := s.workspaceRepo.FindByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
 	}
+	return mapWorkspaceToDTO(workspace), nil
+}
 
-	if err := s.memberRepo.Add(ctx, member); err != nil {
-		s.logger.Error("failed to invite member", "error", err, "workspace_id", workspaceID, "user_id", userID)
+// ListWorkspaces returns every workspace userID is a member of, not just
+// the ones they own.
+func (s *workspaceService) ListWorkspaces(ctx context.Context, userID uuid.UUID, query ListWorkspacesQuery) ([]WorkspaceDTO, error) {
+	workspaces, err := s.workspaceRepo.ListByMember(ctx, userID, query.Sort)
+	if err != nil {
 		return nil, err
 	}
-	s.logger.Info("member invited", "workspace_id", workspaceID, "user_id", userID, "role", role)
-	return mapWorkspaceMemberToDTO(member), nil
+	result := make([]WorkspaceDTO, 0, len(workspaces))
+	for i := range workspaces {
+		dto := mapWorkspaceToDTO(&workspaces[i].Workspace)
+		dto.MyRole = workspaces[i].MyRole
+		dto.MemberCount = &workspaces[i].MemberCount
+		dto.ProjectCount = &workspaces[i].ProjectCount
+		dto.LastActivityAt = &workspaces[i].LastActivityAt
+		result = append(result, *dto)
+	}
+	return result, nil
 }
 
-func (s *workspaceService) ListMembers(ctx context.Context, workspaceID uuid.UUID) ([]WorkspaceMemberDTO, error) {
+// LookupInvitee resolves an exact email to an account for members allowed
+// to invite. Only people who already share a workspace with the actor are
+// found; everyone else gets the same not-found as an unregistered address.
+func (s *workspaceService) LookupInvitee(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, email string) (*user.DirectoryEntryDTO, error) {
+	allowed, err := s.permissionService.HasPermission(ctx, actorID, workspaceID, rbac.PermissionInviteMember)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrPermissionDenied
+	}
+	return s.directory.LookupByEmail(ctx, actorID, email)
+}
+
+func (s *workspaceService) ListMembers(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID) ([]WorkspaceMemberDTO, error) {
+	allowed, err := s.permissionService.CanViewWorkspace(ctx, actorID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrPermissionDenied
+	}
 	members, err := s.memberRepo.ListByWorkspace(ctx, workspaceID)
 	if err != nil {
 		return nil, err
@@ -162,23 +312,101 @@ func (s *workspaceService) ListMembers(ctx context.Context, workspaceID uuid.UUI
 	return result, nil
 }
 
+// UpdateMemberRole changes the role of a member of workspaceID. The actor
+// cannot grant a role above their own or touch someone who outranks them,
+// and the last owner cannot be demoted.
 func (s *workspaceService) UpdateMemberRole(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, memberID uuid.UUID, role string) error {
+	newRole := rbac.Role(role)
+	if !rbac.IsValidRole(newRole) {
+		return ErrInvalidRole
+	}
 	allowed, err := s.permissionService.HasPermission(ctx, actorID, workspaceID, rbac.PermissionUpdateMember)
 	if err != nil {
 		return err
 	}
 	if !allowed {
-		return errors.New("permission denied")
+		return ErrPermissionDenied
 	}
 
-	if role == "" {
-		return errors.New("role is required")
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txWorkspaceRepo := NewWorkspaceRepository(tx)
+		txMemberRepo := NewWorkspaceMemberRepository(tx)
+		// Locking the workspace serialises role changes, so two owners
+		// cannot demote each other at the same time.
+		workspace, err := txWorkspaceRepo.FindForUpdate(ctx, workspaceID)
+		if err != nil {
+			return err
+		}
+		target, err := txMemberRepo.FindByID(ctx, workspaceID, memberID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrMemberNotFound
+			}
+			return err
+		}
+		if err := checkMemberChange(ctx, txMemberRepo, workspace, actorID, target, &newRole); err != nil {
+			return err
+		}
+		if target.Role == role {
+			return nil
+		}
+		if err := txMemberRepo.UpdateRole(ctx, workspaceID, memberID, role); err != nil {
+			return err
+		}
+		return txWorkspaceRepo.LogActivity(ctx, &models.ActivityLog{
+			WorkspaceID: workspaceID,
+			UserID:      &actorID,
+			Action:      "workspace.member_role_updated",
+			TargetType:  "user",
+			TargetID:    &target.UserID,
+			Metadata:    map[string]interface{}{"from": target.Role, "to": role},
+		})
+	})
+	if err != nil {
+		return err
 	}
-	if err := s.memberRepo.UpdateRole(ctx, memberID, role); err != nil {
-		s.logger.Error("failed to update member role", "error", err, "member_id", memberID, "role", role)
+	s.logger.Info("member role updated", "workspace_id", workspaceID, "member_id", memberID, "role", role)
+	return nil
+}
+
+// checkMemberChange enforces the role hierarchy on a change to target. A
+// nil newRole means target is being removed.
+func checkMemberChange(ctx context.Context, members repository.WorkspaceMemberRepository, workspace *models.Workspace, actorID uuid.UUID, target *models.WorkspaceMember, newRole *rbac.Role) error {
+	actor, err := members.FindByUserAndWorkspace(ctx, actorID, workspace.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPermissionDenied
+		}
 		return err
 	}
-	s.logger.Info("member role updated", "member_id", memberID, "role", role)
+	actorRole := rbac.Role(actor.Role)
+	targetRole := rbac.Role(target.Role)
+
+	if rbac.Outranks(targetRole, actorRole) {
+		return ErrMemberOutranksActor
+	}
+	if newRole != nil && rbac.Outranks(*newRole, actorRole) {
+		return ErrRoleAboveActor
+	}
+	// The owner role only changes hands through the ownership transfer,
+	// which keeps workspaces.owner_id in step.
+	if newRole != nil && *newRole == rbac.RoleOwner && targetRole != rbac.RoleOwner {
+		return ErrOwnerProtected
+	}
+	stillOwner := newRole != nil && *newRole == rbac.RoleOwner
+	if stillOwner || targetRole != rbac.RoleOwner {
+		return nil
+	}
+	if target.UserID == workspace.OwnerID {
+		return ErrOwnerProtected
+	}
+	owners, err := members.CountByRole(ctx, workspace.ID, string(rbac.RoleOwner))
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
 	return nil
 }
 
@@ -188,9 +416,39 @@ func (s *workspaceService) RemoveMember(ctx context.Context, actorID uuid.UUID,
 		return err
 	}
 	if !allowed {
-		return errors.New("permission denied")
+		return ErrPermissionDenied
 	}
-	if err := s.memberRepo.Remove(ctx, workspaceID, userID); err != nil {
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txWorkspaceRepo := NewWorkspaceRepository(tx)
+		txMemberRepo := NewWorkspaceMemberRepository(tx)
+		workspace, err := txWorkspaceRepo.FindForUpdate(ctx, workspaceID)
+		if err != nil {
+			return err
+		}
+		target, err := txMemberRepo.FindByUserAndWorkspace(ctx, userID, workspaceID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrMemberNotFound
+			}
+			return err
+		}
+		if err := checkMemberChange(ctx, txMemberRepo, workspace, actorID, target, nil); err != nil {
+			return err
+		}
+		if err := txMemberRepo.Remove(ctx, workspaceID, userID); err != nil {
+			return err
+		}
+		return txWorkspaceRepo.LogActivity(ctx, &models.ActivityLog{
+			WorkspaceID: workspaceID,
+			UserID:      &actorID,
+			Action:      "workspace.member_removed",
+			TargetType:  "user",
+			TargetID:    &userID,
+			Metadata:    map[string]interface{}{"role": target.Role},
+		})
+	})
+	if err != nil {
 		s.logger.Error("failed to remove member", "error", err, "workspace_id", workspaceID, "user_id", userID)
 		return err
 	}
@@ -198,16 +456,107 @@ func (s *workspaceService) RemoveMember(ctx context.Context, actorID uuid.UUID,
 	return nil
 }
 
+// ListMemberSecurityEvents lets the owner review authentication activity of
+// everyone currently in the workspace.
+func (s *workspaceService) ListMemberSecurityEvents(ctx context.Context, actorID uuid.UUID, workspaceID uuid.UUID, query auth.SecurityEventQuery) ([]auth.SecurityEventDTO, error) {
+	allowed, err := s.permissionService.HasPermission(ctx, actorID, workspaceID, rbac.PermissionViewSecurityLog)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrPermissionDenied
+	}
+
+	members, err := s.memberRepo.ListByWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	// Events from before someone joined belong to their life outside this
+	// workspace.
+	joinedAt := make(map[uuid.UUID]time.Time, len(members))
+	for i := range members {
+		joinedAt[members[i].UserID] = members[i].CreatedAt
+	}
+	return s.securityEvents.ListSecurityEventsForMembers(ctx, joinedAt, query)
+}
+
+// Start purges workspaces whose grace period has ended and chat history
+// past the plan retention, once right away and then every interval.
+func (s *workspaceService) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		s.purgeDue(ctx)
+		s.expireChatHistory(ctx)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.purgeDue(ctx)
+				s.expireChatHistory(ctx)
+			}
+		}
+	}()
+}
+
+func (s *workspaceService) purgeDue(ctx context.Context) {
+	now := time.Now()
+	workspaces, err := s.workspaceRepo.ListDueForDeletion(ctx, now, purgeBatchSize)
+	if err != nil {
+		s.logger.Error("failed to list workspaces due for deletion", "error", err)
+		return
+	}
+	for i := range workspaces {
+		purged, err := s.purge(ctx, workspaces[i].ID, now)
+		if err != nil {
+			s.logger.Error("failed to delete workspace", "error", err, "workspace_id", workspaces[i].ID)
+			continue
+		}
+		if purged {
+			s.logger.Info("workspace deleted", "workspace_id", workspaces[i].ID)
+		}
+	}
+}
+
+// purge removes the workspace rows in one transaction. Attachments are
+// links to files hosted elsewhere, so there is nothing else to clean up.
+func (s *workspaceService) purge(ctx context.Context, workspaceID uuid.UUID, now time.Time) (bool, error) {
+	var purged bool
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		txWorkspaceRepo := NewWorkspaceRepository(tx)
+		due, err := txWorkspaceRepo.LockDueForDeletion(ctx, workspaceID, now)
+		if err != nil || !due {
+			return err
+		}
+		purged = true
+		return txWorkspaceRepo.Purge(ctx, workspaceID)
+	})
+	if err != nil {
+		return false, err
+	}
+	return purged, nil
+}
+
 func mapWorkspaceToDTO(workspace *models.Workspace) *WorkspaceDTO {
-	return &WorkspaceDTO{
-		ID:        workspace.ID,
-		Name:      workspace.Name,
-		Slug:      workspace.Slug,
-		Plan:      workspace.Plan,
-		OwnerID:   workspace.OwnerID,
-		CreatedAt: workspace.CreatedAt,
-		UpdatedAt: workspace.UpdatedAt,
+	dto := &WorkspaceDTO{
+		ID:                   workspace.ID,
+		Name:                 workspace.Name,
+		Slug:                 workspace.Slug,
+		Plan:                 workspace.Plan,
+		OwnerID:              workspace.OwnerID,
+		RequireTwoFactor:     workspace.RequireTwoFactor,
+		RequireVerifiedEmail: workspace.RequireVerifiedEmail,
+		DeletionScheduledAt:  workspace.DeletionScheduledAt,
+		CreatedAt:            workspace.CreatedAt,
+		UpdatedAt:            workspace.UpdatedAt,
+	}
+	if nominationOpen(workspace, time.Now()) {
+		expiresAt := workspace.OwnershipNominatedAt.Add(ownershipNominationTTL)
+		dto.PendingOwnerID = workspace.PendingOwnerID
+		dto.PendingOwnerExpiresAt = &expiresAt
 	}
+	return dto
 }
 
 func mapWorkspaceMemberToDTO(member *models.WorkspaceMember) *WorkspaceMemberDTO {
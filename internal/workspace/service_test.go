@@ -0,0 +1,72 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"kerjakuy/internal/models"
+	"kerjakuy/internal/pkg/rbac"
+
+	"github.com/google/uuid"
+)
+
+func TestCheckMemberChange(t *testing.T) {
+	var (
+		founder  = uuid.New()
+		coOwner  = uuid.New()
+		admin    = uuid.New()
+		admin2   = uuid.New()
+		member   = uuid.New()
+		outsider = uuid.New()
+	)
+	roles := map[uuid.UUID]string{
+		founder: string(rbac.RoleOwner),
+		coOwner: string(rbac.RoleOwner),
+		admin:   string(rbac.RoleAdmin),
+		admin2:  string(rbac.RoleAdmin),
+		member:  string(rbac.RoleMember),
+	}
+	role := func(r rbac.Role) *rbac.Role { return &r }
+
+	tests := []struct {
+		name    string
+		actor   uuid.UUID
+		target  uuid.UUID
+		newRole *rbac.Role
+		owners  []uuid.UUID
+		want    error
+	}{
+		{name: "admin promotes member to admin", actor: admin, target: member, newRole: role(rbac.RoleAdmin)},
+		{name: "admin removes admin", actor: admin, target: admin2},
+		{name: "admin cannot touch an owner", actor: admin, target: coOwner, newRole: role(rbac.RoleMember), want: ErrMemberOutranksActor},
+		{name: "admin cannot grant owner", actor: admin, target: member, newRole: role(rbac.RoleOwner), want: ErrRoleAboveActor},
+		{name: "owner cannot grant owner outside transfer", actor: founder, target: admin, newRole: role(rbac.RoleOwner), want: ErrOwnerProtected},
+		{name: "owner demotes co-owner", actor: founder, target: coOwner, newRole: role(rbac.RoleAdmin)},
+		{name: "workspace owner is protected", actor: coOwner, target: founder, want: ErrOwnerProtected},
+		{name: "last owner cannot be demoted", actor: coOwner, target: coOwner, newRole: role(rbac.RoleAdmin), owners: []uuid.UUID{coOwner}, want: ErrLastOwner},
+		{name: "non member is denied", actor: outsider, target: member, want: ErrPermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members := &fakeMembers{roles: map[uuid.UUID]string{}}
+			for id, r := range roles {
+				members.roles[id] = r
+			}
+			if tt.owners != nil {
+				for id, r := range members.roles {
+					if r == string(rbac.RoleOwner) && id != tt.owners[0] {
+						members.roles[id] = string(rbac.RoleAdmin)
+					}
+				}
+			}
+			workspace := &models.Workspace{ID: uuid.New(), OwnerID: founder}
+			target := &models.WorkspaceMember{UserID: tt.target, WorkspaceID: workspace.ID, Role: members.roles[tt.target]}
+
+			err := checkMemberChange(context.Background(), members, workspace, tt.actor, target, tt.newRole)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("checkMemberChange = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
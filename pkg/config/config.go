@@ -5,24 +5,79 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	GinMode         string
-	DBHost          string
-	DBUser          string
-	DBPass          string
-	DBName          string
-	DBPort          string
-	DBSSL           string
-	AppPort         string
-	JWTSecret       string
-	JWTIssuer       string
-	AccessTokenTTL  time.Duration
-	RefreshTokenTTL time.Duration
+	GinMode                string
+	DBHost                 string
+	DBUser                 string
+	DBPass                 string
+	DBName                 string
+	DBPort                 string
+	DBSSL                  string
+	AppPort                string
+	JWTSecret              string
+	JWTIssuer              string
+	JWTPrivateKeyFile      string
+	JWTPublicKeyFiles      []string
+	AccessTokenTTL         time.Duration
+	RefreshTokenTTL        time.Duration
+	OAuthProviders         []OAuthProviderConfig
+	AppURL                 string
+	PasswordResetTTL       time.Duration
+	EmailVerifyTTL         time.Duration
+	MagicLinkTTL           time.Duration
+	EmailChangeTTL         time.Duration
+	EmailChangeRevertTTL   time.Duration
+	ImpersonationTTL       time.Duration
+	AccountDeletionGrace   time.Duration
+	WorkspaceInvitationTTL time.Duration
+	WorkspaceDeletionGrace time.Duration
+	Mail                   MailConfig
+	Storage                StorageConfig
+	AvatarBaseURL          string
+	CookieSecure           bool
+	CookieSameSite         string
+	CookieDomain           string
+	LoginMaxFailures       int
+	LoginLockout           time.Duration
+	RevocationSyncInterval time.Duration
+}
+
+// MailConfig selects the outgoing mail driver: "log" (default), "file" or
+// "smtp".
+type MailConfig struct {
+	Driver       string
+	From         string
+	Dir          string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// StorageConfig selects where uploaded files are kept: "local" (default)
+// stores them under Dir.
+type StorageConfig struct {
+	Driver string
+	Dir    string
+}
+
+// OAuthProviderConfig describes a single OAuth2/OIDC login provider. A
+// provider is only enabled when both the client id and secret are set.
+type OAuthProviderConfig struct {
+	Name         string
+	Kind         string
+	ClientID     string
+	ClientSecret string
+	IssuerURL    string
+	RedirectURL  string
+	Scopes       []string
 }
 
 func LoadConfig() *Config {
@@ -34,24 +89,69 @@ func LoadConfig() *Config {
 	refreshTTL := parseDurationWithDefault(os.Getenv("JWT_REFRESH_TTL"), 7*24*time.Hour)
 
 	cfg := &Config{
-		GinMode:         os.Getenv("GIN_MODE"),
-		DBHost:          os.Getenv("DB_HOST"),
-		DBUser:          os.Getenv("DB_USER"),
-		DBPass:          os.Getenv("DB_PASS"),
-		DBName:          os.Getenv("DB_NAME"),
-		DBPort:          os.Getenv("DB_PORT"),
-		DBSSL:           os.Getenv("DB_SSL"),
-		AppPort:         os.Getenv("APP_PORT"),
-		JWTSecret:       os.Getenv("JWT_SECRET"),
-		JWTIssuer:       os.Getenv("JWT_ISSUER"),
-		AccessTokenTTL:  accessTTL,
-		RefreshTokenTTL: refreshTTL,
+		GinMode:                os.Getenv("GIN_MODE"),
+		DBHost:                 os.Getenv("DB_HOST"),
+		DBUser:                 os.Getenv("DB_USER"),
+		DBPass:                 os.Getenv("DB_PASS"),
+		DBName:                 os.Getenv("DB_NAME"),
+		DBPort:                 os.Getenv("DB_PORT"),
+		DBSSL:                  os.Getenv("DB_SSL"),
+		AppPort:                os.Getenv("APP_PORT"),
+		JWTSecret:              os.Getenv("JWT_SECRET"),
+		JWTIssuer:              os.Getenv("JWT_ISSUER"),
+		JWTPrivateKeyFile:      os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		JWTPublicKeyFiles:      splitList(os.Getenv("JWT_PUBLIC_KEY_FILES")),
+		AccessTokenTTL:         accessTTL,
+		RefreshTokenTTL:        refreshTTL,
+		OAuthProviders:         loadOAuthProviders(),
+		AppURL:                 strings.TrimRight(os.Getenv("APP_URL"), "/"),
+		PasswordResetTTL:       parseDurationWithDefault(os.Getenv("PASSWORD_RESET_TTL"), time.Hour),
+		EmailVerifyTTL:         parseDurationWithDefault(os.Getenv("EMAIL_VERIFICATION_TTL"), 24*time.Hour),
+		MagicLinkTTL:           parseDurationWithDefault(os.Getenv("MAGIC_LINK_TTL"), 15*time.Minute),
+		EmailChangeTTL:         parseDurationWithDefault(os.Getenv("EMAIL_CHANGE_TTL"), 24*time.Hour),
+		EmailChangeRevertTTL:   parseDurationWithDefault(os.Getenv("EMAIL_CHANGE_REVERT_TTL"), 7*24*time.Hour),
+		ImpersonationTTL:       parseDurationWithDefault(os.Getenv("IMPERSONATION_TTL"), 15*time.Minute),
+		AccountDeletionGrace:   parseDurationWithDefault(os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"), 14*24*time.Hour),
+		WorkspaceInvitationTTL: parseDurationWithDefault(os.Getenv("WORKSPACE_INVITATION_TTL"), 7*24*time.Hour),
+		WorkspaceDeletionGrace: time.Duration(parseIntWithDefault(os.Getenv("WORKSPACE_DELETION_GRACE_DAYS"), 30)) * 24 * time.Hour,
+		CookieSecure:           os.Getenv("COOKIE_SECURE") == "true",
+		CookieSameSite:         os.Getenv("COOKIE_SAMESITE"),
+		CookieDomain:           os.Getenv("COOKIE_DOMAIN"),
+		LoginMaxFailures:       parseIntWithDefault(os.Getenv("LOGIN_MAX_FAILURES"), 5),
+		LoginLockout:           parseDurationWithDefault(os.Getenv("LOGIN_LOCKOUT_DURATION"), 15*time.Minute),
+		RevocationSyncInterval: parseDurationWithDefault(os.Getenv("TOKEN_REVOCATION_SYNC_INTERVAL"), 5*time.Second),
+		Mail: MailConfig{
+			Driver:       os.Getenv("MAIL_DRIVER"),
+			From:         os.Getenv("MAIL_FROM"),
+			Dir:          os.Getenv("MAIL_DIR"),
+			SMTPHost:     os.Getenv("SMTP_HOST"),
+			SMTPPort:     os.Getenv("SMTP_PORT"),
+			SMTPUsername: os.Getenv("SMTP_USERNAME"),
+			SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		},
+		Storage: StorageConfig{
+			Driver: os.Getenv("STORAGE_DRIVER"),
+			Dir:    os.Getenv("STORAGE_DIR"),
+		},
+		AvatarBaseURL: strings.TrimRight(os.Getenv("AVATAR_BASE_URL"), "/"),
 	}
 
 	if cfg.AppPort == "" {
 		cfg.AppPort = "8080"
 	}
 
+	if cfg.AppURL == "" {
+		cfg.AppURL = "http://localhost:" + cfg.AppPort
+	}
+
+	if cfg.AvatarBaseURL == "" {
+		cfg.AvatarBaseURL = cfg.AppURL + "/api/v1/avatars"
+	}
+
+	if strings.EqualFold(cfg.CookieSameSite, "none") && !cfg.CookieSecure {
+		log.Println("peringatan: COOKIE_SAMESITE=none membutuhkan COOKIE_SECURE=true, browser akan menolak cookie")
+	}
+
 	if cfg.JWTIssuer == "" {
 		cfg.JWTIssuer = "kerjakuy"
 	}
@@ -60,13 +160,15 @@ func LoadConfig() *Config {
 		log.Fatal("Konfigurasi database DB_USER atau DB_NAME hilang di lingkungan.")
 	}
 
-	if cfg.JWTSecret == "" {
-		log.Fatal("Konfigurasi JWT_SECRET wajib diisi untuk fitur autentikasi.")
+	if cfg.JWTSecret == "" && cfg.JWTPrivateKeyFile == "" {
+		log.Fatal("Konfigurasi JWT_SECRET atau JWT_PRIVATE_KEY_FILE wajib diisi untuk fitur autentikasi.")
 	}
 
 	return cfg
 }
 
+// parseDurationWithDefault falls back on non-positive values too: every
+// duration here is a TTL or an interval, and time.NewTicker panics on zero.
 func parseDurationWithDefault(value string, fallback time.Duration) time.Duration {
 	if value == "" {
 		return fallback
@@ -76,5 +178,64 @@ func parseDurationWithDefault(value string, fallback time.Duration) time.Duratio
 		log.Printf("peringatan: gagal parsing durasi %s, menggunakan default %s\n", value, fallback)
 		return fallback
 	}
+	if d <= 0 {
+		log.Printf("peringatan: durasi %s harus lebih dari nol, menggunakan default %s\n", value, fallback)
+		return fallback
+	}
 	return d
 }
+
+func parseIntWithDefault(value string, fallback int) int {
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("peringatan: gagal parsing angka %s, menggunakan default %d\n", value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// loadOAuthProviders reads the Google, GitHub and generic OIDC presets from
+// OAUTH_<NAME>_* variables, e.g. OAUTH_GOOGLE_CLIENT_ID.
+func loadOAuthProviders() []OAuthProviderConfig {
+	presets := []struct {
+		name string
+		kind string
+	}{
+		{name: "google", kind: "google"},
+		{name: "github", kind: "github"},
+		{name: "oidc", kind: "oidc"},
+	}
+
+	var providers []OAuthProviderConfig
+	for _, preset := range presets {
+		prefix := "OAUTH_" + strings.ToUpper(preset.name) + "_"
+		p := OAuthProviderConfig{
+			Name:         preset.name,
+			Kind:         preset.kind,
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			IssuerURL:    os.Getenv(prefix + "ISSUER_URL"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       splitList(os.Getenv(prefix + "SCOPES")),
+		}
+		if p.ClientID == "" || p.ClientSecret == "" {
+			continue
+		}
+		if p.Kind == "oidc" && p.IssuerURL == "" {
+			log.Printf("peringatan: %sISSUER_URL kosong, provider %s dilewati\n", prefix, p.Name)
+			continue
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDurationWithDefault(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: time.Minute},
+		{value: "10s", want: 10 * time.Second},
+		{value: "bukan-durasi", want: time.Minute},
+		{value: "0s", want: time.Minute},
+		{value: "-5s", want: time.Minute},
+	}
+	for _, tt := range tests {
+		if got := parseDurationWithDefault(tt.value, time.Minute); got != tt.want {
+			t.Errorf("parseDurationWithDefault(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -11,7 +11,7 @@ import (
 
 func InitPostgresDB(cfg *config.Config) *gorm.DB {
 	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=Asia/Jakarta",
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=UTC",
 		cfg.DBHost,
 		cfg.DBUser,
 		cfg.DBPass,